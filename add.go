@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// 新建记录：aliddns -c config.json add [-type A] [-ttl 600] [-zone example.com] 名称 值
+// 名称为完整域名，指定 -zone 时也可以是主机记录；服务商为配置中第一条记录的服务商
+func runAdd(u *updater, args []string) error {
+	fs := flag.NewFlagSet("add", flag.ExitOnError)
+	recordType := fs.String("type", "A", "Record type: A, AAAA, CNAME, MX, NS, SRV or TXT")
+	ttl := fs.Int("ttl", 0, "TTL in seconds (default: the provider's default)")
+	zone := fs.String("zone", "", "Domain the record belongs to, looked up from the name when empty")
+	priority := fs.Int("priority", 0, "MX record priority")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		return fmt.Errorf("usage: add [-type TYPE] [-ttl TTL] [-zone ZONE] [-priority N] name value")
+	}
+
+	rec := RecordConfig{
+		Provider:   u.config.records()[0].Provider,
+		Record:     strings.TrimSuffix(fs.Arg(0), "."),
+		RecordType: strings.ToUpper(*recordType),
+		TTL:        *ttl,
+		Priority:   *priority,
+	}
+	value := fs.Arg(1)
+	if *zone != "" {
+		rec.DomainName = strings.TrimSuffix(*zone, ".")
+		rec.Record = providerRecordName(rec.Provider, rec.Record, rec.DomainName)
+	}
+
+	// 与配置中的记录做同样的检查，地址类记录的值也要检查
+	rec.Value = value
+	if errs := rec.validate(); len(errs) > 0 {
+		return errs[0]
+	}
+	if err := validateRecordValue(rec, value); err != nil {
+		return err
+	}
+
+	ctx, cancel := u.config.runContext()
+	defer cancel()
+	providers := make(map[string]provider)
+	rec, err := u.prepare(ctx, providers, rec)
+	if err != nil {
+		return err
+	}
+	if err := providers[rec.Provider].createRecord(rec, value); err != nil {
+		return fmt.Errorf("%s: %w", rec, err)
+	}
+	fmt.Print(trf("Added %s %s %s (%s)\n", rec.fqdn(), rec.RecordType, value, rec.Provider))
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"golang.org/x/term"
+)
+
+// age 加密文件的开头，二进制格式和 ASCII armor 格式
+const (
+	ageHeader      = "age-encryption.org/v1"
+	ageArmorHeader = "-----BEGIN AGE ENCRYPTED FILE-----"
+)
+
+// 判断配置文件是否用 age 加密
+func isAgeEncrypted(data []byte) bool {
+	trimmed := bytes.TrimSpace(data)
+	return bytes.HasPrefix(trimmed, []byte(ageHeader)) || bytes.HasPrefix(trimmed, []byte(ageArmorHeader))
+}
+
+// 解密 age 加密的配置文件使用的身份。第一次解密时读取私钥或口令，之后重新加载配置时复用，
+// 常驻运行中不会在终端等待输入口令
+type ageKeys struct {
+	keyFile    string // age-keygen 生成的私钥文件，留空时使用口令
+	identities []age.Identity
+	noPrompt   bool // 已经进入常驻循环，不再询问口令
+}
+
+func newAgeKeys(keyFile string) *ageKeys {
+	return &ageKeys{keyFile: keyFile}
+}
+
+// 解密使用的身份，指定了身份文件时用私钥，否则用口令，
+// 口令从 ALIDDNS_AGE_PASSPHRASE 读取，未设置时在终端输入
+func (k *ageKeys) get() ([]age.Identity, error) {
+	if k.identities != nil {
+		return k.identities, nil
+	}
+	if k.keyFile != "" {
+		f, err := os.Open(k.keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open age key file: %w", err)
+		}
+		defer f.Close()
+		identities, err := age.ParseIdentities(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse age key file: %w", err)
+		}
+		k.identities = identities
+		return identities, nil
+	}
+
+	passphrase, err := agePassphrase(!k.noPrompt)
+	if err != nil {
+		return nil, err
+	}
+	identity, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	k.identities = []age.Identity{identity}
+	return k.identities, nil
+}
+
+// 解密 age 加密的配置文件
+func decryptConfig(data []byte, keys *ageKeys) ([]byte, error) {
+	identities, err := keys.get()
+	if err != nil {
+		return nil, err
+	}
+
+	var src io.Reader = bytes.NewReader(data)
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(ageArmorHeader)) {
+		src = armor.NewReader(bytes.NewReader(bytes.TrimSpace(data)))
+	}
+	r, err := age.Decrypt(src, identities...)
+	if err != nil {
+		// 口令或私钥不对时不保留，之后可以重新输入；文件写到一半等其他错误不影响已经读取的口令
+		var noMatch *age.NoIdentityMatchError
+		if errors.As(err, &noMatch) && !keys.noPrompt {
+			keys.identities = nil
+		}
+		return nil, fmt.Errorf("failed to decrypt config: %w", err)
+	}
+	plain, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt config: %w", err)
+	}
+	return plain, nil
+}
+
+// 读取解密口令，prompt 为 false 时只读取环境变量
+func agePassphrase(prompt bool) (string, error) {
+	if p, ok := os.LookupEnv(envPrefix + "AGE_PASSPHRASE"); ok {
+		return p, nil
+	}
+	if !prompt {
+		return "", fmt.Errorf("the config is encrypted with a passphrase, set %sAGE_PASSPHRASE or restart to enter it", envPrefix)
+	}
+
+	fmt.Fprint(os.Stderr, tr("Config passphrase: "))
+	defer fmt.Fprintln(os.Stderr)
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		p, err := term.ReadPassword(int(os.Stdin.Fd()))
+		if err != nil {
+			return "", fmt.Errorf("failed to read passphrase: %w", err)
+		}
+		return string(p), nil
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,575 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk"
+	sdkerrors "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/alidns"
+)
+
+// 阿里云 DNS
+type aliyunProvider struct {
+	client *alidns.Client
+	ids    map[string]recordIDs // 本次更新用到的记录 ID，保存到状态文件
+}
+
+// 阿里云接口被限流时返回的错误码，例如 Throttling.User（用户维度的流控）、Throttling.Api
+func isAliyunThrottlingCode(code string) bool {
+	return code == "Throttling" || strings.HasPrefix(code, "Throttling.")
+}
+
+// 识别阿里云接口的限流响应：状态码为 400 或 503，响应内容中的 Code 为 Throttling 系列。
+// 响应头中有 Retry-After 时按要求的时间等待
+func aliyunThrottled(resp *http.Response, body []byte) (bool, time.Duration) {
+	var result struct {
+		Code string `json:"Code"`
+	}
+	if json.Unmarshal(body, &result) != nil || !isAliyunThrottlingCode(result.Code) {
+		return false, 0
+	}
+	return true, retryAfter(resp)
+}
+
+// 默认的阿里云地域
+const defaultAliyunRegion = "cn-hangzhou"
+
+// AliyunNetwork 的取值
+const (
+	aliyunNetworkPublic = "public"
+	aliyunNetworkVPC    = "vpc"
+)
+
+// 地域 ID，例如 cn-hangzhou、ap-southeast-1
+var aliyunRegionPattern = regexp.MustCompile(`^[a-z]{2}(-[a-z0-9]+)+$`)
+
+// 接口地址只能是域名或 IP，可以带端口，不带协议和路径
+func validEndpoint(endpoint string) bool {
+	u, err := url.Parse("https://" + endpoint)
+	return err == nil && u.Host == endpoint && u.Hostname() != ""
+}
+
+func (c Config) aliyunRegion() string {
+	if c.AliyunRegion == "" {
+		return defaultAliyunRegion
+	}
+	return c.AliyunRegion
+}
+
+// 按 AliyunRegion、AliyunNetwork 和 AliyunEndpoint 设置阿里云 DNS 接口的地址。
+// 都没有填写时保持 SDK 默认的中心地址 alidns.aliyuncs.com
+func (c Config) setAliyunEndpoint(client *sdk.Client) {
+	if c.AliyunEndpoint != "" {
+		client.Domain = c.AliyunEndpoint
+		return
+	}
+	// VPC 地址只有按地域的形式，例如 alidns-vpc.cn-hangzhou.aliyuncs.com
+	if c.AliyunRegion != "" || c.AliyunNetwork == aliyunNetworkVPC {
+		client.EndpointType = "regional"
+	}
+	if c.AliyunNetwork == aliyunNetworkVPC {
+		client.Network = aliyunNetworkVPC
+	}
+}
+
+// 创建阿里云 DNS 客户端
+func newAliyunProvider(ctx context.Context, config Config) (*aliyunProvider, error) {
+	credential, err := aliyunCredential(config)
+	if err != nil {
+		return nil, err
+	}
+	transport, err := newProviderTransport(ctx, config, "aliyun")
+	if err != nil {
+		return nil, err
+	}
+	sdkConfig := sdk.NewConfig()
+	sdkConfig.Transport = transport
+	client, err := alidns.NewClientWithOptions(config.aliyunRegion(), sdkConfig, credential)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+	config.setAliyunEndpoint(&client.Client)
+	return &aliyunProvider{client: client, ids: make(map[string]recordIDs)}, nil
+}
+
+func (p *aliyunProvider) updateRecord(rec RecordConfig, newIP, oldIP string) (string, error) {
+	slog.Debug("New IP to update", "record", rec, "value", newIP)
+	return p.updateDNSRecord(rec, newIP, oldIP)
+}
+
+// 用缓存的 RecordId 直接修改记录，省去查询。值与上次发布的相同时仍然发送修改请求，
+// 以便发现控制台中被删除或改动的记录（值相同时接口返回 DomainRecordDuplicate）。
+// 记录已被删除或不属于当前账号时回退到查询后更新，其余错误直接报告
+func (p *aliyunProvider) updateByID(j *updateJob) bool {
+	slog.Debug("New IP to update", "record", j.rec, "value", j.newValue)
+	r := alidns.Record{RecordId: j.ids.recordID, RR: j.rec.Record, Type: j.rec.RecordType}
+	if err := updateAliyunRecord(p.client, r, j.rec, j.newValue); err != nil {
+		if isAliyunRecordGone(err) {
+			slog.Warn("Cached record ID is not usable, looking up the record", "record", j.rec, "id", j.ids.recordID, "error", err)
+			return false
+		}
+		j.err = err
+		return true
+	}
+	p.ids[j.rec.key()] = j.ids
+	j.current = j.oldValue
+	return true
+}
+
+// 按 RecordId 修改时记录已不存在：记录被删除（或 ID 属于其他账号）时返回 DomainRecordNotBelongToUser
+func isAliyunRecordGone(err error) bool {
+	var serverErr *sdkerrors.ServerError
+	if !errors.As(err, &serverErr) {
+		return false
+	}
+	code := serverErr.ErrorCode()
+	return code == "DomainRecordNotBelongToUser" || strings.Contains(code, "NotFound") ||
+		strings.Contains(code, "NoExist") || strings.Contains(code, "NotExist")
+}
+
+func (p *aliyunProvider) cachedIDs(rec RecordConfig) recordIDs {
+	return p.ids[rec.key()]
+}
+
+func (p *aliyunProvider) createRecord(rec RecordConfig, value string) error {
+	return createDNSRecord(p.client, rec, value)
+}
+
+func (p *aliyunProvider) deleteRecords(rec RecordConfig, value string) error {
+	matched, err := findDNSRecords(p.client, rec)
+	if err != nil {
+		return err
+	}
+	for _, r := range matched {
+		if value != "" && r.Value != value {
+			continue
+		}
+		if err := deleteDNSRecord(p.client, r.RecordId); err != nil {
+			return err
+		}
+		slog.Info("Deleted record", "type", r.Type, "rr", r.RR, "value", r.Value)
+	}
+	return nil
+}
+
+func (p *aliyunProvider) setEnabled(rec RecordConfig, enabled bool, _ string) error {
+	matched, err := findDNSRecords(p.client, rec)
+	if err != nil {
+		return err
+	}
+	if len(matched) == 0 {
+		return fmt.Errorf("%s record %s not found in domain %s: %w", rec.RecordType, rec.Record, rec.DomainName, errRecordNotFound)
+	}
+
+	status := "Disable"
+	if enabled {
+		status = "Enable"
+	}
+	for _, r := range matched {
+		statusRequest := alidns.CreateSetDomainRecordStatusRequest()
+		statusRequest.RecordId = r.RecordId
+		statusRequest.Status = status
+		if _, err := p.client.SetDomainRecordStatus(statusRequest); err != nil {
+			return fmt.Errorf("failed to set domain record status: %w", err)
+		}
+		slog.Info(status+"d record", "type", r.Type, "rr", r.RR, "value", r.Value)
+	}
+	return nil
+}
+
+// 查询一次记录，检查凭据是否有效、域名是否在账号下
+func (p *aliyunProvider) checkAccess(rec RecordConfig) error {
+	_, err := findDNSRecords(p.client, rec)
+	return err
+}
+
+// 列出账号下的全部域名
+func (p *aliyunProvider) listZones() ([]string, error) {
+	var names []string
+	for page := 1; ; page++ {
+		describeRequest := alidns.CreateDescribeDomainsRequest()
+		describeRequest.PageNumber = requests.NewInteger(page)
+		describeRequest.PageSize = requests.NewInteger(100)
+		describeResponse, err := p.client.DescribeDomains(describeRequest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe domains: %w", err)
+		}
+
+		domains := describeResponse.Domains.Domain
+		for _, d := range domains {
+			names = append(names, d.DomainName)
+		}
+		if len(domains) < 100 || int64(page*100) >= describeResponse.TotalCount {
+			return names, nil
+		}
+	}
+}
+
+// 列出域名下的全部记录
+func (p *aliyunProvider) listRecords(domain string) ([]zoneRecord, error) {
+	records, err := describeDomainRecords(p.client, domain, "", "")
+	if err != nil {
+		return nil, err
+	}
+	result := make([]zoneRecord, 0, len(records))
+	for _, r := range records {
+		result = append(result, zoneRecord{ID: r.RecordId, Record: r.RR, RecordType: r.Type, Value: r.Value, TTL: int(r.TTL)})
+	}
+	return result, nil
+}
+
+// 用 GetMainDomainName 从完整域名中解析出主域名
+func (p *aliyunProvider) findZone(fqdn string) (string, error) {
+	mainRequest := alidns.CreateGetMainDomainNameRequest()
+	mainRequest.InputString = fqdn
+	mainResponse, err := p.client.GetMainDomainName(mainRequest)
+	if err != nil {
+		return "", fmt.Errorf("failed to get main domain name: %w", err)
+	}
+	return mainResponse.DomainName, nil
+}
+
+// 每页查询的记录数，阿里云允许的最大值为 500
+const aliyunPageSize = 500
+
+// 查询主机记录和类型都匹配的 DNS 记录。用 RRKeyWord 和 Type 在服务端缩小范围，
+// 再精确匹配主机记录
+func findDNSRecords(client *alidns.Client, rec RecordConfig) ([]alidns.Record, error) {
+	records, err := describeDomainRecords(client, rec.DomainName, rec.Record, rec.RecordType)
+	if err != nil {
+		return nil, err
+	}
+	return filterAliyunRecords(records, rec), nil
+}
+
+// 逐页查询域名下的记录，避免记录较多的域名只查到第一页。rrKeyWord 和 recordType 为空时查询全部记录
+func describeDomainRecords(client *alidns.Client, domainName, rrKeyWord, recordType string) ([]alidns.Record, error) {
+	var all []alidns.Record
+	for page := 1; ; page++ {
+		describeRequest := alidns.CreateDescribeDomainRecordsRequest()
+		describeRequest.DomainName = domainName
+		describeRequest.RRKeyWord = rrKeyWord
+		describeRequest.Type = recordType
+		describeRequest.PageNumber = requests.NewInteger(page)
+		describeRequest.PageSize = requests.NewInteger(aliyunPageSize)
+		describeResponse, err := client.DescribeDomainRecords(describeRequest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe domain records: %w", err)
+		}
+
+		records := describeResponse.DomainRecords.Record
+		all = append(all, records...)
+
+		if len(records) < aliyunPageSize || int64(page*aliyunPageSize) >= describeResponse.TotalCount {
+			return all, nil
+		}
+	}
+}
+
+// 筛选出主机记录和类型都匹配的记录
+func filterAliyunRecords(records []alidns.Record, rec RecordConfig) []alidns.Record {
+	var matched []alidns.Record
+	for _, r := range records {
+		if r.RR == rec.Record && r.Type == rec.RecordType {
+			matched = append(matched, r)
+		}
+	}
+	return matched
+}
+
+// 批量更新。阿里云的批量接口（OperateBatchDomain）只支持添加和删除记录，
+// 所以把查询合并为每个域名一次，修改仍然逐条调用，只修改值有变化的记录
+func (p *aliyunProvider) updateBatch(jobs []*updateJob) {
+	byDomain := make(map[string][]*updateJob)
+	var domains []string
+	for _, j := range jobs {
+		if _, ok := byDomain[j.rec.DomainName]; !ok {
+			domains = append(domains, j.rec.DomainName)
+		}
+		byDomain[j.rec.DomainName] = append(byDomain[j.rec.DomainName], j)
+	}
+
+	for _, domain := range domains {
+		records, err := describeDomainRecords(p.client, domain, "", "")
+		for _, j := range byDomain[domain] {
+			if err != nil {
+				j.err = err
+				continue
+			}
+			slog.Debug("New IP to update", "record", j.rec, "value", j.newValue)
+			j.current, j.err = p.updateMatched(j.rec, filterAliyunRecords(records, j.rec), j.newValue, j.oldValue)
+		}
+	}
+}
+
+// 加权解析：让同名记录与各个地址一一对应，开启加权解析并设置每条记录的权重
+func (p *aliyunProvider) updateWeighted(rec RecordConfig, values []weightedValue) (string, error) {
+	// 两个出口地址相同时合并为一条记录，权重相加
+	var merged []weightedValue
+	for _, v := range values {
+		if v.weight <= 0 {
+			v.weight = 1
+		}
+		found := false
+		for i := range merged {
+			if merged[i].value == v.value {
+				merged[i].weight = min(merged[i].weight+v.weight, 100)
+				found = true
+			}
+		}
+		if !found {
+			merged = append(merged, v)
+		}
+	}
+
+	matched, err := findDNSRecords(p.client, rec)
+	if err != nil {
+		return "", err
+	}
+	current := make([]string, len(matched))
+	for i, r := range matched {
+		current[i] = r.Value
+	}
+
+	// 值已经正确的记录保持不变，缺少的地址优先改写多余的记录，不够时新建，最后删除仍然多余的记录
+	used := make([]bool, len(matched))
+	var missing []string
+	for _, v := range merged {
+		found := false
+		for i, r := range matched {
+			if !used[i] && r.Value == v.value {
+				used[i] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, v.value)
+		}
+	}
+	for _, value := range missing {
+		reused := false
+		for i, r := range matched {
+			if used[i] {
+				continue
+			}
+			used[i] = true
+			reused = true
+			if err := updateAliyunRecord(p.client, r, rec, value); err != nil {
+				return "", err
+			}
+			break
+		}
+		if !reused {
+			if err := createDNSRecord(p.client, rec, value); err != nil {
+				return "", err
+			}
+		}
+	}
+	for i, r := range matched {
+		if used[i] {
+			continue
+		}
+		if err := deleteDNSRecord(p.client, r.RecordId); err != nil {
+			return "", err
+		}
+		slog.Info("Deleted record", "type", r.Type, "rr", r.RR, "value", r.Value)
+	}
+
+	// 只有一个地址时不需要加权解析
+	if len(merged) < 2 {
+		return joinWeightedValues(current), nil
+	}
+
+	subDomain := rec.DomainName
+	if rec.Record != "@" {
+		subDomain = rec.Record + "." + rec.DomainName
+	}
+	statusRequest := alidns.CreateSetDNSSLBStatusRequest()
+	statusRequest.DomainName = rec.DomainName
+	statusRequest.SubDomain = subDomain
+	statusRequest.Type = rec.RecordType
+	statusRequest.Open = requests.NewBoolean(true)
+	if _, err := p.client.SetDNSSLBStatus(statusRequest); err != nil {
+		return "", fmt.Errorf("failed to enable weighted resolution: %w", err)
+	}
+
+	// 重新查询，拿到新建记录的 RecordId 和当前权重
+	matched, err = findDNSRecords(p.client, rec)
+	if err != nil {
+		return "", err
+	}
+	for _, r := range matched {
+		for _, v := range merged {
+			if r.Value != v.value || r.Weight == v.weight {
+				continue
+			}
+			weightRequest := alidns.CreateUpdateDNSSLBWeightRequest()
+			weightRequest.RecordId = r.RecordId
+			weightRequest.Weight = requests.NewInteger(v.weight)
+			if _, err := p.client.UpdateDNSSLBWeight(weightRequest); err != nil {
+				return "", fmt.Errorf("failed to update weight of %s: %w", r.Value, err)
+			}
+			slog.Info("Set record weight", "type", r.Type, "rr", r.RR, "value", r.Value, "weight", v.weight)
+		}
+	}
+
+	return joinWeightedValues(current), nil
+}
+
+// 更新 DNS 记录，同名同类型有多条记录时按 MultiRecordPolicy 选择要更新的记录
+func (p *aliyunProvider) updateDNSRecord(rec RecordConfig, newIP, oldIP string) (string, error) {
+	// 查询当前的 DNS 记录
+	matched, err := findDNSRecords(p.client, rec)
+	if err != nil {
+		return "", err
+	}
+	return p.updateMatched(rec, matched, newIP, oldIP)
+}
+
+// 用查询到的记录更新，返回记录原来的值
+func (p *aliyunProvider) updateMatched(rec RecordConfig, matched []alidns.Record, newIP, oldIP string) (string, error) {
+	client := p.client
+	delete(p.ids, rec.key())
+	if len(matched) == 0 {
+		if rec.CreateMissing {
+			return "", createDNSRecord(client, rec, newIP)
+		}
+		return "", fmt.Errorf("%s record %s not found in domain %s: %w", rec.RecordType, rec.Record, rec.DomainName, errRecordNotFound)
+	}
+
+	values := make([]string, len(matched))
+	for i, r := range matched {
+		values[i] = r.Value
+	}
+
+	// 删除重复的记录，只保留一条
+	if len(matched) > 1 && rec.MultiRecordPolicy == policyCollapse {
+		keep := canonicalIndex(values, newIP)
+		for i, r := range matched {
+			if i == keep {
+				continue
+			}
+			if err := deleteDNSRecord(client, r.RecordId); err != nil {
+				return "", err
+			}
+			slog.Info("Deleted duplicate record", "type", r.Type, "rr", r.RR, "value", r.Value)
+		}
+		matched = matched[keep : keep+1]
+		values = values[keep : keep+1]
+	}
+
+	targets, err := selectTargets(values, oldIP, rec.MultiRecordPolicy)
+	if err != nil {
+		return "", err
+	}
+
+	currentIP := matched[targets[0]].Value // 获取当前记录的 IP
+	for _, i := range targets {
+		if err := updateAliyunRecord(client, matched[i], rec, newIP); err != nil {
+			return "", err
+		}
+	}
+	if len(targets) == 1 {
+		p.ids[rec.key()] = recordIDs{recordID: matched[targets[0]].RecordId}
+	}
+
+	return currentIP, nil
+}
+
+// 把一条已有的记录更新为新地址
+func updateAliyunRecord(client *alidns.Client, r alidns.Record, rec RecordConfig, newIP string) error {
+	// 检查当前 IP 和新 IP 是否相同，-force 时仍然更新
+	if r.Value == newIP && !rec.force {
+		slog.Info("IP address is already up to date", "rr", r.RR, "value", r.Value)
+		return nil // 无需更新
+	}
+
+	// 更新 DNS 记录
+	updateRequest := alidns.CreateUpdateDomainRecordRequest()
+	updateRequest.RecordId = r.RecordId
+	updateRequest.RR = rec.Record
+	updateRequest.Type = rec.RecordType
+	updateRequest.Value = newIP
+	if rec.TTL > 0 {
+		updateRequest.TTL = requests.NewInteger(rec.TTL)
+	}
+	if rec.RecordType == "MX" && rec.Priority > 0 {
+		updateRequest.Priority = requests.NewInteger(rec.Priority)
+	}
+
+	// 尝试更新 DNS 记录，并处理可能的错误
+	_, err := client.UpdateDomainRecord(updateRequest)
+	if err != nil {
+		// 未知类型错误处理，用错误信息的字符串进行匹配
+		if strings.Contains(err.Error(), "DomainRecordDuplicate") {
+			slog.Info("The DNS record already exists with the same value", "record", rec, "value", newIP)
+			return nil // 记录已经存在
+		}
+		return fmt.Errorf("failed to update domain record: %w", err)
+	}
+
+	return updateRemark(client, r.RecordId, rec, newIP)
+}
+
+// 在记录备注中标记由本程序管理
+func updateRemark(client *alidns.Client, recordID string, rec RecordConfig, value string) error {
+	if rec.Remark == "" {
+		return nil
+	}
+	remarkRequest := alidns.CreateUpdateDomainRecordRemarkRequest()
+	remarkRequest.RecordId = recordID
+	remarkRequest.Remark = remarkText(rec, value)
+	if _, err := client.UpdateDomainRecordRemark(remarkRequest); err != nil {
+		return fmt.Errorf("failed to update domain record remark: %w", err)
+	}
+	return nil
+}
+
+// 新建 DNS 记录
+func createDNSRecord(client *alidns.Client, rec RecordConfig, newIP string) error {
+	addRequest := alidns.CreateAddDomainRecordRequest()
+	addRequest.DomainName = rec.DomainName
+	addRequest.RR = rec.Record
+	addRequest.Type = rec.RecordType
+	addRequest.Value = newIP
+	if rec.TTL > 0 {
+		addRequest.TTL = requests.NewInteger(rec.TTL)
+	}
+	if rec.RecordType == "MX" && rec.Priority > 0 {
+		addRequest.Priority = requests.NewInteger(rec.Priority)
+	}
+
+	addResponse, err := client.AddDomainRecord(addRequest)
+	if err != nil && strings.Contains(err.Error(), "DomainRecordDuplicate") {
+		// 同样的记录已经存在，例如上次请求已经送达却没有收到响应
+		slog.Info("The DNS record already exists with the same value", "record", rec, "value", newIP)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add domain record: %w", err)
+	}
+
+	slog.Info("Created record", "record", rec, "value", newIP)
+	return updateRemark(client, addResponse.RecordId, rec, newIP)
+}
+
+// 删除 DNS 记录
+func deleteDNSRecord(client *alidns.Client, recordID string) error {
+	deleteRequest := alidns.CreateDeleteDomainRecordRequest()
+	deleteRequest.RecordId = recordID
+	if _, err := client.DeleteDomainRecord(deleteRequest); err != nil {
+		return fmt.Errorf("failed to delete domain record %s: %w", recordID, err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/auth"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/auth/credentials"
+)
+
+// ECS 实例元数据服务中 RAM 角色凭据的地址
+const ecsRAMRoleURL = "http://100.100.100.200/latest/meta-data/ram/security-credentials/"
+
+// 阿里云 DNS 和 PrivateZone 共用的凭据。配置了 RAMRole 时从 ECS 实例元数据获取临时凭据，
+// SDK 会在过期前自动刷新，配置文件中不需要 AccessKey；配置了 SecurityToken 时使用 STS 临时凭据
+func aliyunCredential(config Config) (auth.Credential, error) {
+	if config.AliyunProfile != "" {
+		return aliyunCLIProfile(config.AliyunProfile)
+	}
+
+	if config.RAMRole != "" {
+		role := config.RAMRole
+		if strings.EqualFold(role, "auto") {
+			var err error
+			if role, err = ecsRAMRoleName(); err != nil {
+				return nil, err
+			}
+		}
+		return credentials.NewEcsRamRoleCredential(role), nil
+	}
+
+	if config.AccessKeyID == "" || config.AccessKeySecret == "" {
+		return nil, fmt.Errorf("Aliyun credentials are not configured: set AccessKeyID and AccessKeySecret, RAMRole or AliyunProfile")
+	}
+	if config.SecurityToken != "" {
+		return credentials.NewStsTokenCredential(config.AccessKeyID, config.AccessKeySecret, config.SecurityToken), nil
+	}
+	return credentials.NewAccessKeyCredential(config.AccessKeyID, config.AccessKeySecret), nil
+}
+
+// 使用阿里云 CLI（aliyun configure）保存在 ~/.aliyun/config.json 中的凭据，
+// 支持 AK、RamRoleArn、EcsRamRole 等模式，"current" 表示 CLI 当前使用的配置
+func aliyunCLIProfile(name string) (auth.Credential, error) {
+	if name == "current" {
+		name = ""
+	}
+	provider := credentials.NewCLIProfileCredentialsProviderBuilder().WithProfileName(name).Build()
+	// 启动时先读取一次，配置有误时直接报错，而不是等到调用接口
+	if _, err := provider.GetCredentials(); err != nil {
+		return nil, fmt.Errorf("failed to load Aliyun CLI profile: %w", err)
+	}
+	return provider, nil
+}
+
+// 查询实例绑定的 RAM 角色名称
+func ecsRAMRoleName() (string, error) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(ecsRAMRoleURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to query ECS instance metadata: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read ECS instance metadata: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("no RAM role attached to this ECS instance (status %d)", resp.StatusCode)
+	}
+
+	role := strings.TrimSpace(strings.SplitN(string(body), "\n", 2)[0])
+	if role == "" {
+		return "", fmt.Errorf("no RAM role attached to this ECS instance")
+	}
+	return role, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// 审计日志中的一条，每次调用服务商接口追加一行 JSON，可以与阿里云操作审计、Cloudflare 审计日志对照
+type auditEntry struct {
+	Time      time.Time         `json:"Time"`
+	Provider  string            `json:"Provider"`
+	Method    string            `json:"Method"`
+	Endpoint  string            `json:"Endpoint"`         // 不含查询参数的地址
+	Params    map[string]string `json:"Params,omitempty"` // 查询参数，已去掉签名和凭据
+	Body      string            `json:"Body,omitempty"`   // 请求体，Cloudflare 为记录的 JSON
+	Status    int               `json:"Status,omitempty"`
+	RequestID string            `json:"RequestID,omitempty"` // 阿里云的 RequestId，Cloudflare 的 CF-Ray
+	Latency   int64             `json:"LatencyMs"`
+	Error     string            `json:"Error,omitempty"`
+}
+
+// 记录每个请求的 RoundTripper，认证信息在请求头中，不写入日志
+type auditTransport struct {
+	base     http.RoundTripper
+	file     string
+	provider string
+}
+
+// 配置了 AuditLog 时返回记录请求的 RoundTripper，否则直接返回 base，见 newProviderTransport
+func newAuditTransport(config Config, provider string, base http.RoundTripper) http.RoundTripper {
+	if config.AuditLog == "" {
+		return base
+	}
+	return &auditTransport{base: base, file: config.AuditLog, provider: provider}
+}
+
+func (t *auditTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	entry := auditEntry{
+		Time:     time.Now(),
+		Provider: t.provider,
+		Method:   req.Method,
+		Endpoint: req.URL.Scheme + "://" + req.URL.Host + req.URL.Path,
+		Params:   auditParams(req),
+	}
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			data, _ := ioutil.ReadAll(body)
+			body.Close()
+			entry.Body = string(data)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	entry.Latency = time.Since(entry.Time).Milliseconds()
+	if err != nil {
+		entry.Error = err.Error()
+	} else {
+		entry.Status = resp.StatusCode
+		entry.RequestID = auditRequestID(resp)
+	}
+	t.write(entry)
+	return resp, err
+}
+
+// 查询参数中去掉签名和临时凭据，AccessKeyId 只保留开头
+func auditParams(req *http.Request) map[string]string {
+	query := req.URL.Query()
+	if len(query) == 0 {
+		return nil
+	}
+	params := make(map[string]string, len(query))
+	for k, v := range query {
+		switch k {
+		case "Signature", "SecurityToken":
+			continue
+		case "AccessKeyId":
+			params[k] = maskSecret(strings.Join(v, ","))
+		default:
+			params[k] = strings.Join(v, ",")
+		}
+	}
+	return params
+}
+
+// 只保留前 4 个字符
+func maskSecret(s string) string {
+	if len(s) <= 4 {
+		return "****"
+	}
+	return s[:4] + "****"
+}
+
+// 从响应头读取请求 ID，阿里云的响应头中没有时从响应的 JSON 中读取，读取后恢复响应体
+func auditRequestID(resp *http.Response) string {
+	for _, h := range []string{"X-Acs-Request-Id", "Cf-Ray"} {
+		if id := resp.Header.Get(h); id != "" {
+			return id
+		}
+	}
+	if !strings.Contains(resp.Header.Get("Content-Type"), "json") {
+		return ""
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+	if err != nil {
+		return ""
+	}
+	var result struct {
+		RequestID string `json:"RequestId"`
+	}
+	json.Unmarshal(data, &result)
+	return result.RequestID
+}
+
+// 追加到审计日志，写入失败只记录日志，不影响请求
+func (t *auditTransport) write(entry auditEntry) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	f, err := os.OpenFile(t.file, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err == nil {
+		_, err = f.Write(append(line, '\n'))
+		f.Close()
+	}
+	if err != nil {
+		slog.Warn("Failed to write audit log", "error", err)
+	}
+}
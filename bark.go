@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 默认的 Bark 服务器
+const defaultBarkServer = "https://api.day.app"
+
+// Bark（iOS）推送，Key 为 App 中显示的设备 Key，自建服务器时 URL 填服务器地址
+type barkNotifier struct {
+	server string
+	key    string
+}
+
+func newBarkNotifier(c NotifyConfig) (*barkNotifier, error) {
+	if c.Key == "" {
+		return nil, fmt.Errorf("bark notification requires Key")
+	}
+	n := &barkNotifier{server: strings.TrimSuffix(c.URL, "/"), key: c.Key}
+	if n.server == "" {
+		n.server = defaultBarkServer
+	}
+	return n, nil
+}
+
+// 消息归入 aliDDNS 分组，失败通知为时效性通知，专注模式下也会提醒
+func (n *barkNotifier) notify(e notifyEvent) error {
+	title, text := notifyMessage(e)
+	msg := map[string]string{
+		"device_key": n.key,
+		"title":      "aliDDNS: " + title,
+		"body":       text,
+		"group":      "aliDDNS",
+	}
+	if e.Error != "" {
+		msg["level"] = "timeSensitive"
+	}
+
+	var resp struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := postJSON(n.server+"/push", msg, &resp); err != nil {
+		return err
+	}
+	if resp.Code != 200 {
+		return fmt.Errorf("code %d: %s", resp.Code, resp.Message)
+	}
+	return nil
+}
+
+func (n *barkNotifier) String() string {
+	return "bark"
+}
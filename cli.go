@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+// 子命令及其说明，按帮助中显示的顺序排列
+var commands = []struct {
+	name  string
+	usage string
+	flags bool // 命令有自己的参数，命令名之后的参数不按全局参数解析
+}{
+	{"update", "Check the external IP and update all records once", false},
+	{"daemon", "Keep running and check every -i or Interval", false},
+	{"tui", "Run as a daemon with a terminal dashboard of records, next check and recent logs", false},
+	{"list", "List configured records, or the provider's zones and records: list [zones|records ZONE]", false},
+	{"ip", "Detect and print the external IP address only: ip [-4] [-6] [-o FORMAT]", true},
+	{"verify", "Resolve the records via public resolvers and compare with the detected IP: verify [-resolver LIST] [-o FORMAT]", true},
+	{"doctor", "Check the config, credentials, IP sources and records and suggest fixes", false},
+	{"status", "Show whether the last successful check is recent (Nagios exit codes)", false},
+	{"history", "Show the update history: history [-n N] [-changes] [-failed] [-o FORMAT] [record...]", true},
+	{"stats", "Show how often records change: stats [-days N] [record...]", true},
+	{"enable", "Enable records: enable [record...]", false},
+	{"disable", "Disable records, updates skip them: disable [record...]", false},
+	{"add", "Create a record at the provider: add [-type TYPE] [-ttl TTL] [-zone ZONE] name value", true},
+	{"delete", "Delete records at the provider: delete [-yes] [-type TYPE] [-value VALUE] record...", true},
+	{"config", "Check or migrate the config file: config validate|migrate", true},
+	{"init", "Create a config file interactively", false},
+	{"version", "Print the version and build information", false},
+}
+
+// 命令行帮助，列出子命令和全局参数
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprint(out, trf("Usage: %s [flags] [command] [args]\n\nCommands:\n", os.Args[0]))
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %s\t%s\n", c.name, tr(c.usage))
+	}
+	w.Flush()
+	fmt.Fprint(out, tr("\nWithout a command, runs as a daemon when -i or Interval is set, otherwise updates once.\n\nFlags:\n"))
+	flag.VisitAll(func(f *flag.Flag) {
+		f.Usage = tr(f.Usage)
+	})
+	flag.PrintDefaults()
+}
+
+// 取出子命令和它的参数。全局参数也可以写在命令名之后，例如 aliddns update -c config.json；
+// 有自己参数的命令（history、stats、config）把剩下的参数原样交给命令处理
+func parseCommand() (string, []string, error) {
+	if flag.NArg() == 0 {
+		return "", nil, nil
+	}
+	name, args := flag.Arg(0), flag.Args()[1:]
+	for _, c := range commands {
+		if c.name != name {
+			continue
+		}
+		if c.flags {
+			return name, args, nil
+		}
+		if err := flag.CommandLine.Parse(args); err != nil {
+			return "", nil, err
+		}
+		return name, flag.Args(), nil
+	}
+	return "", nil, fmt.Errorf("unknown command %q, run with -h to see the commands", name)
+}
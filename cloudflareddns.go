@@ -1,242 +1,956 @@
-package main
-
-import (
-	"bytes"
-	"encoding/json"
-	"fmt"
-	"io/ioutil"
-	"net/http"
-	"os"
-)
-
-type Config struct {
-	CF_API_TOKEN string `json:"CF_API_TOKEN"`
-	DOMAIN_NAME  string `json:"DOMAIN_NAME"`
-	RECORD_NAME  string `json:"RECORD_NAME"`
-}
-
-var (
-	cfApiToken string
-	domainName string
-	recordName string
-)
-
-func loadConfig(filePath string) error {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	byteValue, err := ioutil.ReadAll(file)
-	if err != nil {
-		return err
-	}
-
-	var config Config
-	err = json.Unmarshal(byteValue, &config)
-	if err != nil {
-		return err
-	}
-
-	cfApiToken = config.CF_API_TOKEN
-	domainName = config.DOMAIN_NAME
-	recordName = config.RECORD_NAME
-
-	return nil
-}
-
-type CloudflareZoneResponse struct {
-	Result []struct {
-		Id   string `json:"id"`
-		Name string `json:"name"`
-	} `json:"result"`
-}
-
-type CloudflareDNSResponse struct {
-	Result []struct {
-		Id      string `json:"id"`
-		Name    string `json:"name"`
-		Type    string `json:"type"`
-		Content string `json:"content"`
-	} `json:"result"`
-}
-
-type UpdateDNSRequest struct {
-	Type    string `json:"type"`
-	Name    string `json:"name"`
-	Content string `json:"content"`
-	TTL     int    `json:"ttl"`
-	Proxied bool   `json:"proxied"`
-}
-
-func getZoneID(domainName string) (string, error) {
-	url := "https://api.cloudflare.com/client/v4/zones"
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return "", err
-	}
-	req.Header.Set("Authorization", "Bearer "+cfApiToken)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
-
-	var response CloudflareZoneResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return "", err
-	}
-
-	for _, zone := range response.Result {
-		if zone.Name == domainName {
-			return zone.Id, nil
-		}
-	}
-
-	return "", fmt.Errorf("未找到域名 %s 的Zone ID", domainName)
-}
-
-func getDNSRecord(zoneID, recordName string) (string, string, error) {
-	url := fmt.Sprintf("https://api.cloudflare.com/client/v4/zones/%s/dns_records?name=%s", zoneID, recordName)
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return "", "", err
-	}
-	req.Header.Set("Authorization", "Bearer "+cfApiToken)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return "", "", err
-	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return "", "", err
-	}
-
-	var response CloudflareDNSResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return "", "", err
-	}
-
-	if len(response.Result) == 0 {
-		return "", "", fmt.Errorf("未找到DNS记录 %s", recordName)
-	}
-
-	return response.Result[0].Id, response.Result[0].Content, nil
-}
-
-func getExternalIP() (string, error) {
-	resp, err := http.Get("https://ipinfo.io/ip")
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	ip, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
-
-	return string(ip), nil
-}
-
-func updateDNSRecord(zoneID, recordID, recordName, newIP string) error {
-	url := fmt.Sprintf("https://api.cloudflare.com/client/v4/zones/%s/dns_records/%s", zoneID, recordID)
-	updateRequest := UpdateDNSRequest{
-		Type:    "A",
-		Name:    recordName,
-		Content: newIP,
-		TTL:     1,
-		Proxied: false,
-	}
-
-	jsonData, err := json.Marshal(updateRequest)
-	if err != nil {
-		return err
-	}
-
-	req, err := http.NewRequest("PUT", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Authorization", "Bearer "+cfApiToken)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("更新DNS记录失败，状态码: %d", resp.StatusCode)
-	}
-
-	return nil
-}
-
-func main() {
-	// 定义命令行参数
-	configFilePath := flag.String("c", "config.json", "配置文件路径")
-	flag.Parse()
-	
-	// 加载配置文件
-	err := loadConfig("config.json")
-	if err != nil {
-		fmt.Println("加载配置文件失败:", err)
-		return
-	}
-
-	// 打印读取的配置
-	fmt.Printf("CF_API_TOKEN: %s\n", cfApiToken)
-	fmt.Printf("DOMAIN_NAME: %s\n", domainName)
-	fmt.Printf("RECORD_NAME: %s\n", recordName)
-
-	zoneID, err := getZoneID(domainName)
-	if err != nil {
-		fmt.Println("获取Zone ID失败:", err)
-		return
-	}
-
-	fmt.Printf("域名 %s 的Zone ID是: %s\n", domainName, zoneID)
-
-	recordID, recordContent, err := getDNSRecord(zoneID, recordName)
-	if err != nil {
-		fmt.Println("获取DNS记录失败:", err)
-		return
-	}
-
-	fmt.Printf("DNS记录 %s 的内容是: %s\n", recordName, recordContent)
-
-	externalIP, err := getExternalIP()
-	if err != nil {
-		fmt.Println("获取外网IP失败:", err)
-		return
-	}
-
-	fmt.Printf("本地外网IP地址是: %s\n", externalIP)
-
-	if externalIP == recordContent {
-		fmt.Println("外网IP与DNS记录匹配，无需更新。")
-		return
-	}
-
-	fmt.Println("外网IP与DNS记录不匹配，正在更新DNS记录...")
-	if err := updateDNSRecord(zoneID, recordID, recordName, externalIP); err != nil {
-		fmt.Println("更新DNS记录失败:", err)
-		return
-	}
-
-	fmt.Println("DNS记录更新成功。")
-}
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cloudflare DNS
+type cloudflareProvider struct {
+	apiToken string
+	apiKey   string // 旧版的 Global API Key，与邮箱一起使用
+	email    string
+	zoneIDs  map[string]string    // 域名到 Zone ID 的缓存，同一 Zone 的多条记录只查询一次
+	ids      map[string]recordIDs // 本次更新用到的记录 ID，保存到状态文件
+	proxied  map[string]bool      // 更新后记录实际是否经过 Cloudflare 代理，未配置 Proxied 时保持原样
+	client   *http.Client
+}
+
+func newCloudflareProvider(ctx context.Context, config Config) (*cloudflareProvider, error) {
+	if config.CFAPIToken == "" && (config.CFAPIKey == "" || config.CFEmail == "") {
+		return nil, fmt.Errorf("CF_API_TOKEN, or CF_API_KEY and CF_API_EMAIL, is required")
+	}
+	transport, err := newProviderTransport(ctx, config, "cloudflare")
+	if err != nil {
+		return nil, err
+	}
+	return &cloudflareProvider{
+		apiToken: config.CFAPIToken,
+		apiKey:   config.CFAPIKey,
+		email:    config.CFEmail,
+		zoneIDs:  make(map[string]string),
+		ids:      make(map[string]recordIDs),
+		proxied:  make(map[string]bool),
+		client:   &http.Client{Transport: transport},
+	}, nil
+}
+
+// 设置认证头，优先使用 API Token
+func (p *cloudflareProvider) setAuth(req *http.Request) {
+	if p.apiToken != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiToken)
+		return
+	}
+	req.Header.Set("X-Auth-Key", p.apiKey)
+	req.Header.Set("X-Auth-Email", p.email)
+}
+
+type cloudflareTokenResponse struct {
+	Success bool `json:"success"`
+	Result  struct {
+		Id       string `json:"id"`
+		Status   string `json:"status"`
+		Policies []struct {
+			Effect           string `json:"effect"`
+			PermissionGroups []struct {
+				Name string `json:"name"`
+			} `json:"permission_groups"`
+		} `json:"policies"`
+	} `json:"result"`
+}
+
+// 启动时检查 API Token 是否有效，以及是否有编辑 DNS 的权限（Zone.DNS 编辑，即 "DNS Write"）。
+// 读取权限需要 Token 本身有读取 API Token 的权限，没有时只检查是否有效
+func verifyCloudflareToken(ctx context.Context, config Config) error {
+	transport, err := newProviderTransport(ctx, config, "cloudflare")
+	if err != nil {
+		return err
+	}
+	p := &cloudflareProvider{apiToken: config.CFAPIToken, client: &http.Client{Transport: transport}}
+	var verify cloudflareTokenResponse
+	status, err := p.getJSON("https://api.cloudflare.com/client/v4/user/tokens/verify", &verify)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusTooManyRequests || status >= 500 {
+		return cloudflareStatusError("failed to verify API token", status)
+	}
+	if status != http.StatusOK || !verify.Success {
+		return fmt.Errorf("API token is invalid, status %d: %w", status, errAuth)
+	}
+	if verify.Result.Status != "active" {
+		return fmt.Errorf("API token status is %s, not usable: %w", verify.Result.Status, errAuth)
+	}
+
+	var detail cloudflareTokenResponse
+	status, err = p.getJSON("https://api.cloudflare.com/client/v4/user/tokens/"+verify.Result.Id, &detail)
+	if err != nil || status != http.StatusOK {
+		slog.Info("Cloudflare API token is valid (cannot read its permissions, skipping the permission check)")
+		return nil
+	}
+	for _, policy := range detail.Result.Policies {
+		if policy.Effect != "allow" {
+			continue
+		}
+		for _, group := range policy.PermissionGroups {
+			if group.Name == "DNS Write" {
+				slog.Info("Cloudflare API token is valid and has DNS edit permission")
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("API token does not have DNS edit permission (Zone.DNS edit): %w", errAuth)
+}
+
+// 接口返回的状态码不是 200 时的错误，401 和 403 表示凭据无效或权限不足，
+// 429 表示请求太频繁（重试后仍被限流）
+func cloudflareStatusError(action string, status int) error {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%s, status %d: %w", action, status, errAuth)
+	case http.StatusTooManyRequests:
+		return fmt.Errorf("%s: rate limited by the Cloudflare API (status 429), reduce the check frequency or the number of records", action)
+	}
+	return fmt.Errorf("%s, status %d", action, status)
+}
+
+// Cloudflare 限流时返回的错误码，状态码一般为 429
+const cloudflareRateLimitCode = 971
+
+// 识别 Cloudflare 的限流响应：状态码 429，或错误码 971（"Please wait and consider throttling your request speed"）。
+// 按响应头 Retry-After 要求的时间等待
+func cloudflareThrottled(resp *http.Response, body []byte) (bool, time.Duration) {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true, retryAfter(resp)
+	}
+	var result struct {
+		Errors []struct {
+			Code int `json:"code"`
+		} `json:"errors"`
+	}
+	if json.Unmarshal(body, &result) != nil {
+		return false, 0
+	}
+	for _, e := range result.Errors {
+		if e.Code == cloudflareRateLimitCode {
+			return true, retryAfter(resp)
+		}
+	}
+	return false, 0
+}
+
+// 发送 GET 请求并解析 JSON 响应，返回状态码
+func (p *cloudflareProvider) getJSON(endpoint string, v interface{}) (int, error) {
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return 0, err
+	}
+	p.setAuth(req)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return resp.StatusCode, err
+	}
+	return resp.StatusCode, nil
+}
+
+type CloudflareZoneResponse struct {
+	Result []struct {
+		Id   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"result"`
+}
+
+type CloudflareDNSRecord struct {
+	Id      string   `json:"id"`
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	Content string   `json:"content"`
+	TTL     int      `json:"ttl"` // 1 表示自动
+	Proxied bool     `json:"proxied"`
+	Comment string   `json:"comment"`
+	Tags    []string `json:"tags"`
+
+	Priority *int `json:"priority"` // MX 和 SRV 记录的优先级
+}
+
+// 记录的值，格式与配置中的 Value 相同：SRV 记录的优先级不在 content 中，加回到前面
+func (r CloudflareDNSRecord) value() string {
+	if r.Type == "SRV" && r.Priority != nil {
+		return strconv.Itoa(*r.Priority) + " " + r.Content
+	}
+	return r.Content
+}
+
+type CloudflareDNSResponse struct {
+	Result     []CloudflareDNSRecord `json:"result"`
+	ResultInfo struct {
+		Page       int `json:"page"`
+		TotalPages int `json:"total_pages"`
+	} `json:"result_info"`
+}
+
+// 每页查询的记录数
+const cloudflarePerPage = 100
+
+type UpdateDNSRequest struct {
+	Type    string   `json:"type"`
+	Name    string   `json:"name"`
+	Content string   `json:"content"`
+	TTL     int      `json:"ttl"`
+	Proxied bool     `json:"proxied"`
+	Comment string   `json:"comment,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+
+	Priority *int      `json:"priority,omitempty"` // MX 和 SRV 记录的优先级
+	Data     *srvValue `json:"data,omitempty"`     // SRV 记录的各个字段
+}
+
+// 设置 MX、SRV 等记录类型特有的字段
+func (r *UpdateDNSRequest) setTypeFields(rec RecordConfig, value string) {
+	switch rec.RecordType {
+	case "MX":
+		priority := rec.Priority
+		r.Priority = &priority
+	case "SRV":
+		if srv, err := parseSRV(value); err == nil {
+			r.Data = &srv
+			r.Priority = &srv.Priority
+			r.Content = cloudflareContent(rec, value)
+		}
+	}
+}
+
+// 记录在 Cloudflare 中的 content，SRV 记录的优先级单独存放，不在 content 中
+func cloudflareContent(rec RecordConfig, value string) string {
+	if rec.RecordType == "SRV" {
+		if fields := strings.Fields(value); len(fields) == 4 {
+			return strings.Join(fields[1:], " ")
+		}
+	}
+	return value
+}
+
+func (p *cloudflareProvider) getZoneID(domainName string) (string, error) {
+	if id, ok := p.zoneIDs[domainName]; ok {
+		return id, nil
+	}
+
+	// 按名称过滤，避免账号下 Zone 较多时目标不在第一页
+	endpoint := "https://api.cloudflare.com/client/v4/zones?name=" + url.QueryEscape(domainName)
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	p.setAuth(req)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", cloudflareStatusError("failed to query zone", resp.StatusCode)
+	}
+
+	var response CloudflareZoneResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", err
+	}
+
+	for _, zone := range response.Result {
+		if zone.Name == domainName {
+			p.zoneIDs[domainName] = zone.Id
+			return zone.Id, nil
+		}
+	}
+
+	return "", fmt.Errorf("zone ID of %s not found: %w", domainName, errZoneNotFound)
+}
+
+// 列出账号下的全部 Zone
+func (p *cloudflareProvider) listZones() ([]string, error) {
+	var names []string
+	for page := 1; ; page++ {
+		var response struct {
+			CloudflareZoneResponse
+			ResultInfo struct {
+				TotalPages int `json:"total_pages"`
+			} `json:"result_info"`
+		}
+		endpoint := fmt.Sprintf("https://api.cloudflare.com/client/v4/zones?page=%d&per_page=50", page)
+		status, err := p.getJSON(endpoint, &response)
+		if err != nil {
+			return nil, err
+		}
+		if status != http.StatusOK {
+			return nil, cloudflareStatusError("failed to list zones", status)
+		}
+
+		for _, zone := range response.Result {
+			names = append(names, zone.Name)
+		}
+		if page >= response.ResultInfo.TotalPages {
+			return names, nil
+		}
+	}
+}
+
+// 列出 Zone 下的全部记录
+func (p *cloudflareProvider) listRecords(domain string) ([]zoneRecord, error) {
+	zoneID, err := p.getZoneID(domain)
+	if err != nil {
+		return nil, err
+	}
+	records, err := p.listZoneRecords(zoneID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]zoneRecord, 0, len(records))
+	for _, r := range records {
+		result = append(result, zoneRecord{ID: r.Id, Record: r.Name, RecordType: r.Type, Value: r.value(), TTL: r.TTL})
+	}
+	return result, nil
+}
+
+// 查询一次记录，检查凭据是否有效、Zone 是否在账号下
+func (p *cloudflareProvider) checkAccess(rec RecordConfig) error {
+	zoneID, err := p.getZoneID(rec.DomainName)
+	if err != nil {
+		return err
+	}
+	_, err = p.getDNSRecords(zoneID, rec.Record, rec.RecordType)
+	return err
+}
+
+// 从长到短尝试记录名的各级后缀，找到账号下存在的 Zone
+func (p *cloudflareProvider) findZone(fqdn string) (string, error) {
+	return findZoneBySuffix(fqdn, func(name string) error {
+		_, err := p.getZoneID(name)
+		return err
+	})
+}
+
+// 查询名称和类型都匹配的记录，逐页查询直到最后一页
+func (p *cloudflareProvider) getDNSRecords(zoneID, recordName, recordType string) ([]CloudflareDNSRecord, error) {
+	var records []CloudflareDNSRecord
+	for page := 1; ; page++ {
+		response, err := p.getDNSRecordsPage(zoneID, recordName, recordType, page)
+		if err != nil {
+			return nil, err
+		}
+
+		// 服务端已按名称和类型过滤，这里再精确匹配一次，防止 A 和 AAAA 混淆
+		for _, r := range response.Result {
+			if strings.EqualFold(r.Name, recordName) && r.Type == recordType {
+				records = append(records, r)
+			}
+		}
+
+		if page >= response.ResultInfo.TotalPages {
+			break
+		}
+	}
+
+	if len(records) == 0 {
+		return nil, fmt.Errorf("DNS record %s (%s) not found: %w", recordName, recordType, errRecordNotFound)
+	}
+
+	return records, nil
+}
+
+// 查询 Zone 下的全部记录
+func (p *cloudflareProvider) listZoneRecords(zoneID string) ([]CloudflareDNSRecord, error) {
+	var records []CloudflareDNSRecord
+	for page := 1; ; page++ {
+		response, err := p.getDNSRecordsPage(zoneID, "", "", page)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, response.Result...)
+		if page >= response.ResultInfo.TotalPages {
+			return records, nil
+		}
+	}
+}
+
+// 筛选出名称和类型都匹配的记录
+func filterCloudflareRecords(records []CloudflareDNSRecord, rec RecordConfig) []CloudflareDNSRecord {
+	var matched []CloudflareDNSRecord
+	for _, r := range records {
+		if strings.EqualFold(r.Name, rec.Record) && r.Type == rec.RecordType {
+			matched = append(matched, r)
+		}
+	}
+	return matched
+}
+
+func (p *cloudflareProvider) getDNSRecordsPage(zoneID, recordName, recordType string, page int) (CloudflareDNSResponse, error) {
+	var response CloudflareDNSResponse
+
+	query := url.Values{}
+	if recordName != "" {
+		query.Set("name", recordName)
+	}
+	if recordType != "" {
+		query.Set("type", recordType)
+	}
+	query.Set("page", strconv.Itoa(page))
+	query.Set("per_page", strconv.Itoa(cloudflarePerPage))
+	endpoint := fmt.Sprintf("https://api.cloudflare.com/client/v4/zones/%s/dns_records?%s", zoneID, query.Encode())
+
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return response, err
+	}
+	p.setAuth(req)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return response, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return response, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return response, cloudflareStatusError("failed to get DNS records", resp.StatusCode)
+	}
+
+	if err := json.Unmarshal(body, &response); err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+// 记录的 TTL，未配置时保持记录现有的 TTL，1 表示由 Cloudflare 自动设置
+func cloudflareTTL(rec RecordConfig, current int) int {
+	if rec.TTL > 0 {
+		return rec.TTL
+	}
+	if current > 0 {
+		return current
+	}
+	return 1
+}
+
+// 记录是否经过 Cloudflare 代理，未配置时保持记录现有的状态
+func cloudflareProxied(rec RecordConfig, current bool) bool {
+	if rec.Proxied != nil {
+		return *rec.Proxied
+	}
+	return current
+}
+
+// 记录的备注，未配置 Remark 时保留原有的备注，PUT 会覆盖整条记录
+func cloudflareComment(rec RecordConfig, current, value string) string {
+	if rec.Remark != "" {
+		return remarkText(rec, value)
+	}
+	return current
+}
+
+// 记录的标签，在原有标签的基础上补上配置的标签，PUT 会覆盖整条记录
+func cloudflareTags(rec RecordConfig, current []string) []string {
+	tags := append([]string(nil), current...)
+	for _, t := range rec.Tags {
+		if !hasCloudflareTags(tags, []string{t}) {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// 检查记录是否带有全部指定的标签
+func hasCloudflareTags(tags, want []string) bool {
+	for _, w := range want {
+		found := false
+		for _, t := range tags {
+			if strings.EqualFold(t, w) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// 配置了标签时，只把带有这些标签的记录当作本程序管理的记录；都没有标签时（例如首次运行）使用全部记录，更新后补上标签
+func taggedCloudflareRecords(records []CloudflareDNSRecord, rec RecordConfig) []CloudflareDNSRecord {
+	if len(rec.Tags) == 0 {
+		return records
+	}
+	var tagged []CloudflareDNSRecord
+	for _, r := range records {
+		if hasCloudflareTags(r.Tags, rec.Tags) {
+			tagged = append(tagged, r)
+		}
+	}
+	if len(tagged) == 0 {
+		return records
+	}
+	return tagged
+}
+
+func (p *cloudflareProvider) createDNSRecord(zoneID string, rec RecordConfig, newIP string) error {
+	url := fmt.Sprintf("https://api.cloudflare.com/client/v4/zones/%s/dns_records", zoneID)
+	createRequest := UpdateDNSRequest{
+		Type:    rec.RecordType,
+		Name:    rec.Record,
+		Content: newIP,
+		TTL:     cloudflareTTL(rec, 0),
+		Proxied: cloudflareProxied(rec, false),
+		Comment: cloudflareComment(rec, "", newIP),
+		Tags:    cloudflareTags(rec, nil),
+	}
+	createRequest.setTypeFields(rec, newIP)
+
+	jsonData, err := json.Marshal(createRequest)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
+	}
+	p.setAuth(req)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return cloudflareStatusError("failed to create DNS record", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (p *cloudflareProvider) deleteDNSRecord(zoneID, recordID string) error {
+	url := fmt.Sprintf("https://api.cloudflare.com/client/v4/zones/%s/dns_records/%s", zoneID, recordID)
+	req, err := http.NewRequest("DELETE", url, nil)
+	if err != nil {
+		return err
+	}
+	p.setAuth(req)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return cloudflareStatusError("failed to delete DNS record", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// 生成更新请求，PUT 会覆盖整条记录，未配置的字段保持原值
+func newCloudflareUpdate(record CloudflareDNSRecord, rec RecordConfig, newIP string) UpdateDNSRequest {
+	updateRequest := UpdateDNSRequest{
+		Type:    rec.RecordType,
+		Name:    rec.Record,
+		Content: newIP,
+		TTL:     cloudflareTTL(rec, record.TTL),
+		Proxied: cloudflareProxied(rec, record.Proxied),
+		Comment: cloudflareComment(rec, record.Comment, newIP),
+		Tags:    cloudflareTags(rec, record.Tags),
+	}
+	updateRequest.setTypeFields(rec, newIP)
+	return updateRequest
+}
+
+func (p *cloudflareProvider) updateDNSRecord(zoneID string, record CloudflareDNSRecord, rec RecordConfig, newIP string) error {
+	url := fmt.Sprintf("https://api.cloudflare.com/client/v4/zones/%s/dns_records/%s", zoneID, record.Id)
+	updateRequest := newCloudflareUpdate(record, rec, newIP)
+
+	jsonData, err := json.Marshal(updateRequest)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("PUT", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
+	}
+	p.setAuth(req)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return cloudflareStatusError("failed to update DNS record", resp.StatusCode)
+	}
+
+	slog.Info("Updated record", "record", rec, "value", newIP)
+	return nil
+}
+
+// 只修改指定字段的更新请求，未配置的字段保持记录原有的值
+type cloudflarePatch struct {
+	Content  string    `json:"content"`
+	TTL      int       `json:"ttl,omitempty"`
+	Proxied  *bool     `json:"proxied,omitempty"`
+	Comment  string    `json:"comment,omitempty"`
+	Priority *int      `json:"priority,omitempty"`
+	Data     *srvValue `json:"data,omitempty"`
+}
+
+// 用缓存的 Zone ID 和记录 ID 直接修改记录，不知道原有的值，所以用 PATCH 只修改配置的字段。
+// 值与上次发布的相同时仍然发送修改请求，以便发现控制台中被删除或改动的记录；
+// 配置了标签时需要合并原有的标签，仍然查询后更新。
+// 记录已不存在时回退到查询后更新，其余错误直接报告
+func (p *cloudflareProvider) updateByID(j *updateJob) bool {
+	rec := j.rec
+	if j.ids.zoneID == "" || len(rec.Tags) > 0 {
+		return false
+	}
+
+	full := UpdateDNSRequest{Content: j.newValue}
+	full.setTypeFields(rec, j.newValue)
+	patch := cloudflarePatch{Content: full.Content, Proxied: rec.Proxied, Priority: full.Priority, Data: full.Data}
+	if rec.TTL > 0 {
+		patch.TTL = rec.TTL
+	}
+	if rec.Remark != "" {
+		patch.Comment = remarkText(rec, j.newValue)
+	}
+
+	jsonData, err := json.Marshal(patch)
+	if err != nil {
+		return false
+	}
+
+	url := fmt.Sprintf("https://api.cloudflare.com/client/v4/zones/%s/dns_records/%s", j.ids.zoneID, j.ids.recordID)
+	req, err := http.NewRequest("PATCH", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return false
+	}
+	p.setAuth(req)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		j.err = fmt.Errorf("failed to update DNS record: %w", err)
+		return true
+	}
+	defer resp.Body.Close()
+
+	// 记录或 Zone 已被删除时返回 404（记录 ID 格式不对时为 400）
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusBadRequest:
+		slog.Warn("Cached record ID is not usable, looking up the record", "record", rec, "id", j.ids.recordID, "status", resp.StatusCode)
+		return false
+	default:
+		j.err = cloudflareStatusError("failed to update DNS record", resp.StatusCode)
+		return true
+	}
+
+	// 响应中是修改后的记录，未配置 Proxied 时从中得知记录是否经过代理
+	var result struct {
+		Result CloudflareDNSRecord `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err == nil {
+		p.proxied[rec.key()] = result.Result.Proxied
+	} else {
+		p.proxied[rec.key()] = cloudflareProxied(rec, j.proxied)
+	}
+
+	if j.oldValue == j.newValue {
+		slog.Info("IP address is already up to date", "record", rec, "value", j.newValue)
+	} else {
+		slog.Info("Updated record", "record", rec, "value", j.newValue)
+	}
+	p.zoneIDs[rec.DomainName] = j.ids.zoneID
+	p.ids[rec.key()] = j.ids
+	j.current = j.oldValue
+	return true
+}
+
+func (p *cloudflareProvider) cachedIDs(rec RecordConfig) recordIDs {
+	return p.ids[rec.key()]
+}
+
+func (p *cloudflareProvider) isProxied(rec RecordConfig) bool {
+	return p.proxied[rec.key()]
+}
+
+// 批量接口中的一条覆盖更新
+type cloudflareBatchPut struct {
+	Id string `json:"id"`
+	UpdateDNSRequest
+}
+
+// 通过批量接口一次提交同一 Zone 的多条更新，Cloudflare 保证全部成功或全部失败
+func (p *cloudflareProvider) batchPut(zoneID string, puts []cloudflareBatchPut) error {
+	url := fmt.Sprintf("https://api.cloudflare.com/client/v4/zones/%s/dns_records/batch", zoneID)
+	jsonData, err := json.Marshal(map[string][]cloudflareBatchPut{"puts": puts})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
+	}
+	p.setAuth(req)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return cloudflareStatusError("failed to batch update DNS records", resp.StatusCode)
+	}
+
+	slog.Info("Batch updated DNS records", "count", len(puts))
+	return nil
+}
+
+// 批量更新，每个 Zone 只查询一次全部记录，需要修改的记录合并为一次批量请求
+func (p *cloudflareProvider) updateBatch(jobs []*updateJob) {
+	byZone := make(map[string][]*updateJob)
+	var zones []string
+	for _, j := range jobs {
+		zoneID, err := p.getZoneID(j.rec.DomainName)
+		if err != nil {
+			j.err = fmt.Errorf("failed to get zone ID: %w", err)
+			continue
+		}
+		if _, ok := byZone[zoneID]; !ok {
+			zones = append(zones, zoneID)
+		}
+		byZone[zoneID] = append(byZone[zoneID], j)
+	}
+
+	for _, zoneID := range zones {
+		records, err := p.listZoneRecords(zoneID)
+		if err != nil {
+			for _, j := range byZone[zoneID] {
+				j.err = fmt.Errorf("failed to get DNS records: %w", err)
+			}
+			continue
+		}
+
+		var puts []cloudflareBatchPut
+		var pending []*updateJob
+		for _, j := range byZone[zoneID] {
+			queued := false
+			collect := func(_ string, record CloudflareDNSRecord, rec RecordConfig, value string) error {
+				puts = append(puts, cloudflareBatchPut{Id: record.Id, UpdateDNSRequest: newCloudflareUpdate(record, rec, value)})
+				queued = true
+				return nil
+			}
+			j.current, j.err = p.updateMatched(zoneID, j.rec, filterCloudflareRecords(records, j.rec), j.newValue, j.oldValue, collect)
+			if queued && j.err == nil {
+				pending = append(pending, j)
+			}
+		}
+
+		if len(puts) == 0 {
+			continue
+		}
+		if err := p.batchPut(zoneID, puts); err != nil {
+			for _, j := range pending {
+				j.err = err
+			}
+		}
+	}
+}
+
+func (p *cloudflareProvider) createRecord(rec RecordConfig, value string) error {
+	zoneID, err := p.getZoneID(rec.DomainName)
+	if err != nil {
+		return fmt.Errorf("failed to get zone ID: %w", err)
+	}
+
+	records, err := p.getDNSRecords(zoneID, rec.Record, rec.RecordType)
+	if err != nil && !errors.Is(err, errRecordNotFound) {
+		return fmt.Errorf("failed to get DNS records: %w", err)
+	}
+	for _, r := range records {
+		if r.Content == cloudflareContent(rec, value) {
+			slog.Info("The DNS record already exists with the same value", "name", r.Name, "value", value)
+			return nil
+		}
+	}
+
+	if err := p.createDNSRecord(zoneID, rec, value); err != nil {
+		return fmt.Errorf("failed to create DNS record: %w", err)
+	}
+	slog.Info("Created record", "record", rec, "value", value)
+	return nil
+}
+
+func (p *cloudflareProvider) deleteRecords(rec RecordConfig, value string) error {
+	zoneID, err := p.getZoneID(rec.DomainName)
+	if err != nil {
+		return fmt.Errorf("failed to get zone ID: %w", err)
+	}
+
+	records, err := p.getDNSRecords(zoneID, rec.Record, rec.RecordType)
+	if errors.Is(err, errRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get DNS records: %w", err)
+	}
+
+	for _, r := range records {
+		if value != "" && r.Content != cloudflareContent(rec, value) {
+			continue
+		}
+		if err := p.deleteDNSRecord(zoneID, r.Id); err != nil {
+			return fmt.Errorf("failed to delete DNS record: %w", err)
+		}
+		slog.Info("Deleted record", "name", r.Name, "value", r.Content)
+	}
+	return nil
+}
+
+// Cloudflare 没有停用记录的功能，停用时删除记录，启用时用上次的值重新创建
+func (p *cloudflareProvider) setEnabled(rec RecordConfig, enabled bool, value string) error {
+	if !enabled {
+		return p.deleteRecords(rec, "")
+	}
+	if value == "" {
+		return fmt.Errorf("the previous record value is unknown, set CreateMissing and run the update again")
+	}
+	return p.createRecord(rec, value)
+}
+
+// 更新 Cloudflare DNS 记录，返回记录原来的值
+func (p *cloudflareProvider) updateRecord(rec RecordConfig, externalIP, oldIP string) (string, error) {
+	domainName := rec.DomainName
+
+	zoneID, err := p.getZoneID(domainName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get zone ID: %w", err)
+	}
+
+	slog.Debug("Found zone ID", "domain", domainName, "zone_id", zoneID)
+
+	records, err := p.getDNSRecords(zoneID, rec.Record, rec.RecordType)
+	if err != nil && !errors.Is(err, errRecordNotFound) {
+		return "", fmt.Errorf("failed to get DNS records: %w", err)
+	}
+
+	return p.updateMatched(zoneID, rec, records, externalIP, oldIP, p.updateDNSRecord)
+}
+
+// 用查询到的记录更新，需要修改的记录交给 put 处理，返回记录原来的值
+func (p *cloudflareProvider) updateMatched(zoneID string, rec RecordConfig, records []CloudflareDNSRecord, externalIP, oldIP string,
+	put func(zoneID string, record CloudflareDNSRecord, rec RecordConfig, newIP string) error) (string, error) {
+	recordName := rec.Record
+	delete(p.ids, rec.key())
+	delete(p.proxied, rec.key())
+
+	if len(records) == 0 {
+		if !rec.CreateMissing {
+			return "", fmt.Errorf("failed to get DNS records: DNS record %s (%s) not found: %w", recordName, rec.RecordType, errRecordNotFound)
+		}
+		slog.Info("DNS record does not exist, creating it", "name", recordName)
+		if err := p.createDNSRecord(zoneID, rec, externalIP); err != nil {
+			return "", fmt.Errorf("failed to create DNS record: %w", err)
+		}
+		slog.Info("Created record", "record", rec, "value", externalIP)
+		p.proxied[rec.key()] = cloudflareProxied(rec, false)
+		return "", nil
+	}
+
+	records = taggedCloudflareRecords(records, rec)
+	values := make([]string, len(records))
+	for i, r := range records {
+		values[i] = r.value()
+	}
+
+	// 删除重复的记录，只保留一条
+	if len(records) > 1 && rec.MultiRecordPolicy == policyCollapse {
+		keep := canonicalIndex(values, externalIP)
+		for i, r := range records {
+			if i == keep {
+				continue
+			}
+			if err := p.deleteDNSRecord(zoneID, r.Id); err != nil {
+				return "", fmt.Errorf("failed to delete duplicate DNS record: %w", err)
+			}
+			slog.Info("Deleted duplicate record", "name", r.Name, "value", r.Content)
+		}
+		records = records[keep : keep+1]
+		values = values[keep : keep+1]
+	}
+
+	targets, err := selectTargets(values, oldIP, rec.MultiRecordPolicy)
+	if err != nil {
+		return "", err
+	}
+
+	for _, i := range targets {
+		record := records[i]
+		slog.Debug("Current DNS record value", "name", recordName, "value", record.Content)
+
+		if !rec.force && cloudflareContent(rec, externalIP) == record.Content && cloudflareProxied(rec, record.Proxied) == record.Proxied &&
+			hasCloudflareTags(record.Tags, rec.Tags) {
+			slog.Info("IP address is already up to date", "name", recordName, "value", record.Content)
+			continue
+		}
+
+		slog.Info("IP address changed, updating the DNS record", "name", recordName, "old", record.Content, "new", externalIP)
+		if err := put(zoneID, record, rec, externalIP); err != nil {
+			return "", fmt.Errorf("failed to update DNS record: %w", err)
+		}
+	}
+
+	if len(targets) == 1 {
+		p.ids[rec.key()] = recordIDs{zoneID: zoneID, recordID: records[targets[0]].Id}
+	}
+	p.proxied[rec.key()] = cloudflareProxied(rec, records[targets[0]].Proxied)
+	return records[targets[0]].value(), nil
+}
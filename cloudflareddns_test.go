@@ -0,0 +1,82 @@
+package main
+
+import "testing"
+
+func TestCloudflareTTL(t *testing.T) {
+	tests := []struct {
+		ttl     int // 配置的 TTL
+		current int // 记录现有的 TTL，新建时为 0
+		want    int
+	}{
+		{0, 0, 1},
+		{600, 0, 600},
+		{0, 300, 300},
+		{600, 300, 600},
+		{0, 1, 1},
+	}
+	for _, tt := range tests {
+		if got := cloudflareTTL(RecordConfig{TTL: tt.ttl}, tt.current); got != tt.want {
+			t.Errorf("cloudflareTTL(%d, %d) = %d, want %d", tt.ttl, tt.current, got, tt.want)
+		}
+	}
+}
+
+func TestNewCloudflareUpdate(t *testing.T) {
+	yes := true
+	priority := 10
+	existing := CloudflareDNSRecord{
+		Id: "r1", Name: "home.example.com", Type: "A", Content: "192.0.2.1",
+		TTL: 300, Proxied: true, Comment: "set in the console", Tags: []string{"team:ops"},
+	}
+	tests := []struct {
+		name   string
+		record CloudflareDNSRecord
+		rec    RecordConfig
+		value  string
+		check  func(r UpdateDNSRequest) bool
+	}{
+		{
+			name:   "unset fields keep the existing values",
+			record: existing,
+			rec:    RecordConfig{Record: "home.example.com", RecordType: "A"},
+			value:  "203.0.113.7",
+			check: func(r UpdateDNSRequest) bool {
+				return r.Content == "203.0.113.7" && r.TTL == 300 && r.Proxied &&
+					r.Comment == "set in the console" && len(r.Tags) == 1 && r.Tags[0] == "team:ops"
+			},
+		},
+		{
+			name:   "configured fields win",
+			record: existing,
+			rec:    RecordConfig{Record: "home.example.com", RecordType: "A", TTL: 60, Proxied: new(bool), Remark: "ddns", Tags: []string{"ddns"}},
+			value:  "203.0.113.7",
+			check: func(r UpdateDNSRequest) bool {
+				return r.TTL == 60 && !r.Proxied && r.Comment == "ddns" && len(r.Tags) == 2
+			},
+		},
+		{
+			name:   "auto TTL",
+			record: CloudflareDNSRecord{Type: "AAAA", TTL: 1},
+			rec:    RecordConfig{Record: "home.example.com", RecordType: "AAAA", Proxied: &yes},
+			value:  "2001:db8::1",
+			check: func(r UpdateDNSRequest) bool {
+				return r.TTL == 1 && r.Proxied && r.Priority == nil && r.Data == nil
+			},
+		},
+		{
+			name:   "SRV priority and data",
+			record: CloudflareDNSRecord{Type: "SRV", TTL: 300, Priority: &priority},
+			rec:    RecordConfig{Record: "_sip._tcp.example.com", RecordType: "SRV"},
+			value:  "10 5 5060 sip.example.com",
+			check: func(r UpdateDNSRequest) bool {
+				return r.Content == "5 5060 sip.example.com" && r.Priority != nil && *r.Priority == 10 &&
+					r.Data != nil && r.Data.Port == 5060 && r.TTL == 300
+			},
+		},
+	}
+	for _, tt := range tests {
+		if got := newCloudflareUpdate(tt.record, tt.rec, tt.value); !tt.check(got) {
+			t.Errorf("%s: got %+v", tt.name, got)
+		}
+	}
+}
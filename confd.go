@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 合并时追加而不是覆盖的列表字段，每个文件可以只写自己的几条记录
+var appendedConfigKeys = map[string]bool{"Records": true, "Domains": true}
+
+// 配置目录（conf.d）中的配置文件，按文件名排序，例如 00-global.yaml、10-home.json。
+// 忽略隐藏文件和其他扩展名的文件
+func configDirFiles(dir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || strings.HasPrefix(name, ".") || !isConfigFileName(name) {
+			continue
+		}
+		files = append(files, filepath.Join(dir, name))
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// 是否为支持的配置文件扩展名
+func isConfigFileName(name string) bool {
+	switch strings.ToLower(filepath.Ext(strings.TrimSuffix(name, ".age"))) {
+	case ".json", ".yaml", ".yml", ".toml":
+		return true
+	}
+	return false
+}
+
+// 按顺序合并配置目录中的文件：Records 和 Domains 追加，Profiles 按名称合并，其余字段后面的文件覆盖前面的
+func readConfigDir(dir string, opts loadOptions) ([]byte, error) {
+	files, err := configDirFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no config files in %s", dir)
+	}
+
+	merged := make(map[string]json.RawMessage)
+	for _, file := range files {
+		data, err := readConfigJSON(file, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		var part map[string]json.RawMessage
+		if err := json.Unmarshal(data, &part); err != nil {
+			return nil, fmt.Errorf("%s: failed to unmarshal config: %w", file, err)
+		}
+		for k, v := range part {
+			if merged[k], err = mergeConfigValue(k, merged[k], v); err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+		}
+	}
+	return json.Marshal(merged)
+}
+
+// 合并同一个字段的两个值
+func mergeConfigValue(key string, old, value json.RawMessage) (json.RawMessage, error) {
+	if old == nil {
+		return value, nil
+	}
+	switch {
+	case appendedConfigKeys[key]:
+		var a, b []json.RawMessage
+		if err := json.Unmarshal(old, &a); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", key, err)
+		}
+		if err := json.Unmarshal(value, &b); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", key, err)
+		}
+		return json.Marshal(append(a, b...))
+	case key == profilesKey:
+		var a, b map[string]json.RawMessage
+		if err := json.Unmarshal(old, &a); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", key, err)
+		}
+		if err := json.Unmarshal(value, &b); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", key, err)
+		}
+		for name, profile := range b {
+			a[name] = profile
+		}
+		return json.Marshal(a)
+	default:
+		return value, nil
+	}
+}
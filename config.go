@@ -0,0 +1,486 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// 配置结构体
+type Config struct {
+	AccessKeyID     string `json:"AccessKeyID"`
+	AccessKeySecret string `json:"AccessKeySecret"`
+	DomainName      string `json:"DomainName"`
+	Record          string `json:"Record"`
+	RecordType      string `json:"RecordType"`
+
+	// 从文件读取凭据，例如 Docker/Kubernetes 挂载的 /run/secrets/aliyun_secret，与上面的字段二选一
+	AccessKeyIDFile     string `json:"AccessKeyIDFile"`
+	AccessKeySecretFile string `json:"AccessKeySecretFile"`
+
+	// STS 临时凭据的 SecurityToken，与 AccessKeyID/AccessKeySecret 一起使用。
+	// 从文件读取时，定时运行模式下每次更新前重新读取，外部程序轮换凭据后不需要重启
+	SecurityToken     string `json:"SecurityToken"`
+	SecurityTokenFile string `json:"SecurityTokenFile"`
+
+	// 使用阿里云 CLI 配置文件 ~/.aliyun/config.json 中的凭据，填配置名称，"current" 表示 CLI 当前的配置
+	AliyunProfile string `json:"AliyunProfile"`
+
+	// 在阿里云 ECS 上运行时使用实例绑定的 RAM 角色获取临时凭据，填角色名称或 "auto"，不需要 AccessKey
+	RAMRole string `json:"RAMRole"`
+
+	// 阿里云接口的地域，例如国际站账号填 ap-southeast-1。留空时使用 cn-hangzhou 和中心地址 alidns.aliyuncs.com，
+	// 填写后使用该地域的地址 alidns.<地域>.aliyuncs.com
+	AliyunRegion string `json:"AliyunRegion"`
+
+	// 访问阿里云 DNS 接口的网络：public（默认）或 vpc，只能访问 VPC 内网的 ECS 填 vpc，使用 alidns-vpc.<地域>.aliyuncs.com
+	AliyunNetwork string `json:"AliyunNetwork"`
+
+	// 阿里云 DNS 接口的地址，例如 alidns.cn-hongkong.aliyuncs.com，填写后忽略 AliyunRegion 和 AliyunNetwork 推算的地址
+	AliyunEndpoint string `json:"AliyunEndpoint"`
+
+	// Cloudflare 配置，填写 CF_API_TOKEN（或 CF_API_KEY）时使用 Cloudflare 更新
+	CFAPIToken   string `json:"CF_API_TOKEN"`
+	CFDomainName string `json:"DOMAIN_NAME"`
+	CFRecordName string `json:"RECORD_NAME"`
+
+	// 从文件读取 Cloudflare API Token，与 CF_API_TOKEN 二选一
+	CFAPITokenFile string `json:"CF_API_TOKEN_FILE"`
+
+	// 旧版的 Global API Key 和账号邮箱，不使用 API Token 时填写
+	CFAPIKey     string `json:"CF_API_KEY"`
+	CFEmail      string `json:"CF_API_EMAIL"`
+	CFAPIKeyFile string `json:"CF_API_KEY_FILE"`
+
+	// 地址过滤，CIDR 格式，例如 ["10.0.0.0/8"]
+	AllowCIDRs []string `json:"AllowCIDRs"`
+	DenyCIDRs  []string `json:"DenyCIDRs"`
+
+	// IP 来源，按顺序尝试，未配置时使用 icanhazip.com
+	IPSources   []SourceConfig `json:"IPSources"`
+	IPv6Sources []SourceConfig `json:"IPv6Sources"`
+
+	// 要更新的记录列表，配置 Records 或 Domains 后忽略上面单条记录的字段
+	Records []RecordConfig `json:"Records"`
+	Domains []DomainConfig `json:"Domains"`
+
+	// 所有记录的默认设置，例如 TTL、记录类型、服务商、Proxied、IP 来源和加权解析，记录中填写的字段优先。
+	// 其中的 IPSources 与记录单独配置的来源相同，不经过全局的地址过滤
+	Defaults *RecordConfig `json:"Defaults"`
+
+	// 同时更新 A 记录（IPv4）和 AAAA 记录（IPv6）
+	DualStack bool `json:"DualStack"`
+
+	// 记录不存在时自动创建，对所有记录生效
+	CreateMissing bool `json:"CreateMissing"`
+
+	// 更新前删除同名同类型的重复记录，对所有记录生效
+	RemoveDuplicates bool `json:"RemoveDuplicates"`
+
+	// 同名同类型有多条记录时的处理策略：first（默认）、all、match 或 collapse
+	MultiRecordPolicy string `json:"MultiRecordPolicy"`
+
+	// 更新记录时写入的备注，对所有记录生效，例如 "managed by aliDDNS, last update {date} host={host}"
+	Remark string `json:"Remark"`
+
+	// Cloudflare 记录的标签，对所有记录生效，例如 ["ddns-managed"]
+	Tags []string `json:"Tags"`
+
+	// 更新后检查新值是否已经在权威服务器或公共 DNS 上生效，留空时不检查
+	Verify *VerifyConfig `json:"Verify"`
+
+	// 记录的值变化后发送通知，例如企业微信群机器人，可以配置多个
+	Notify []NotifyConfig `json:"Notify"`
+
+	// 更新记录前后运行的命令，例如重启 VPN、刷新防火墙规则，记录和新旧地址通过 DDNS_ 开头的环境变量传入
+	PreUpdate  string `json:"PreUpdate"`
+	PostUpdate string `json:"PostUpdate"`
+
+	// 状态文件，保存上次发布的地址等信息，留空时不保存
+	StateFile string `json:"StateFile"`
+
+	// 把每次检查的结果推送到 StatsD 或 InfluxDB，可以配置多个，见 metricspush.go
+	Metrics []MetricsConfig `json:"Metrics"`
+
+	// 审计日志文件，每次调用服务商接口追加一行 JSON（地址、去掉签名和凭据的参数、状态码、请求 ID），留空时不记录
+	AuditLog string `json:"AuditLog"`
+
+	// 状态输出文件，每次检查后写入当前地址、每条记录的状态和错误（JSON），留空时不写入
+	StatusFile string `json:"StatusFile"`
+
+	// 更新历史文件（JSON Lines），每次更新记录追加一行，可以用 history 命令查看，留空时不记录
+	HistoryFile string `json:"HistoryFile"`
+
+	// 常驻模式的检查间隔，例如 "5m"，留空时只运行一次
+	Interval string `json:"Interval"`
+
+	// 超过多久没有成功的检查视为过期，例如 "1h"，见 status 命令。留空时为检查间隔的 3 倍（至少 5 分钟），
+	// 没有设置间隔时为 1 小时
+	StaleAfter string `json:"StaleAfter"`
+
+	// 单次网络请求（查询外网 IP、调用服务商接口）的超时，默认 "30s"；
+	// 一次检查（检测地址并更新所有记录）的总时限，默认 "5m"，超时后未完成的请求被取消
+	RequestTimeout string `json:"RequestTimeout"`
+	RunTimeout     string `json:"RunTimeout"`
+
+	// 出站代理，例如 "http://127.0.0.1:8080" 或 "socks5://127.0.0.1:1080"，"direct" 表示直连。
+	// IPProxy 用于查询外网 IP，APIProxy 用于调用服务商接口、发送通知和推送指标；留空时使用环境变量 HTTPS_PROXY/HTTP_PROXY/ALL_PROXY
+	IPProxy  string `json:"IPProxy"`
+	APIProxy string `json:"APIProxy"`
+
+	// 解析服务商接口、IP 查询地址和代理的域名使用的 DNS 服务器，按顺序尝试，留空时使用本机的 DNS。
+	// 可以填 IP 地址（默认 53 端口）或 DoH 地址，例如 ["223.5.5.5", "https://1.1.1.1/dns-query"]
+	DNSServers []string `json:"DNSServers"`
+
+	// 出站 HTTPS 连接的 CA 证书、客户端证书和最低 TLS 版本，留空时使用系统的根证书，见 tls.go
+	TLS *TLSConfig `json:"TLS"`
+
+	// 查询外网 IP 和调用服务商接口遇到临时错误时的重试，留空时最多尝试 3 次，见 retry.go
+	Retry *RetryConfig `json:"Retry"`
+
+	// 常驻模式下 HTTP 服务的监听地址，例如 ":9876"，提供 Prometheus 指标 /metrics 和健康检查 /healthz、/readyz，留空时不启动
+	Listen string `json:"Listen"`
+
+	// 命令行参数对每条记录的覆盖（服务商、类型和 TTL），见 applyFlags
+	recordOverride RecordConfig
+
+	// 服务商接口共用的 Transport，由 newUpdater 创建，为空时每个服务商各自创建，见 newProviderTransport
+	apiTransport *http.Transport
+}
+
+// 判断过期的时长，配置已经检查过，无效的值按默认处理
+func (c Config) staleAfter() time.Duration {
+	if d, err := time.ParseDuration(c.StaleAfter); err == nil && d > 0 {
+		return d
+	}
+	if d, err := time.ParseDuration(c.Interval); err == nil && d > 0 {
+		if 3*d < minStaleAfter {
+			return minStaleAfter
+		}
+		return 3 * d
+	}
+	return time.Hour
+}
+
+// 单条记录配置
+type RecordConfig struct {
+	Provider   string   `json:"Provider"`   // aliyun（默认）、cloudflare 或 pvtz（阿里云内网 DNS）
+	Providers  []string `json:"Providers"`  // 同时推送到多个服务商，配置后忽略 Provider
+	DomainName string   `json:"DomainName"` // 阿里云为主域名，Cloudflare 为 Zone 名称
+	Record     string   `json:"Record"`     // 阿里云为主机记录（RR），Cloudflare 为完整记录名
+	RecordType string   `json:"RecordType"` // A（默认）、AAAA、CNAME、NS、MX、SRV 或 TXT
+	Value      string   `json:"Value"`      // 非地址类记录的值，A/AAAA 记录的值来自 IP 检测
+	Priority   int      `json:"Priority"`   // MX 记录的优先级
+	TTL        int      `json:"TTL"`        // 留空时使用服务商默认值
+	Proxied    *bool    `json:"Proxied"`    // Cloudflare 是否代理，留空时保持记录现有的状态
+
+	// 记录不存在时自动创建
+	CreateMissing bool `json:"CreateMissing"`
+
+	// 更新前删除同名同类型的重复记录，等同于 MultiRecordPolicy 为 collapse
+	RemoveDuplicates bool `json:"RemoveDuplicates"`
+
+	// 同名同类型有多条记录时的处理策略
+	MultiRecordPolicy string `json:"MultiRecordPolicy"`
+
+	// 更新记录时写入的备注（阿里云 Remark / Cloudflare comment），留空时不修改
+	Remark string `json:"Remark"`
+
+	// Cloudflare 记录的标签，创建或更新时补上；同名记录有多条时优先使用带有这些标签的记录
+	Tags []string `json:"Tags"`
+
+	// 阿里云加权解析，每项对应一个出口（例如两条宽带），各自检测地址并按权重发布
+	Weights []WeightConfig `json:"Weights"`
+
+	// 这条记录单独使用的 IP 来源，例如内网记录使用 local 来源发布局域网地址。
+	// 配置后不使用全局的 IPSources/IPv6Sources，也不经过全局的 AllowCIDRs/DenyCIDRs
+	IPSources []SourceConfig `json:"IPSources"`
+
+	// 值相同时也调用接口更新，用于修复在控制台手动修改过的 TTL、代理等设置，由 -force 设置
+	force bool
+}
+
+// 加权解析中的一个出口
+type WeightConfig struct {
+	IPSources []SourceConfig `json:"IPSources"` // 这个出口的 IP 来源
+	Weight    int            `json:"Weight"`    // 权重，1-100，留空时为 1
+}
+
+// 域名配置，域名下的记录继承域名和服务商
+type DomainConfig struct {
+	Provider   string         `json:"Provider"`
+	DomainName string         `json:"DomainName"`
+	Records    []RecordConfig `json:"Records"`
+}
+
+func (r RecordConfig) String() string {
+	return fmt.Sprintf("%s %s/%s (%s)", r.Provider, r.Record, r.DomainName, r.RecordType)
+}
+
+// 记录的完整域名
+func (r RecordConfig) fqdn() string {
+	if r.Provider == "cloudflare" {
+		return r.Record
+	}
+	if r.Record == "@" || r.Record == "" {
+		return r.DomainName
+	}
+	return r.Record + "." + r.DomainName
+}
+
+// 记录在状态文件中的键
+func (r RecordConfig) key() string {
+	return r.Provider + "/" + r.DomainName + "/" + r.Record + "/" + r.RecordType
+}
+
+// 配置文件的读取选项
+type loadOptions struct {
+	optional bool     // 配置文件不存在时只使用环境变量
+	age      *ageKeys // 解密 age 加密的配置文件使用的身份
+	profile  string   // 使用配置文件中 Profiles 下的哪个配置，留空时只使用顶层字段
+}
+
+// 读取配置文件，按扩展名识别格式：.yaml/.yml 为 YAML，.toml 为 TOML，其余为 JSON。
+// 用 age 加密的文件先解密，格式按去掉 .age 后的扩展名识别。filename 也可以是远程地址，见 remote.go。
+// 环境变量优先于配置文件
+func loadConfig(filename string, opts loadOptions) (Config, error) {
+	var config Config
+	data, err := readConfigJSON(filename, opts)
+	switch {
+	case err == nil:
+		data, err = selectProfile(data, opts.profile)
+		if err != nil {
+			return config, err
+		}
+		data, err = expandEnvRefs(data)
+		if err != nil {
+			return config, err
+		}
+		if err := unmarshalStrict(data, &config, "config"); err != nil {
+			return config, fmt.Errorf("failed to unmarshal config: %w", err)
+		}
+	case opts.optional && os.IsNotExist(err):
+	default:
+		return config, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if err := config.applyEnv(); err != nil {
+		return config, err
+	}
+	if err := config.readSecretFiles(); err != nil {
+		return config, err
+	}
+	if err := config.normalizeIDN(); err != nil {
+		return config, err
+	}
+	return config, nil
+}
+
+// 读取配置并转换为 JSON。filename 为目录时按文件名顺序合并目录下的所有配置文件
+func readConfigJSON(filename string, opts loadOptions) ([]byte, error) {
+	if info, err := os.Stat(filename); err == nil && info.IsDir() {
+		return readConfigDir(filename, opts)
+	}
+
+	data, err := readConfigSource(filename)
+	if err != nil {
+		return nil, err
+	}
+	if isAgeEncrypted(data) {
+		if data, err = decryptConfig(data, opts.age); err != nil {
+			return nil, err
+		}
+	}
+	return configToJSON(strings.TrimSuffix(configFormatName(filename), ".age"), data)
+}
+
+// 把其他格式的配置转换为 JSON，所有格式共用 Config 的 JSON 字段名
+func configToJSON(filename string, data []byte) ([]byte, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		var v interface{}
+		if err := yaml.Unmarshal(data, &v); err != nil {
+			return nil, fmt.Errorf("failed to parse YAML config: %w", err)
+		}
+		return json.Marshal(v)
+	case ".toml":
+		var v map[string]interface{}
+		if _, err := toml.Decode(string(data), &v); err != nil {
+			return nil, fmt.Errorf("failed to parse TOML config: %w", err)
+		}
+		return json.Marshal(v)
+	default:
+		return data, nil
+	}
+}
+
+// 本次需要更新的记录，未配置 Records 和 Domains 时使用单条记录的字段
+func (c Config) records() []RecordConfig {
+	records := append([]RecordConfig(nil), c.Records...)
+	for _, d := range c.Domains {
+		for _, r := range d.Records {
+			if r.Provider == "" {
+				r.Provider = d.Provider
+			}
+			if r.DomainName == "" {
+				r.DomainName = d.DomainName
+			}
+			records = append(records, r)
+		}
+	}
+	if len(records) == 0 {
+		if c.CFAPIToken != "" || c.CFAPIKey != "" {
+			records = []RecordConfig{{Provider: "cloudflare", DomainName: c.CFDomainName, Record: c.CFRecordName, RecordType: c.RecordType}}
+		} else {
+			records = []RecordConfig{{Provider: "aliyun", DomainName: c.DomainName, Record: c.Record, RecordType: c.RecordType}}
+		}
+	}
+
+	result := make([]RecordConfig, 0, len(records))
+	for _, r := range records {
+		if c.Defaults != nil {
+			r.applyDefaults(*c.Defaults)
+		}
+		// 命令行参数优先
+		if c.recordOverride.Provider != "" {
+			r.Provider, r.Providers = c.recordOverride.Provider, nil
+		}
+		if c.recordOverride.RecordType != "" {
+			r.RecordType = c.recordOverride.RecordType
+		}
+		if c.recordOverride.TTL > 0 {
+			r.TTL = c.recordOverride.TTL
+		}
+		r.force = c.recordOverride.force
+		if r.Provider == "" {
+			r.Provider = "aliyun"
+		}
+		r.Provider = strings.ToLower(r.Provider)
+		if r.RecordType == "" {
+			r.RecordType = "A"
+		}
+		r.RecordType = strings.ToUpper(r.RecordType)
+		r.CreateMissing = r.CreateMissing || c.CreateMissing
+		r.RemoveDuplicates = r.RemoveDuplicates || c.RemoveDuplicates
+		if r.MultiRecordPolicy == "" {
+			r.MultiRecordPolicy = c.MultiRecordPolicy
+		}
+		if r.MultiRecordPolicy == "" && r.RemoveDuplicates {
+			r.MultiRecordPolicy = policyCollapse
+		}
+		r.MultiRecordPolicy = strings.ToLower(r.MultiRecordPolicy)
+		if r.Remark == "" {
+			r.Remark = c.Remark
+		}
+		if len(r.Tags) == 0 {
+			r.Tags = c.Tags
+		}
+		r.DomainName = strings.TrimSuffix(r.DomainName, ".")
+		r.Record = strings.TrimSuffix(r.Record, ".")
+
+		for _, pr := range r.expandProviders() {
+			// 记录名可以写主机记录、@ 或完整域名，统一转换为服务商需要的形式；
+			// 未配置域名时在运行时根据完整域名查找
+			if pr.DomainName != "" {
+				pr.Record = providerRecordName(pr.Provider, pr.Record, pr.DomainName)
+			}
+			result = append(result, pr)
+
+			// 双栈时为每条 A 记录补一条同名的 AAAA 记录
+			if c.DualStack && pr.RecordType == "A" {
+				pr.RecordType = "AAAA"
+				result = append(result, pr)
+			}
+		}
+	}
+	return result
+}
+
+// 用默认设置补齐记录中没有填写的字段
+func (r *RecordConfig) applyDefaults(d RecordConfig) {
+	if r.Provider == "" && len(r.Providers) == 0 {
+		r.Provider, r.Providers = d.Provider, d.Providers
+	}
+	if r.DomainName == "" {
+		r.DomainName = d.DomainName
+	}
+	if r.RecordType == "" {
+		r.RecordType = d.RecordType
+	}
+	if r.Priority == 0 {
+		r.Priority = d.Priority
+	}
+	if r.TTL == 0 {
+		r.TTL = d.TTL
+	}
+	if r.Proxied == nil {
+		r.Proxied = d.Proxied
+	}
+	r.CreateMissing = r.CreateMissing || d.CreateMissing
+	r.RemoveDuplicates = r.RemoveDuplicates || d.RemoveDuplicates
+	if r.MultiRecordPolicy == "" {
+		r.MultiRecordPolicy = d.MultiRecordPolicy
+	}
+	if r.Remark == "" {
+		r.Remark = d.Remark
+	}
+	if len(r.Tags) == 0 {
+		r.Tags = d.Tags
+	}
+	// IP 来源和加权解析只用于地址类记录，同时配置的 CNAME、TXT 等记录不继承
+	addr := r.RecordType == "" || isAddressType(strings.ToUpper(r.RecordType))
+	if addr && len(r.IPSources) == 0 && len(r.Weights) == 0 {
+		r.IPSources = d.IPSources
+	}
+	if addr && len(r.Weights) == 0 && len(r.IPSources) == 0 {
+		r.Weights = d.Weights
+	}
+}
+
+// 把配置了多个服务商的记录拆成每个服务商一条
+func (r RecordConfig) expandProviders() []RecordConfig {
+	if len(r.Providers) == 0 {
+		return []RecordConfig{r}
+	}
+
+	records := make([]RecordConfig, 0, len(r.Providers))
+	for _, name := range r.Providers {
+		pr := r
+		pr.Provider = strings.ToLower(name)
+		pr.Providers = nil
+		records = append(records, pr)
+	}
+	return records
+}
+
+// 转换记录名：阿里云和 PrivateZone 使用主机记录（@、www），Cloudflare 使用完整的记录名。
+// record 可以是主机记录、@、域名本身或完整域名，比较时忽略大小写
+func providerRecordName(provider, record, domain string) string {
+	record = strings.TrimSuffix(record, ".")
+	lower, suffix := strings.ToLower(record), "."+strings.ToLower(domain)
+	apex := record == "" || record == "@" || lower == strings.ToLower(domain)
+	switch provider {
+	case "cloudflare":
+		if apex {
+			return domain
+		}
+		if !strings.HasSuffix(lower, suffix) {
+			return record + "." + domain
+		}
+	case "aliyun", "pvtz":
+		if apex {
+			return "@"
+		}
+		if strings.HasSuffix(lower, suffix) {
+			return record[:len(record)-len(suffix)]
+		}
+	}
+	return record
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// 配置文件变化后等待的时间，编辑器保存时可能连续产生多个事件，只重新加载一次
+const configReloadDelay = 500 * time.Millisecond
+
+// 常驻循环的回调，tui 命令用来显示每次检查的结果和手动触发检查，普通的常驻模式不需要
+type daemonHooks struct {
+	checked func(status *Status, next time.Time) // 每次检查后调用，status 为空表示没有完成检查
+	trigger <-chan struct{}                      // 收到时立即检查
+}
+
+// 常驻运行，按间隔检查，文件来源内容变化时立即触发更新。
+// 配置文件或凭据文件变化时调用 reload 重新加载，加载或检查失败时继续使用原来的配置
+func runDaemon(u *updater, interval time.Duration, configFile string, reload func() (*updater, time.Duration, error), hooks daemonHooks) {
+	u.daemon = true
+	trigger := make(chan struct{}, 1)
+	reloadTrigger := make(chan struct{}, 1)
+
+	var sourceWatcher, configWatcher *fsnotify.Watcher
+	watch := func() {
+		var err error
+		if sourceWatcher, err = watchFileSources(u.fileSources(), trigger); err != nil {
+			slog.Warn("Failed to watch IP files", "error", err)
+		}
+		if configWatcher, err = watchConfigFiles(configPaths(configFile, u.config), reloadTrigger); err != nil {
+			slog.Warn("Failed to watch config file", "error", err)
+		}
+	}
+	unwatch := func() {
+		for _, w := range []*fsnotify.Watcher{sourceWatcher, configWatcher} {
+			if w != nil {
+				w.Close()
+			}
+		}
+	}
+	watch()
+	defer unwatch()
+	if isRemoteConfig(configFile) {
+		go pollRemoteConfig(configFile, reloadTrigger)
+	}
+
+	health.setInterval(interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	nextCheck := time.Now().Add(interval)
+
+	update := true
+	var retry <-chan time.Time
+	for {
+		if update {
+			u.lastStatus = nil
+			if err := u.runOnce(); err != nil {
+				slog.Error("Update failed", "error", err)
+			}
+
+			// 有没能发布的更新时，不等下一次检查，按计划提前重试
+			next := nextCheck
+			retry = nil
+			if at, ok := u.state.nextPendingRetry(u.recordKeys); ok && at.Before(nextCheck) {
+				next = at
+				retry = time.After(time.Until(at))
+			}
+			if hooks.checked != nil {
+				hooks.checked(u.lastStatus, next)
+			}
+		}
+		update = true
+
+		select {
+		case <-ticker.C:
+			nextCheck = time.Now().Add(interval)
+		case <-retry:
+			slog.Info("Retrying pending updates")
+		case <-hooks.trigger:
+		case <-trigger:
+			slog.Info("IP file changed, updating")
+		case <-reloadTrigger:
+			next, d, err := reload()
+			if err != nil {
+				slog.Error("Config reload failed, keeping the running config", "error", err)
+				update = false
+				continue
+			}
+			unwatch()
+			u.closeIdleConnections()
+			dropRemovedPending(u, next)
+			setOutboundTransport(next.config.apiTransport)
+			u = next
+			u.daemon = true
+			if d != interval {
+				interval = d
+				ticker.Reset(interval)
+				nextCheck = time.Now().Add(interval)
+				health.setInterval(interval)
+			}
+			watch()
+			slog.Info("Config reloaded, updating")
+		}
+	}
+}
+
+// 需要监听的配置文件：本地的配置文件和从文件读取的凭据，远程配置定期检查
+func configPaths(configFile string, c Config) []string {
+	var paths []string
+	if !isRemoteConfig(configFile) {
+		paths = append(paths, configFile)
+	}
+	for _, s := range c.secretFiles() {
+		if s.file != "" {
+			paths = append(paths, s.file)
+		}
+	}
+	return paths
+}
+
+// 监听配置文件所在目录，文件变化后稍等片刻再发送重新加载信号。
+// 配置目录（conf.d）中增加、修改或删除配置文件都会重新加载
+func watchConfigFiles(paths []string, reload chan<- struct{}) (*fsnotify.Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]bool)
+	dirs := make(map[string]bool)
+	for _, path := range paths {
+		path = filepath.Clean(path)
+		watchDir := filepath.Dir(path)
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			dirs[path] = true
+			watchDir = path
+		} else {
+			files[path] = true
+		}
+		if err := watcher.Add(watchDir); err != nil {
+			watcher.Close()
+			return nil, err
+		}
+	}
+	changed := func(event fsnotify.Event) bool {
+		path := filepath.Clean(event.Name)
+		if dirs[filepath.Dir(path)] && isConfigFileName(path) {
+			return event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) != 0
+		}
+		return files[path] && event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0
+	}
+
+	go func() {
+		var timer *time.Timer
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					if timer != nil {
+						timer.Stop()
+					}
+					return
+				}
+				if !changed(event) {
+					continue
+				}
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(configReloadDelay, func() {
+					select {
+					case reload <- struct{}{}:
+					default:
+					}
+				})
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				slog.Warn("Config watcher error", "error", err)
+			}
+		}
+	}()
+
+	return watcher, nil
+}
+
+// 用到的全部文件来源：全局来源、记录单独配置的来源和加权解析各个出口的来源
+func (u *updater) fileSources() []*fileSource {
+	var sources []*fileSource
+	for _, list := range u.sources {
+		for _, s := range list {
+			if fs, ok := s.(*fileSource); ok {
+				sources = append(sources, fs)
+			}
+		}
+	}
+	add := func(configs []SourceConfig) {
+		for _, c := range configs {
+			if strings.ToLower(c.Type) == "file" && c.Path != "" {
+				sources = append(sources, &fileSource{path: c.Path})
+			}
+		}
+	}
+	for _, rec := range u.config.records() {
+		add(rec.IPSources)
+		for _, w := range rec.Weights {
+			add(w.IPSources)
+		}
+	}
+	return sources
+}
+
+// 监听文件来源所在目录，文件内容变化时发送触发信号
+func watchFileSources(sources []*fileSource, trigger chan<- struct{}) (*fsnotify.Watcher, error) {
+	files := make(map[string]*fileSource)
+	for _, fs := range sources {
+		files[filepath.Clean(fs.path)] = fs
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	// 监听目录而不是文件本身，这样原子替换（写临时文件再重命名）也能被发现
+	for path := range files {
+		if err := watcher.Add(filepath.Dir(path)); err != nil {
+			watcher.Close()
+			return nil, err
+		}
+	}
+
+	// 记录文件上次的内容，只有内容变化才触发
+	last := make(map[string]string)
+	for path, fs := range files {
+		last[path], _ = fs.getIP(context.Background())
+	}
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				path := filepath.Clean(event.Name)
+				fs, watched := files[path]
+				if !watched || event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+					continue
+				}
+				ip, err := fs.getIP(context.Background())
+				if err != nil || ip == last[path] {
+					continue
+				}
+				last[path] = ip
+				select {
+				case trigger <- struct{}{}:
+				default:
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				slog.Warn("File watcher error", "error", err)
+			}
+		}
+	}()
+
+	return watcher, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// 删除记录：aliddns -c config.json delete [-yes] [-type A] [-value 值] 主机记录或完整域名...
+// 名称与配置中的记录匹配时删除这些记录，否则按完整域名在默认服务商中查找
+func runDelete(u *updater, args []string) error {
+	fs := flag.NewFlagSet("delete", flag.ExitOnError)
+	yes := fs.Bool("yes", false, "Delete without asking for confirmation")
+	recordType := fs.String("type", "", "Only delete records of this type (default: the configured type, or A)")
+	value := fs.String("value", "", "Only delete the record with this value")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: delete [-yes] [-type TYPE] [-value VALUE] record...")
+	}
+	*recordType = strings.ToUpper(*recordType)
+
+	ctx, cancel := u.config.runContext()
+	defer cancel()
+	providers := make(map[string]provider)
+	var targets []RecordConfig
+	for _, name := range fs.Args() {
+		var matched []RecordConfig
+		for _, rec := range u.config.records() {
+			if matchRecordName(rec, []string{name}) && (*recordType == "" || rec.RecordType == *recordType) {
+				matched = append(matched, rec)
+			}
+		}
+		// 不在配置中的记录使用第一条记录的服务商
+		if len(matched) == 0 {
+			rec := RecordConfig{Provider: u.config.records()[0].Provider, Record: strings.TrimSuffix(name, "."), RecordType: *recordType}
+			if rec.RecordType == "" {
+				rec.RecordType = "A"
+			}
+			matched = append(matched, rec)
+		}
+		for _, rec := range matched {
+			rec, err := u.prepare(ctx, providers, rec)
+			if err != nil {
+				return fmt.Errorf("%s: %w", rec, err)
+			}
+			targets = append(targets, rec)
+		}
+	}
+
+	fmt.Println(tr("Records to delete:"))
+	for _, rec := range targets {
+		if *value != "" {
+			fmt.Printf("  %s %s %s (%s)\n", rec.fqdn(), rec.RecordType, *value, rec.Provider)
+		} else {
+			fmt.Print(trf("  %s %s, all values (%s)\n", rec.fqdn(), rec.RecordType, rec.Provider))
+		}
+	}
+	if !*yes {
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return fmt.Errorf("not deleting without confirmation, use -yes")
+		}
+		in := &prompter{reader: bufio.NewReader(os.Stdin)}
+		if !in.confirm("Delete these records?", false) {
+			fmt.Println(tr("Cancelled"))
+			return nil
+		}
+	}
+
+	var errs []string
+	for _, rec := range targets {
+		if err := providers[rec.Provider].deleteRecords(rec, *value); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", rec, err))
+			continue
+		}
+		delete(u.state.Records, rec.key())
+	}
+	if err := u.state.save(u.config.StateFile); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
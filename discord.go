@@ -0,0 +1,29 @@
+package main
+
+import "fmt"
+
+// Discord 频道的 Webhook 通知
+type discordNotifier struct {
+	webhook string
+}
+
+func newDiscordNotifier(c NotifyConfig) (*discordNotifier, error) {
+	if c.URL == "" {
+		return nil, fmt.Errorf("discord notification requires URL")
+	}
+	return &discordNotifier{webhook: c.URL}, nil
+}
+
+// 标题加粗，正文放在代码块中保持对齐；成功时返回 204，没有内容
+func (n *discordNotifier) notify(e notifyEvent) error {
+	title, text := notifyMessage(e)
+	msg := map[string]string{
+		"username": "aliDDNS",
+		"content":  "**" + title + "**\n```\n" + text + "\n```",
+	}
+	return postJSON(n.webhook, msg, nil)
+}
+
+func (n *discordNotifier) String() string {
+	return "discord"
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// 服务商接口响应超过这个时间时提示网络较慢
+const slowAPILatency = 3 * time.Second
+
+// doctor 的检查结果
+const (
+	checkPass = "pass"
+	checkWarn = "warn"
+	checkFail = "fail"
+)
+
+// 一项检查的结果，-o json|yaml 时原样输出
+type doctorCheck struct {
+	Check     string `json:"Check"`
+	Status    string `json:"Status"` // pass、warn 或 fail
+	Detail    string `json:"Detail,omitempty"`
+	Hint      string `json:"Hint,omitempty"` // 没有通过时的处理建议
+	LatencyMs int64  `json:"LatencyMs,omitempty"`
+}
+
+// 逐项检查配置、凭据和网络，输出每项的结果和处理建议：aliddns -c config.json doctor。
+// 只查询不修改记录，有检查没有通过时以 1 退出
+func runDoctor(filename string, opts loadOptions, overrides *flagOverrides, format string) error {
+	var checks []doctorCheck
+	add := func(c doctorCheck) {
+		checks = append(checks, c)
+	}
+
+	config, ok := doctorConfig(filename, opts, overrides, add)
+	if ok {
+		ctx, cancel := config.runContext()
+		defer cancel()
+		doctorIPSources(ctx, config, add)
+		doctorIPv6(config, add)
+		doctorProviders(ctx, config, add)
+	}
+
+	if err := writeFormatted(format, checks, func(w io.Writer) error {
+		return printDoctorChecks(w, checks)
+	}); err != nil {
+		return err
+	}
+	failed := 0
+	for _, c := range checks {
+		if c.Status == checkFail {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+	return nil
+}
+
+// 检查配置文件能否加载、取值是否有效，返回加载后的配置。配置无法加载时不再做后面的检查
+func doctorConfig(filename string, opts loadOptions, overrides *flagOverrides, add func(doctorCheck)) (Config, bool) {
+	_, statErr := os.Stat(filename)
+	configless := opts.optional && os.IsNotExist(statErr)
+
+	config, err := loadConfig(filename, opts)
+	if err == nil {
+		err = config.applyFlags(overrides)
+	}
+	if err != nil {
+		add(doctorCheck{Check: "config", Status: checkFail, Detail: err.Error(),
+			Hint: tr("Fix the config file; aliddns config validate reports problems with line numbers")})
+		return config, false
+	}
+
+	// 没有配置文件也没有用参数指定记录时，后面的检查都没有对象
+	if configless {
+		if err := config.requireRecords(filename); err != nil {
+			add(doctorCheck{Check: "config", Status: checkFail, Detail: err.Error(),
+				Hint: tr("Create a config file with aliddns init, or give -domain and -record")})
+			return config, false
+		}
+	}
+	var issues []string
+	if configless {
+		issues = config.validate()
+	} else {
+		issues = validateConfigFile(filename, opts, overrides, false)
+	}
+	// 取值有问题时仍然继续检查凭据和网络，一次列出所有问题
+	if len(issues) > 0 {
+		add(doctorCheck{Check: "config", Status: checkFail, Detail: strings.Join(issues, "; "),
+			Hint: tr("Fix the config file; aliddns config validate reports problems with line numbers")})
+		return config, true
+	}
+
+	detail := filename
+	if configless {
+		detail = tr("no config file, using flags and environment variables")
+	}
+	add(doctorCheck{Check: "config", Status: checkPass, Detail: detail})
+	return config, true
+}
+
+// 配置的记录用到的地址族
+func recordFamilies(config Config) map[int]bool {
+	families := make(map[int]bool)
+	for _, rec := range config.records() {
+		if isAddressType(rec.RecordType) {
+			families[familyOf(rec.RecordType)] = true
+		}
+	}
+	return families
+}
+
+// 逐个访问全局 IP 来源，检查能否取到通过过滤的地址
+func doctorIPSources(ctx context.Context, config Config, add func(doctorCheck)) {
+	filter, err := newIPFilter(config.AllowCIDRs, config.DenyCIDRs)
+	if err != nil {
+		add(doctorCheck{Check: "ip filter", Status: checkFail, Detail: err.Error(), Hint: tr("Fix AllowCIDRs/DenyCIDRs in the config")})
+		return
+	}
+
+	transport, err := config.newTransport(config.IPProxy)
+	if err != nil {
+		add(doctorCheck{Check: "network settings", Status: checkFail, Detail: err.Error(), Hint: tr("Fix IPProxy, DNSServers and TLS in the config")})
+		return
+	}
+
+	families := recordFamilies(config)
+	for _, family := range []int{4, 6} {
+		if !families[family] {
+			continue
+		}
+		configs := config.IPSources
+		if family == 6 {
+			configs = config.IPv6Sources
+		}
+		sources, err := newIPSources(configs, family, familyTransport(transport, family))
+		if err != nil {
+			add(doctorCheck{Check: fmt.Sprintf("IPv%d sources", family), Status: checkFail, Detail: err.Error(), Hint: tr("Fix IPSources/IPv6Sources in the config")})
+			continue
+		}
+
+		for _, s := range sources {
+			c := doctorCheck{Check: fmt.Sprintf("IPv%d source %s", family, s)}
+			start := time.Now()
+			sctx, scancel := context.WithTimeout(ctx, config.requestTimeout())
+			ip, err := s.getIP(sctx)
+			scancel()
+			c.LatencyMs = time.Since(start).Milliseconds()
+			if err == nil {
+				err = checkFamily(ip, family)
+			}
+			if err == nil {
+				err = filter.check(ip)
+			}
+			switch {
+			case err != nil:
+				c.Status, c.Detail = checkFail, err.Error()
+				c.Hint = tr("Make sure the source is reachable, or configure another source in IPSources")
+				if family == 6 {
+					c.Hint = tr("Make sure this host has IPv6 connectivity, or configure another source in IPv6Sources")
+				}
+			default:
+				c.Status, c.Detail = checkPass, ip
+			}
+			add(c)
+		}
+	}
+}
+
+// 检查本机是否有公网 IPv6 地址。配置了 AAAA 记录而没有 IPv6 地址时不通过
+func doctorIPv6(config Config, add func(doctorCheck)) {
+	var global string
+	if addrs, err := net.InterfaceAddrs(); err == nil {
+		for _, a := range addrs {
+			if n, ok := a.(*net.IPNet); ok && n.IP.To4() == nil && n.IP.IsGlobalUnicast() && !n.IP.IsPrivate() {
+				global = n.IP.String()
+				break
+			}
+		}
+	}
+
+	used := recordFamilies(config)[6]
+	c := doctorCheck{Check: "IPv6", Status: checkPass}
+	switch {
+	case global != "":
+		c.Detail = trf("global address %s", global)
+	case used:
+		c.Status, c.Detail = checkWarn, tr("no global IPv6 address on this host")
+		c.Hint = tr("AAAA records need IPv6: enable IPv6 (prefix delegation) on the router, or use an IPv6 source that runs elsewhere")
+	default:
+		c.Detail = tr("no global IPv6 address on this host, not needed without AAAA records")
+	}
+	add(c)
+}
+
+// 用配置的凭据访问每个服务商：凭据和权限、接口延迟，以及每条记录所在的域名和记录本身是否存在
+func doctorProviders(ctx context.Context, config Config, add func(doctorCheck)) {
+	if config.CFAPIToken != "" {
+		c := doctorCheck{Check: "cloudflare token", Status: checkPass}
+		start := time.Now()
+		err := verifyCloudflareToken(ctx, config)
+		c.LatencyMs = time.Since(start).Milliseconds()
+		if err != nil {
+			c.Status, c.Detail, c.Hint = checkFail, err.Error(), doctorHint("cloudflare", err)
+		}
+		add(c)
+	}
+
+	providers := make(map[string]provider)
+	failed := make(map[string]bool)
+	zoneRecords := make(map[string][]zoneRecord)
+	for _, rec := range config.records() {
+		if failed[rec.Provider] {
+			continue
+		}
+		p, ok := providers[rec.Provider]
+		if !ok {
+			var err error
+			if p, err = newProvider(ctx, config, rec.Provider); err != nil {
+				add(doctorCheck{Check: rec.Provider + " credentials", Status: checkFail, Detail: err.Error(), Hint: doctorHint(rec.Provider, err)})
+				failed[rec.Provider] = true
+				continue
+			}
+			providers[rec.Provider] = p
+		}
+
+		// 访问记录所在的域名，检查凭据、权限和接口延迟
+		c := doctorCheck{Check: "access " + rec.String()}
+		start := time.Now()
+		rec, err := resolveZone(p, rec)
+		if err == nil {
+			if ac, ok := p.(accessChecker); ok {
+				err = ac.checkAccess(rec)
+			}
+		}
+		c.LatencyMs = time.Since(start).Milliseconds()
+		switch {
+		case err != nil:
+			c.Status, c.Detail, c.Hint = checkFail, err.Error(), doctorHint(rec.Provider, err)
+			add(c)
+			continue
+		case time.Duration(c.LatencyMs)*time.Millisecond > slowAPILatency:
+			c.Status, c.Detail = checkWarn, tr("API responded slowly")
+			c.Hint = tr("Check the network path to the provider API, or use a closer endpoint")
+		default:
+			c.Status = checkPass
+		}
+		add(c)
+
+		// 记录是否已经存在，不存在时只有配置了 CreateMissing 才会自动创建
+		lister, ok := p.(zoneLister)
+		if !ok {
+			continue
+		}
+		key := rec.Provider + " " + rec.DomainName
+		records, listed := zoneRecords[key]
+		if !listed {
+			if records, err = lister.listRecords(rec.DomainName); err != nil {
+				add(doctorCheck{Check: "record " + rec.fqdn(), Status: checkWarn, Detail: err.Error()})
+				continue
+			}
+			zoneRecords[key] = records
+		}
+		c = doctorCheck{Check: "record " + rec.fqdn() + " (" + rec.RecordType + ")"}
+		var values []string
+		for _, r := range records {
+			if strings.EqualFold(r.RecordType, rec.RecordType) && matchRecordName(rec, []string{r.Record}) {
+				values = append(values, r.Value)
+			}
+		}
+		switch {
+		case len(values) > 0:
+			c.Status, c.Detail = checkPass, strings.Join(values, ", ")
+		case rec.CreateMissing:
+			c.Status, c.Detail = checkPass, tr("does not exist yet, will be created by the first update (CreateMissing)")
+		default:
+			c.Status, c.Detail = checkFail, tr("record does not exist")
+			c.Hint = trf("Create it with aliddns add %s VALUE, or set CreateMissing to create it on update", rec.fqdn())
+		}
+		add(c)
+	}
+}
+
+// 按错误的类型给出处理建议
+func doctorHint(providerName string, err error) string {
+	switch exitCode(err) {
+	case exitAuth:
+		switch providerName {
+		case "cloudflare":
+			return tr("Check the API token, it needs Zone.DNS edit permission for this zone")
+		case "pvtz":
+			return tr("Check AccessKeyID/AccessKeySecret, the RAM user needs AliyunPVTZFullAccess")
+		default:
+			return tr("Check AccessKeyID/AccessKeySecret, the RAM user needs AliyunDNSFullAccess")
+		}
+	case exitNotFound:
+		return tr("Make sure the domain belongs to this account and DomainName/Record are spelled correctly")
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return tr("Check the network: DNS resolution, proxy and firewall rules for the provider API")
+	}
+	if strings.Contains(err.Error(), "not configured") || strings.Contains(err.Error(), "requires") {
+		return tr("Configure the credentials in the config file, environment variables or flags")
+	}
+	return ""
+}
+
+func printDoctorChecks(w io.Writer, checks []doctorCheck) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	passed := 0
+	for _, c := range checks {
+		detail := c.Detail
+		if c.LatencyMs > 0 {
+			detail = strings.TrimSpace(fmt.Sprintf("%s (%dms)", detail, c.LatencyMs))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", strings.ToUpper(tr(c.Status)), c.Check, detail)
+		if c.Hint != "" {
+			fmt.Fprintf(tw, "\t\t-> %s\n", c.Hint)
+		}
+		if c.Status != checkFail {
+			passed++
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprint(w, trf("%d of %d checks passed\n", passed, len(checks)))
+	return nil
+}
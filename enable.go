@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 启用或停用记录，names 为空时处理所有记录，否则只处理主机记录在 names 中的记录。
+// 停用的记录保存在状态中，常规更新时跳过，配置保持不变
+func (u *updater) setEnabled(names []string, enabled bool) error {
+	ctx, cancel := u.config.runContext()
+	defer cancel()
+	providers := make(map[string]provider)
+
+	var errs []string
+	for _, rec := range u.config.records() {
+		rec, err := u.prepare(ctx, providers, rec)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", rec, err))
+			continue
+		}
+		if !matchRecordName(rec, names) {
+			continue
+		}
+
+		p := providers[rec.Provider]
+
+		rs := u.state.record(rec.key())
+		if err := p.setEnabled(rec, enabled, rs.IP); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", rec, err))
+			continue
+		}
+		rs.Disabled = !enabled
+	}
+
+	if err := u.state.save(u.config.StateFile); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// 记录的主机记录或完整域名是否在列表中，列表为空时匹配所有记录
+func matchRecordName(rec RecordConfig, names []string) bool {
+	if len(names) == 0 {
+		return true
+	}
+	for _, name := range names {
+		name = strings.TrimSuffix(name, ".")
+		if strings.EqualFold(rec.Record, name) || strings.EqualFold(rec.fqdn(), name) {
+			return true
+		}
+	}
+	return false
+}
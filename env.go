@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 环境变量的前缀，例如 ALIDDNS_ACCESS_KEY_ID
+const envPrefix = "ALIDDNS_"
+
+// 用环境变量覆盖配置文件中的字段，适合在容器中不挂载配置文件运行。
+// 字符串列表用逗号分隔，IP 来源、记录列表等结构化的字段填 JSON
+func (c *Config) applyEnv() error {
+	strs := map[string]*string{
+		"ACCESS_KEY_ID":          &c.AccessKeyID,
+		"ACCESS_KEY_SECRET":      &c.AccessKeySecret,
+		"SECURITY_TOKEN":         &c.SecurityToken,
+		"SECURITY_TOKEN_FILE":    &c.SecurityTokenFile,
+		"RAM_ROLE":               &c.RAMRole,
+		"ALIYUN_PROFILE":         &c.AliyunProfile,
+		"ALIYUN_REGION":          &c.AliyunRegion,
+		"ALIYUN_NETWORK":         &c.AliyunNetwork,
+		"ALIYUN_ENDPOINT":        &c.AliyunEndpoint,
+		"DOMAIN_NAME":            &c.DomainName,
+		"RECORD":                 &c.Record,
+		"RECORD_TYPE":            &c.RecordType,
+		"CF_API_TOKEN":           &c.CFAPIToken,
+		"ACCESS_KEY_ID_FILE":     &c.AccessKeyIDFile,
+		"ACCESS_KEY_SECRET_FILE": &c.AccessKeySecretFile,
+		"CF_API_TOKEN_FILE":      &c.CFAPITokenFile,
+		"CF_API_KEY":             &c.CFAPIKey,
+		"CF_API_EMAIL":           &c.CFEmail,
+		"CF_API_KEY_FILE":        &c.CFAPIKeyFile,
+		"CF_DOMAIN_NAME":         &c.CFDomainName,
+		"CF_RECORD_NAME":         &c.CFRecordName,
+		"MULTI_RECORD_POLICY":    &c.MultiRecordPolicy,
+		"REMARK":                 &c.Remark,
+		"STATE_FILE":             &c.StateFile,
+		"STATUS_FILE":            &c.StatusFile,
+		"HISTORY_FILE":           &c.HistoryFile,
+		"AUDIT_LOG":              &c.AuditLog,
+		"PRE_UPDATE":             &c.PreUpdate,
+		"POST_UPDATE":            &c.PostUpdate,
+		"INTERVAL":               &c.Interval,
+		"STALE_AFTER":            &c.StaleAfter,
+		"REQUEST_TIMEOUT":        &c.RequestTimeout,
+		"RUN_TIMEOUT":            &c.RunTimeout,
+		"IP_PROXY":               &c.IPProxy,
+		"API_PROXY":              &c.APIProxy,
+		"LISTEN":                 &c.Listen,
+	}
+	for name, field := range strs {
+		if v, ok := os.LookupEnv(envPrefix + name); ok {
+			*field = v
+		}
+	}
+
+	bools := map[string]*bool{
+		"DUAL_STACK":        &c.DualStack,
+		"CREATE_MISSING":    &c.CreateMissing,
+		"REMOVE_DUPLICATES": &c.RemoveDuplicates,
+	}
+	for name, field := range bools {
+		v, ok := os.LookupEnv(envPrefix + name)
+		if !ok {
+			continue
+		}
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s%s: %w", envPrefix, name, err)
+		}
+		*field = b
+	}
+
+	lists := map[string]*[]string{
+		"ALLOW_CIDRS": &c.AllowCIDRs,
+		"DENY_CIDRS":  &c.DenyCIDRs,
+		"TAGS":        &c.Tags,
+		"DNS_SERVERS": &c.DNSServers,
+	}
+	for name, field := range lists {
+		if v, ok := os.LookupEnv(envPrefix + name); ok {
+			*field = splitList(v)
+		}
+	}
+
+	structured := map[string]interface{}{
+		"IP_SOURCES":   &c.IPSources,
+		"IPV6_SOURCES": &c.IPv6Sources,
+		"RECORDS":      &c.Records,
+		"DOMAINS":      &c.Domains,
+		"VERIFY":       &c.Verify,
+		"RETRY":        &c.Retry,
+		"TLS":          &c.TLS,
+		"DEFAULTS":     &c.Defaults,
+		"NOTIFY":       &c.Notify,
+		"METRICS":      &c.Metrics,
+	}
+	for name, field := range structured {
+		v, ok := os.LookupEnv(envPrefix + name)
+		if !ok {
+			continue
+		}
+		if err := unmarshalStrict([]byte(v), field, envPrefix+name); err != nil {
+			return fmt.Errorf("invalid %s%s: %w", envPrefix, name, err)
+		}
+	}
+	return nil
+}
+
+// 配置值中的环境变量引用，只支持 ${VAR} 形式，避免误替换密钥中的 $ 字符
+var envRefPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// 展开 JSON 配置中所有字符串值里的 ${VAR}，这样配置文件可以提交到仓库而不包含密钥。
+// 引用的环境变量未设置时报错，避免用空值调用接口
+func expandEnvRefs(data []byte) ([]byte, error) {
+	if !envRefPattern.Match(data) {
+		return data, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+
+	var missing []string
+	var expand func(v interface{}) interface{}
+	expand = func(v interface{}) interface{} {
+		switch t := v.(type) {
+		case string:
+			return envRefPattern.ReplaceAllStringFunc(t, func(ref string) string {
+				name := envRefPattern.FindStringSubmatch(ref)[1]
+				value, ok := os.LookupEnv(name)
+				if !ok {
+					missing = append(missing, name)
+				}
+				return value
+			})
+		case map[string]interface{}:
+			for k, item := range t {
+				t[k] = expand(item)
+			}
+		case []interface{}:
+			for i, item := range t {
+				t[i] = expand(item)
+			}
+		}
+		return v
+	}
+	v = expand(v)
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("environment variables referenced in config are not set: %s", strings.Join(missing, ", "))
+	}
+	return json.Marshal(v)
+}
+
+// 从文件读取的凭据字段
+type secretFile struct {
+	name  string
+	file  string
+	value *string
+}
+
+func (c *Config) secretFiles() []secretFile {
+	return []secretFile{
+		{"AccessKeyID", c.AccessKeyIDFile, &c.AccessKeyID},
+		{"AccessKeySecret", c.AccessKeySecretFile, &c.AccessKeySecret},
+		{"SecurityToken", c.SecurityTokenFile, &c.SecurityToken},
+		{"CF_API_TOKEN", c.CFAPITokenFile, &c.CFAPIToken},
+		{"CF_API_KEY", c.CFAPIKeyFile, &c.CFAPIKey},
+	}
+}
+
+// 读取 *File 字段指向的凭据文件，去掉末尾的换行
+func (c *Config) readSecretFiles() error {
+	for _, s := range c.secretFiles() {
+		if s.file != "" && *s.value != "" {
+			return fmt.Errorf("both %s and its file are set, use only one", s.name)
+		}
+	}
+	return c.reloadSecretFiles()
+}
+
+// 重新读取凭据文件，STS 临时凭据过期前会被外部程序轮换
+func (c *Config) reloadSecretFiles() error {
+	for _, s := range c.secretFiles() {
+		if s.file == "" {
+			continue
+		}
+		data, err := ioutil.ReadFile(s.file)
+		if err != nil {
+			return fmt.Errorf("failed to read %s file: %w", s.name, err)
+		}
+		*s.value = strings.TrimRight(string(data), "\r\n")
+	}
+	return nil
+}
+
+// 拆分逗号分隔的列表，忽略空项
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
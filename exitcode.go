@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"strings"
+
+	sdkerrors "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
+)
+
+// 进程的退出码，cron 包装脚本和监控可以按失败的类型分别处理，不需要解析输出。
+// status 命令使用 Nagios 的退出码，见 status.go
+const (
+	exitOK        = 0
+	exitError     = 1  // 其他错误
+	exitConfig    = 2  // 配置文件或命令行参数错误
+	exitDetect    = 3  // 获取外网 IP 失败
+	exitAuth      = 4  // 服务商认证失败：凭据无效或没有权限
+	exitNotFound  = 5  // 域名或记录不存在
+	exitUpdate    = 6  // 调用服务商接口修改记录失败
+	exitMismatch  = 7  // verify 命令查到的记录与检测到的地址不一致
+	exitUnchanged = 10 // 没有记录变化，只在 -detailed-exit-code 时使用
+)
+
+// 带退出码的错误
+type codedError struct {
+	code int
+	err  error
+}
+
+func (e *codedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *codedError) Unwrap() error {
+	return e.err
+}
+
+// 给错误指定退出码，err 为空时返回空
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &codedError{code: code, err: err}
+}
+
+// 错误对应的退出码：指定过的退出码优先，其次按认证失败、不存在分类，其余为 exitError
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	var e *codedError
+	if errors.As(err, &e) {
+		return e.code
+	}
+	if errors.Is(err, errAuth) {
+		return exitAuth
+	}
+	if errors.Is(err, errRecordNotFound) || errors.Is(err, errZoneNotFound) {
+		return exitNotFound
+	}
+
+	// 阿里云接口的错误码，例如 InvalidAccessKeyId.NotFound、Forbidden.RAM、InvalidDomainName.NoExist
+	var serverErr *sdkerrors.ServerError
+	if errors.As(err, &serverErr) {
+		code := serverErr.ErrorCode()
+		for _, prefix := range []string{"InvalidAccessKeyId", "SignatureDoesNotMatch", "IncompleteSignature", "InvalidSecurityToken", "Forbidden"} {
+			if strings.HasPrefix(code, prefix) {
+				return exitAuth
+			}
+		}
+		if strings.Contains(code, "NoExist") || strings.Contains(code, "NotExist") {
+			return exitNotFound
+		}
+	}
+	return exitError
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// 飞书/Lark 自定义机器人，URL 为机器人的 Webhook 地址。
+// 机器人开启了签名校验时 Secret 填签名密钥
+type feishuNotifier struct {
+	webhook string
+	secret  string
+}
+
+func newFeishuNotifier(c NotifyConfig) (*feishuNotifier, error) {
+	if c.URL == "" {
+		return nil, fmt.Errorf("feishu notification requires URL")
+	}
+	return &feishuNotifier{webhook: c.URL, secret: c.Secret}, nil
+}
+
+func (n *feishuNotifier) notify(e notifyEvent) error {
+	title, text := notifyMessage(e)
+	msg := map[string]interface{}{
+		"msg_type": "text",
+		"content":  map[string]string{"text": title + "\n" + text},
+	}
+	if n.secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		msg["timestamp"] = timestamp
+		msg["sign"] = feishuSign(timestamp, n.secret)
+	}
+
+	var resp struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err := postJSON(n.webhook, msg, &resp); err != nil {
+		return err
+	}
+	if resp.Code != 0 {
+		return fmt.Errorf("code %d: %s", resp.Code, resp.Msg)
+	}
+	return nil
+}
+
+// 飞书的签名：以 "时间戳\n密钥" 为 HMAC-SHA256 的密钥，对空内容签名后 Base64 编码
+func feishuSign(timestamp, secret string) string {
+	mac := hmac.New(sha256.New, []byte(timestamp+"\n"+secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (n *feishuNotifier) String() string {
+	return "feishu"
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+// 覆盖配置文件的命令行参数，方便在脚本中只用参数运行一次
+type flagOverrides struct {
+	accessKeyID     string
+	accessKeySecret string
+	aliyunProfile   string
+	cfToken         string
+	cfKey           string
+	cfEmail         string
+
+	provider   string
+	domain     string
+	record     string
+	recordType string
+	ttl        int
+	force      bool
+}
+
+// 注册覆盖配置的命令行参数
+func registerOverrideFlags() *flagOverrides {
+	o := &flagOverrides{}
+	flag.StringVar(&o.accessKeyID, "access-key-id", "", "Aliyun AccessKeyID")
+	flag.StringVar(&o.accessKeySecret, "access-key-secret", "", "Aliyun AccessKeySecret")
+	flag.StringVar(&o.aliyunProfile, "aliyun-profile", "", "Aliyun CLI profile name in ~/.aliyun/config.json")
+	flag.StringVar(&o.cfToken, "cf-token", "", "Cloudflare API token")
+	flag.StringVar(&o.cfKey, "cf-key", "", "Cloudflare global API key, used with -cf-email")
+	flag.StringVar(&o.cfEmail, "cf-email", "", "Cloudflare account email for -cf-key")
+	flag.StringVar(&o.provider, "provider", "", "DNS provider: aliyun, cloudflare or pvtz")
+	flag.StringVar(&o.domain, "domain", "", "Domain name; with -record, only this record is updated")
+	flag.StringVar(&o.record, "record", "", "Record name (e.g. home, @ or home.example.com); with -domain, only this record is updated")
+	flag.StringVar(&o.recordType, "type", "", "Record type (A, AAAA, ...)")
+	flag.IntVar(&o.ttl, "ttl", 0, "Record TTL in seconds")
+	flag.BoolVar(&o.force, "force", false, "Update records even when they already have the detected value (re-applies TTL, Proxied and Remark)")
+	return o
+}
+
+// 用命令行参数覆盖配置。指定了 -domain 或 -record 时只更新这一条记录，
+// 否则 -provider、-type、-ttl 和 -force 对配置中的每条记录生效
+func (c *Config) applyFlags(o *flagOverrides) error {
+	if o.accessKeyID != "" {
+		c.AccessKeyID = o.accessKeyID
+	}
+	if o.accessKeySecret != "" {
+		c.AccessKeySecret = o.accessKeySecret
+	}
+	if o.aliyunProfile != "" {
+		c.AliyunProfile = o.aliyunProfile
+	}
+	if o.cfToken != "" {
+		c.CFAPIToken = o.cfToken
+	}
+	if o.cfKey != "" {
+		c.CFAPIKey = o.cfKey
+	}
+	if o.cfEmail != "" {
+		c.CFEmail = o.cfEmail
+	}
+
+	if o.domain != "" || o.record != "" {
+		provider := o.provider
+		if provider == "" && (c.CFAPIToken != "" || c.CFAPIKey != "") && c.AccessKeyID == "" && c.RAMRole == "" && c.AliyunProfile == "" {
+			provider = "cloudflare"
+		}
+		c.Records = []RecordConfig{{Provider: provider, DomainName: o.domain, Record: o.record}}
+		c.Domains = nil
+	}
+	c.recordOverride = RecordConfig{Provider: o.provider, RecordType: o.recordType, TTL: o.ttl, force: o.force}
+
+	return c.normalizeIDN()
+}
+
+// 没有配置文件时，要更新的记录只能来自命令行参数或环境变量，都没有时提示怎样指定，
+// 而不是报告一条空记录的凭据错误
+func (c Config) requireRecords(configPath string) error {
+	for _, r := range c.records() {
+		if r.DomainName != "" || r.Record != "" {
+			return nil
+		}
+	}
+	return fmt.Errorf("config file %s not found and no record given: use -c, or -domain and -record with credentials flags (-access-key-id/-access-key-secret or -cf-token) or environment variables", configPath)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 读命令（list、status、history）的输出格式，json 和 yaml 方便用 jq 等工具处理
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatYAML  = "yaml"
+)
+
+func checkOutputFormat(format string) error {
+	switch format {
+	case formatTable, formatJSON, formatYAML:
+		return nil
+	default:
+		return fmt.Errorf("unknown output format %q, use table, json or yaml", format)
+	}
+}
+
+// 按格式输出到标准输出：json 和 yaml 输出 v，table 调用 table 输出给人看的表格
+func writeFormatted(format string, v interface{}, table func(w io.Writer) error) error {
+	switch format {
+	case formatJSON:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case formatYAML:
+		return writeYAML(os.Stdout, v)
+	default:
+		return table(os.Stdout)
+	}
+}
+
+// 先编码为 JSON 再转换为 YAML，字段名与 JSON 相同，并保持结构体中的字段顺序
+func writeYAML(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	clearYAMLStyle(&doc)
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// 从 JSON 解析出的节点带有流式和引号样式，去掉后输出为普通的块格式
+func clearYAMLStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearYAMLStyle(child)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Grafana 注释：记录的值变化时通过 /api/annotations 添加一条全局注释，
+// 在面板中按标签查询后可以和网络流量等图表对照。默认只发送 change 事件
+type grafanaNotifier struct {
+	url      string
+	token    string // 服务账号的 Token
+	username string // 没有 Token 时使用基本认证
+	password string
+	tags     []string
+}
+
+func newGrafanaNotifier(c NotifyConfig) (*grafanaNotifier, error) {
+	if c.URL == "" {
+		return nil, fmt.Errorf("grafana notification requires URL, e.g. http://grafana:3000")
+	}
+	if c.Token == "" && c.Username == "" {
+		return nil, fmt.Errorf("grafana notification requires Token (service account) or Username and Password")
+	}
+	return &grafanaNotifier{
+		url:      strings.TrimSuffix(c.URL, "/"),
+		token:    c.Token,
+		username: c.Username,
+		password: c.Password,
+		tags:     c.Tags,
+	}, nil
+}
+
+// Grafana 注释接口的请求体，时间为毫秒
+type grafanaAnnotation struct {
+	Time int64    `json:"time"`
+	Tags []string `json:"tags"`
+	Text string   `json:"text"`
+}
+
+func (n *grafanaNotifier) notify(e notifyEvent) error {
+	now := time.Now().UnixMilli()
+	title, text := notifyMessage(e)
+	tags := append([]string{"aliddns"}, n.tags...)
+
+	// 每条变化的记录一条注释，带上记录名作为标签，方便只显示某条记录
+	var annotations []grafanaAnnotation
+	switch {
+	case e.Error != "":
+		annotations = append(annotations, grafanaAnnotation{Time: now, Tags: append(tags, "failure"), Text: title + ": " + text})
+	case len(e.Changes) == 0:
+		annotations = append(annotations, grafanaAnnotation{Time: now, Tags: append(tags, "check"), Text: title})
+	}
+	for _, c := range e.Changes {
+		old := c.OldValue
+		if old == "" {
+			old = "(none)"
+		}
+		annotations = append(annotations, grafanaAnnotation{
+			Time: now,
+			Tags: append(append([]string(nil), tags...), "ip-change", c.Record),
+			Text: fmt.Sprintf("%s (%s): %s -> %s", c.Record, c.Type, old, c.NewValue),
+		})
+	}
+
+	for _, a := range annotations {
+		if err := n.post(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *grafanaNotifier) post(a grafanaAnnotation) error {
+	data, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", n.url+"/api/annotations", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.token)
+	} else {
+		req.SetBasicAuth(n.username, n.password)
+	}
+
+	client := outboundClient()
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func (n *grafanaNotifier) String() string {
+	return "grafana " + n.url
+}
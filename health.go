@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 距离上次完成检查超过这个时间视为卡住，至少为检查间隔的 3 倍
+const minStaleAfter = 5 * time.Minute
+
+// 常驻模式的运行状况，/healthz 和 /readyz 根据它返回结果
+type healthStatus struct {
+	mu sync.Mutex
+
+	started     time.Time
+	interval    time.Duration
+	lastCheck   time.Time // 上次完成检查的时间
+	lastSuccess time.Time // 上次没有错误的检查的时间
+	lastError   string
+}
+
+var health = &healthStatus{started: time.Now()}
+
+// 健康检查返回的 JSON
+type healthResponse struct {
+	Status      string     `json:"status"` // ok、failing、stale 或 starting
+	LastCheck   *time.Time `json:"lastCheck,omitempty"`
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+	Age         string     `json:"age,omitempty"` // 距离上次完成检查的时间
+	Error       string     `json:"error,omitempty"`
+}
+
+// 设置检查间隔，用于判断是否卡住，配置重新加载后更新
+func (h *healthStatus) setInterval(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.interval = d
+}
+
+// 记录一次检查的结果
+func (h *healthStatus) observeCheck(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastCheck = time.Now()
+	h.lastError = ""
+	if err != nil {
+		h.lastError = err.Error()
+	} else {
+		h.lastSuccess = h.lastCheck
+	}
+}
+
+// 当前状态，第二个返回值表示是否卡住：启动后或上次检查后太久没有完成检查
+func (h *healthStatus) status() (healthResponse, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	staleAfter := 3 * h.interval
+	if staleAfter < minStaleAfter {
+		staleAfter = minStaleAfter
+	}
+	var resp healthResponse
+	since := h.started
+	if !h.lastCheck.IsZero() {
+		lastCheck := h.lastCheck
+		resp.LastCheck = &lastCheck
+		resp.Age = time.Since(lastCheck).Round(time.Second).String()
+		since = lastCheck
+	}
+	if !h.lastSuccess.IsZero() {
+		lastSuccess := h.lastSuccess
+		resp.LastSuccess = &lastSuccess
+	}
+	resp.Error = h.lastError
+	stale := time.Since(since) > staleAfter
+
+	switch {
+	case stale:
+		resp.Status = "stale"
+	case h.lastCheck.IsZero():
+		resp.Status = "starting"
+	case h.lastError != "":
+		resp.Status = "failing"
+	default:
+		resp.Status = "ok"
+	}
+	return resp, stale
+}
+
+// 存活检查：只有检查卡住时返回 503，更新失败（例如网络中断）不需要重启
+func (h *healthStatus) serveHealthz(w http.ResponseWriter, r *http.Request) {
+	resp, stale := h.status()
+	writeHealth(w, resp, !stale)
+}
+
+// 就绪检查：上次检查成功并且没有卡住时返回 200
+func (h *healthStatus) serveReadyz(w http.ResponseWriter, r *http.Request) {
+	resp, _ := h.status()
+	writeHealth(w, resp, resp.Status == "ok")
+}
+
+func writeHealth(w http.ResponseWriter, resp healthResponse, healthy bool) {
+	w.Header().Set("Content-Type", "application/json")
+	if !healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
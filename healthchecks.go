@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// Healthchecks.io（或兼容的服务，例如 Uptime Kuma 的 Push 监控）的定时 ping。
+// 每次运行成功后 ping URL，失败时 ping URL/fail 并带上错误信息；程序停止运行后服务端收不到 ping 会发出告警。
+// 默认每次运行都发送（On 为 always）
+type healthchecksNotifier struct {
+	url string
+}
+
+func newHealthchecksNotifier(c NotifyConfig) (*healthchecksNotifier, error) {
+	if c.URL == "" {
+		return nil, fmt.Errorf("healthchecks notification requires URL, e.g. https://hc-ping.com/<uuid>")
+	}
+	return &healthchecksNotifier{url: strings.TrimSuffix(c.URL, "/")}, nil
+}
+
+func (n *healthchecksNotifier) notify(e notifyEvent) error {
+	endpoint := n.url
+	if e.Error != "" {
+		endpoint += "/fail"
+	}
+	_, text := notifyMessage(e)
+
+	client := outboundClient()
+	resp, err := client.Post(endpoint, "text/plain; charset=utf-8", strings.NewReader(text))
+	if err != nil {
+		return hideURL(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func (n *healthchecksNotifier) String() string {
+	return "healthchecks"
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// 更新历史中的一条，每次更新记录（无论是否修改、是否成功）追加一行 JSON
+type historyEntry struct {
+	Time     time.Time `json:"Time"`
+	Record   string    `json:"Record"` // 完整域名
+	Type     string    `json:"Type"`
+	Provider string    `json:"Provider"`
+	OldIP    string    `json:"OldIP,omitempty"` // 记录原来的值，查询失败时为空
+	NewIP    string    `json:"NewIP"`
+	Result   string    `json:"Result"` // changed、unchanged 或 failed
+	Error    string    `json:"Error,omitempty"`
+	Latency  int64     `json:"LatencyMs"` // 调用服务商接口的耗时（毫秒），批量更新时为整批的耗时
+}
+
+func newHistoryEntry(j *updateJob, now time.Time) historyEntry {
+	e := historyEntry{
+		Time:     now,
+		Record:   j.rec.fqdn(),
+		Type:     j.rec.RecordType,
+		Provider: j.rec.Provider,
+		OldIP:    j.current,
+		NewIP:    j.newValue,
+		Result:   "unchanged",
+		Latency:  j.latency.Milliseconds(),
+	}
+	switch {
+	case j.err != nil:
+		e.Result = "failed"
+		e.Error = j.err.Error()
+	case j.current != j.newValue:
+		e.Result = "changed"
+	}
+	return e
+}
+
+// 把本次更新的结果追加到历史文件（JSON Lines），filename 为空时不记录
+func appendHistory(filename string, jobs []*updateJob) error {
+	if filename == "" || len(jobs) == 0 {
+		return nil
+	}
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer f.Close()
+
+	// 每行单独写入，多个进程同时追加时也不会交错
+	now := time.Now()
+	for _, j := range jobs {
+		line, err := json.Marshal(newHistoryEntry(j, now))
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(append(line, '\n')); err != nil {
+			return fmt.Errorf("failed to write history file: %w", err)
+		}
+	}
+	return nil
+}
+
+// 读取历史文件，无法解析的行跳过
+func readHistory(filename string) ([]historyEntry, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer f.Close()
+
+	var entries []historyEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e historyEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err == nil {
+			entries = append(entries, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history file: %w", err)
+	}
+	return entries, nil
+}
+
+// 显示更新历史：aliddns -c config.json history [-n 20] [-changes] [-failed] [主机记录或域名...]
+func runHistory(config Config, args []string, format string) error {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	fs.StringVar(&format, "o", format, "Output format: table, json or yaml")
+	limit := fs.Int("n", 20, "Show the last n entries (0 shows all)")
+	changes := fs.Bool("changes", false, "Only show entries where the record value changed")
+	failed := fs.Bool("failed", false, "Only show failed updates")
+	fs.Parse(args)
+	if err := checkOutputFormat(format); err != nil {
+		return err
+	}
+
+	if config.HistoryFile == "" {
+		return fmt.Errorf("HistoryFile is not configured")
+	}
+	entries, err := readHistory(config.HistoryFile)
+	if err != nil {
+		return err
+	}
+
+	shown := []historyEntry{}
+	for _, e := range entries {
+		if *changes && e.Result != "changed" || *failed && e.Result != "failed" {
+			continue
+		}
+		if fs.NArg() > 0 && !matchHistoryRecord(e.Record, fs.Args()) {
+			continue
+		}
+		shown = append(shown, e)
+	}
+	if *limit > 0 && len(shown) > *limit {
+		shown = shown[len(shown)-*limit:]
+	}
+
+	return writeFormatted(format, shown, func(out io.Writer) error {
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, tr("TIME\tRECORD\tTYPE\tPROVIDER\tOLD\tNEW\tRESULT\tLATENCY\tERROR"))
+		for _, e := range shown {
+			old := e.OldIP
+			if old == "" {
+				old = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%dms\t%s\n", e.Time.Local().Format("2006-01-02 15:04:05"),
+				e.Record, e.Type, e.Provider, old, e.NewIP, e.Result, e.Latency, e.Error)
+		}
+		return w.Flush()
+	})
+}
+
+// 按完整域名或第一级主机记录匹配，例如 home 匹配 home.example.com
+func matchHistoryRecord(record string, names []string) bool {
+	for _, name := range names {
+		if record == name || strings.HasPrefix(record, name+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// 统计每条记录的变化频率：aliddns -c config.json stats [-days 30] [主机记录或域名...]。
+// 回答"运营商多久换一次地址"：变化次数、平均每天几次、两次变化的间隔和每天的变化次数
+func runStats(config Config, args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	days := fs.Int("days", 30, "Only count the last n days (0 counts the whole history)")
+	fs.Parse(args)
+
+	if config.HistoryFile == "" {
+		return fmt.Errorf("HistoryFile is not configured")
+	}
+	entries, err := readHistory(config.HistoryFile)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var since time.Time
+	if *days > 0 {
+		since = now.AddDate(0, 0, -*days)
+	}
+
+	// 按记录分组，保持第一次出现的顺序
+	type recordStats struct {
+		name     string
+		changes  []historyEntry
+		values   map[string]bool
+		failures int
+		first    time.Time // 统计范围内第一条历史的时间
+	}
+	var order []string
+	byRecord := make(map[string]*recordStats)
+	for _, e := range entries {
+		if e.Time.Before(since) || fs.NArg() > 0 && !matchHistoryRecord(e.Record, fs.Args()) {
+			continue
+		}
+		key := e.Record + " (" + e.Type + ")"
+		rs, ok := byRecord[key]
+		if !ok {
+			rs = &recordStats{name: key, values: make(map[string]bool), first: e.Time}
+			byRecord[key] = rs
+			order = append(order, key)
+		}
+		switch e.Result {
+		case "changed":
+			rs.changes = append(rs.changes, e)
+			rs.values[e.NewIP] = true
+		case "failed":
+			rs.failures++
+		}
+	}
+	if len(order) == 0 {
+		fmt.Println(tr("No history in the selected period"))
+		return nil
+	}
+
+	for i, key := range order {
+		rs := byRecord[key]
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Println(rs.name)
+
+		period := now.Sub(rs.first)
+		if !since.IsZero() && period > now.Sub(since) {
+			period = now.Sub(since)
+		}
+		perDay := float64(len(rs.changes)) / (period.Hours() / 24)
+		if period < 24*time.Hour {
+			perDay = float64(len(rs.changes))
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprint(w, trf("  changes:\t%d in %s (%.2f per day)\n", len(rs.changes), formatDays(period), perDay))
+		fmt.Fprint(w, trf("  distinct values:\t%d\n", len(rs.values)))
+		fmt.Fprint(w, trf("  failed updates:\t%d\n", rs.failures))
+		if len(rs.changes) > 0 {
+			last := rs.changes[len(rs.changes)-1].Time
+			fmt.Fprint(w, trf("  last change:\t%s (%s ago)\n", last.Local().Format("2006-01-02 15:04:05"), formatDays(now.Sub(last))))
+		}
+		if len(rs.changes) > 1 {
+			gaps := make([]time.Duration, 0, len(rs.changes)-1)
+			var total time.Duration
+			for j := 1; j < len(rs.changes); j++ {
+				gap := rs.changes[j].Time.Sub(rs.changes[j-1].Time)
+				gaps = append(gaps, gap)
+				total += gap
+			}
+			sort.Slice(gaps, func(a, b int) bool { return gaps[a] < gaps[b] })
+			fmt.Fprint(w, trf("  between changes:\tavg %s, median %s, shortest %s, longest %s\n",
+				formatDays(total/time.Duration(len(gaps))), formatDays(gaps[len(gaps)/2]), formatDays(gaps[0]), formatDays(gaps[len(gaps)-1])))
+		}
+		w.Flush()
+
+		// 每天的变化次数，只列出有变化的日期
+		if len(rs.changes) > 0 {
+			fmt.Println(tr("  changes per day:"))
+			var dates []string
+			perDate := make(map[string]int)
+			for _, c := range rs.changes {
+				date := c.Time.Local().Format("2006-01-02")
+				if perDate[date] == 0 {
+					dates = append(dates, date)
+				}
+				perDate[date]++
+			}
+			for _, date := range dates {
+				fmt.Printf("    %s  %s %d\n", date, strings.Repeat("#", perDate[date]), perDate[date])
+			}
+		}
+	}
+	return nil
+}
+
+// 以天、小时、分钟显示时长，例如 2d3h、5h12m、45s
+func formatDays(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d >= 24*time.Hour:
+		days := d / (24 * time.Hour)
+		return fmt.Sprintf("%dd%dh", days, (d-days*24*time.Hour)/time.Hour)
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh%dm", d/time.Hour, (d%time.Hour)/time.Minute)
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	default:
+		return d.String()
+	}
+}
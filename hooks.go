@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// 更新命令的最长运行时间，例如重新签发证书可能较慢
+const hookTimeout = 5 * time.Minute
+
+// 在值可能变化的记录更新前运行 PreUpdate，命令失败时跳过这条记录。
+// 状态文件中上次发布的值与新值相同时认为不会变化；没有状态文件时每次更新前都会运行
+func (u *updater) runPreUpdate(jobs []*updateJob) []*updateJob {
+	if u.config.PreUpdate == "" {
+		return jobs
+	}
+	ready := make([]*updateJob, 0, len(jobs))
+	for _, j := range jobs {
+		if j.oldValue == "" || j.oldValue != j.newValue {
+			if err := runHook(u.config.PreUpdate, hookEnv(j)); err != nil {
+				j.err = fmt.Errorf("PreUpdate failed, record not updated: %w", err)
+				continue
+			}
+		}
+		ready = append(ready, j)
+	}
+	return ready
+}
+
+// 记录的值发生变化或更新失败后运行 PostUpdate，DDNS_RESULT 为 success 或 failed
+func (u *updater) runPostUpdate(j *updateJob) {
+	if u.config.PostUpdate == "" || j.err == nil && j.current == j.newValue {
+		return
+	}
+	env := hookEnv(j)
+	if j.err != nil {
+		env = append(env, "DDNS_RESULT=failed", "DDNS_ERROR="+j.err.Error())
+	} else {
+		env = append(env, "DDNS_RESULT=success")
+	}
+	if err := runHook(u.config.PostUpdate, env); err != nil {
+		slog.Warn("PostUpdate failed", "record", j.rec, "error", err)
+	}
+}
+
+// 传给命令的环境变量。旧值为记录原来的值，更新前还不知道时为上次发布的值
+func hookEnv(j *updateJob) []string {
+	old := j.current
+	if old == "" {
+		old = j.oldValue
+	}
+	return []string{
+		"DDNS_RECORD=" + j.rec.fqdn(),
+		"DDNS_RECORD_TYPE=" + j.rec.RecordType,
+		"DDNS_PROVIDER=" + j.rec.Provider,
+		"DDNS_OLD_IP=" + old,
+		"DDNS_NEW_IP=" + j.newValue,
+	}
+}
+
+// 用系统的 shell 运行命令，输出直接打印
+func runHook(command string, env []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
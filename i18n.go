@@ -0,0 +1,331 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// 界面语言：en 或 zh。程序中的消息以英文书写，英文消息本身就是消息目录的键，
+// 中文消息在 zhMessages 中。错误详情不翻译，保持英文便于搜索和对照服务商的文档
+var language = detectLanguage(os.Getenv(envPrefix + "LANG"))
+
+// 解析语言设置：en、zh（也接受 zh_CN.UTF-8 这样的 locale 写法），auto 或留空时按
+// LC_ALL、LC_MESSAGES、LANG 的顺序检测
+func detectLanguage(setting string) string {
+	if setting == "" || strings.EqualFold(setting, "auto") {
+		for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+			if v := os.Getenv(name); v != "" {
+				setting = v
+				break
+			}
+		}
+	}
+	if strings.HasPrefix(strings.ToLower(setting), "zh") {
+		return "zh"
+	}
+	return "en"
+}
+
+// 设置界面语言，-lang 参数使用
+func setLanguage(setting string) error {
+	switch strings.ToLower(setting) {
+	case "", "auto", "en", "zh":
+	default:
+		if !strings.HasPrefix(strings.ToLower(setting), "zh") && !strings.HasPrefix(strings.ToLower(setting), "en") {
+			return fmt.Errorf("unknown language %q, use auto, en or zh", setting)
+		}
+	}
+	language = detectLanguage(setting)
+	return nil
+}
+
+// 翻译一条消息，目录中没有的消息原样返回
+func tr(msg string) string {
+	if language == "zh" {
+		if s, ok := zhMessages[msg]; ok {
+			return s
+		}
+	}
+	return msg
+}
+
+// 翻译格式字符串后格式化
+func trf(format string, args ...interface{}) string {
+	return fmt.Sprintf(tr(format), args...)
+}
+
+// 翻译日志消息的 Handler，字段名和字段值不变，日志仍然可以按字段检索
+type translateHandler struct {
+	slog.Handler
+}
+
+func (h translateHandler) Handle(ctx context.Context, r slog.Record) error {
+	if language != "en" {
+		t := slog.NewRecord(r.Time, r.Level, tr(r.Message), r.PC)
+		r.Attrs(func(a slog.Attr) bool {
+			t.AddAttrs(a)
+			return true
+		})
+		r = t
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h translateHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return translateHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h translateHandler) WithGroup(name string) slog.Handler {
+	return translateHandler{h.Handler.WithGroup(name)}
+}
+
+// 中文消息目录
+var zhMessages = map[string]string{
+	// 命令行帮助
+	"Usage: %s [flags] [command] [args]\n\nCommands:\n":                                                     "用法：%s [参数] [命令] [命令参数]\n\n命令：\n",
+	"\nWithout a command, runs as a daemon when -i or Interval is set, otherwise updates once.\n\nFlags:\n": "\n不指定命令时，设置了 -i 或 Interval 则常驻运行，否则只更新一次。\n\n参数：\n",
+
+	"Check the external IP and update all records once":                                                              "检测外网 IP 并更新所有记录一次",
+	"Run as a daemon with a terminal dashboard of records, next check and recent logs":                               "带终端仪表盘常驻运行，显示记录状态、下次检查时间和最近的日志",
+	"Keep running and check every -i or Interval":                                                                    "常驻运行，每隔 -i 或 Interval 检查一次",
+	"List configured records, or the provider's zones and records: list [zones|records ZONE]":                        "列出配置的记录，或服务商账号下的域名和记录：list [zones|records 域名]",
+	"Detect and print the external IP address only: ip [-4] [-6] [-o FORMAT]":                                        "只检测并输出外网 IP：ip [-4] [-6] [-o 格式]",
+	"Resolve the records via public resolvers and compare with the detected IP: verify [-resolver LIST] [-o FORMAT]": "通过公共 DNS 解析记录并与检测到的 IP 比较：verify [-resolver 列表] [-o 格式]",
+	"Show whether the last successful check is recent (Nagios exit codes)":                                           "显示最近一次成功检查是否及时（Nagios 退出码）",
+	"Show the update history: history [-n N] [-changes] [-failed] [-o FORMAT] [record...]":                           "显示更新历史：history [-n 条数] [-changes] [-failed] [-o 格式] [记录...]",
+	"Show how often records change: stats [-days N] [record...]":                                                     "显示记录变化的频率：stats [-days 天数] [记录...]",
+	"Enable records: enable [record...]":                                                                             "启用记录：enable [记录...]",
+	"Disable records, updates skip them: disable [record...]":                                                        "停用记录，更新时跳过：disable [记录...]",
+	"Create a record at the provider: add [-type TYPE] [-ttl TTL] [-zone ZONE] name value":                           "在服务商处新建记录：add [-type 类型] [-ttl TTL] [-zone 域名] 名称 值",
+	"Delete records at the provider: delete [-yes] [-type TYPE] [-value VALUE] record...":                            "删除服务商处的记录：delete [-yes] [-type 类型] [-value 值] 记录...",
+	"Check or migrate the config file: config validate|migrate":                                                      "检查或迁移配置文件：config validate|migrate",
+	"Create a config file interactively":                                                                             "交互式生成配置文件",
+	"Print the version and build information":                                                                        "显示版本和构建信息",
+
+	// 全局参数
+	"Path to the config file":                                                                            "配置文件路径",
+	"Run as a daemon and check every interval (e.g. 5m)":                                                 "常驻运行，每隔指定时间检查一次（例如 5m）",
+	"Set a TXT record with this name instead of updating addresses (e.g. _acme-challenge)":               "设置这个名称的 TXT 记录，不更新地址（例如 _acme-challenge）",
+	"TXT record value, read from stdin when empty":                                                       "TXT 记录的值，留空时从标准输入读取",
+	"Delete the TXT record given by -txt (only the one matching -value when set)":                        "删除 -txt 指定的 TXT 记录（设置了 -value 时只删除值相同的一条）",
+	"age identity file for decrypting an encrypted config (passphrase is prompted when empty)":           "解密配置文件的 age 私钥文件（留空时提示输入口令）",
+	"Name of the profile to use from the config's Profiles":                                              "使用配置文件 Profiles 中的哪个配置",
+	"Print nothing when no record changed and no error occurred (for cron)":                              "没有记录变化且没有出错时不输出（用于 cron）",
+	"Exit with 10 instead of 0 when an update run changed no record":                                     "更新时没有记录变化则以 10 而不是 0 退出",
+	"Output format of list, status, history and version: table, json or yaml":                            "list、status、history 和 version 的输出格式：table、json 或 yaml",
+	"Interface language: auto, en or zh (default auto, detected from LANG)":                              "界面语言：auto、en 或 zh（默认 auto，按 LANG 检测）",
+	"Log level: debug, info, warn or error (default info)":                                               "日志级别：debug、info、warn 或 error（默认 info）",
+	"Log format: text or json (default text)":                                                            "日志格式：text 或 json（默认 text）",
+	"Write logs to this file instead of stderr, rotated by size":                                         "日志写入这个文件而不是标准错误，按大小轮转",
+	"Rotate the log file when it reaches this size in MB":                                                "日志文件达到这个大小（MB）时轮转",
+	"Delete rotated log files older than this many days (0 keeps them)":                                  "删除超过这个天数的轮转日志（0 表示保留）",
+	"Number of rotated log files to keep (0 keeps all)":                                                  "保留的轮转日志个数（0 表示全部保留）",
+	"Compress rotated log files with gzip":                                                               "用 gzip 压缩轮转后的日志",
+	"Send logs to syslog: local, or a remote address such as udp://host:514":                             "日志发送到 syslog：local，或远程地址例如 udp://host:514",
+	"Send logs to the systemd journal":                                                                   "日志发送到 systemd journal",
+	"Aliyun AccessKeyID":                                                                                 "阿里云 AccessKeyID",
+	"Aliyun AccessKeySecret":                                                                             "阿里云 AccessKeySecret",
+	"Aliyun CLI profile name in ~/.aliyun/config.json":                                                   "~/.aliyun/config.json 中的阿里云 CLI 配置名称",
+	"Cloudflare API token":                                                                               "Cloudflare API Token",
+	"Cloudflare global API key, used with -cf-email":                                                     "Cloudflare Global API Key，与 -cf-email 一起使用",
+	"Cloudflare account email for -cf-key":                                                               "-cf-key 对应的 Cloudflare 账号邮箱",
+	"DNS provider: aliyun, cloudflare or pvtz":                                                           "DNS 服务商：aliyun、cloudflare 或 pvtz",
+	"Domain name; with -record, only this record is updated":                                             "域名；与 -record 一起使用时只更新这一条记录",
+	"Record name (e.g. home, @ or home.example.com); with -domain, only this record is updated":          "主机记录（例如 home、@ 或 home.example.com）；与 -domain 一起使用时只更新这一条记录",
+	"Record type (A, AAAA, ...)":                                                                         "记录类型（A、AAAA 等）",
+	"Record TTL in seconds":                                                                              "记录的 TTL，单位为秒",
+	"Update records even when they already have the detected value (re-applies TTL, Proxied and Remark)": "即使记录已经是检测到的值也更新（重新写入 TTL、Proxied 和备注）",
+
+	// 日志
+	"Invalid logging options":                                   "日志选项无效",
+	"Invalid arguments":                                         "参数无效",
+	"Invalid command-line arguments":                            "命令行参数无效",
+	"Invalid interval":                                          "检查间隔无效",
+	"Error loading config":                                      "读取配置失败",
+	"Config check failed":                                       "配置检查未通过",
+	"Nothing to update":                                         "没有要更新的记录",
+	"Initialization failed":                                     "初始化失败",
+	"Update failed":                                             "更新失败",
+	"Cloudflare API token check failed":                         "Cloudflare API Token 检查未通过",
+	"DNS does not match the detected IP":                        "DNS 解析结果与检测到的 IP 不一致",
+	"Failed to add record":                                      "新建记录失败",
+	"Failed to create config":                                   "生成配置文件失败",
+	"Failed to delete records":                                  "删除记录失败",
+	"Failed to detect IP address":                               "获取外网 IP 失败",
+	"Failed to list records":                                    "列出记录失败",
+	"Failed to print version":                                   "显示版本失败",
+	"Failed to show history":                                    "显示更新历史失败",
+	"Failed to show statistics":                                 "显示统计失败",
+	"Failed to start HTTP server":                               "启动 HTTP 服务失败",
+	"Failed to update TXT record":                               "更新 TXT 记录失败",
+	"Failed to enable records":                                  "启用记录失败",
+	"Failed to disable records":                                 "停用记录失败",
+	"Config reload failed, keeping the running config":          "重新加载配置失败，继续使用当前配置",
+	"Config reloaded, updating":                                 "配置已重新加载，开始更新",
+	"Config watcher error":                                      "监视配置文件出错",
+	"Failed to watch config file":                               "无法监视配置文件",
+	"Failed to check remote config":                             "检查远程配置失败",
+	"IP file changed, updating":                                 "IP 文件已变化，开始更新",
+	"Failed to watch IP files":                                  "无法监视 IP 文件",
+	"File watcher error":                                        "监视文件出错",
+	"HTTP server listening":                                     "HTTP 服务已启动",
+	"HTTP server stopped":                                       "HTTP 服务已停止",
+	"IP source responded":                                       "IP 来源返回了结果",
+	"Current IP":                                                "当前 IP",
+	"New IP to update":                                          "要更新的新 IP",
+	"Provider API request":                                      "调用服务商接口",
+	"Rate limited by the provider API, retrying":                "服务商接口限流，稍后重试",
+	"Dropping pending update of removed record":                 "记录已从配置中删除，不再发布之前没能发布的更新",
+	"Pending update applied":                                    "之前没能发布的更新已发布",
+	"Retrying pending updates":                                  "重试没能发布的更新",
+	"Transient error, retrying":                                 "遇到临时错误，稍后重试",
+	"IP address is already up to date":                          "外网 IP 与 DNS 记录一致，无需更新",
+	"IP address changed, updating the DNS record":               "外网 IP 与 DNS 记录不一致，正在更新",
+	"Current DNS record value":                                  "DNS 记录的当前值",
+	"DNS record does not exist, creating it":                    "DNS 记录不存在，正在创建",
+	"Found zone ID":                                             "查询到 Zone ID",
+	"Updated record":                                            "DNS 记录更新成功",
+	"Created record":                                            "DNS 记录创建成功",
+	"Deleted record":                                            "已删除 DNS 记录",
+	"Deleted duplicate record":                                  "已删除重复的 DNS 记录",
+	"Enabled record":                                            "已启用记录",
+	"Disabled record":                                           "已停用记录",
+	"Batch updated DNS records":                                 "批量更新了 DNS 记录",
+	"The DNS record already exists with the same value":         "DNS 记录已存在且值相同",
+	"Cached record ID is not usable, looking up the record":     "缓存的记录 ID 无效，重新查询记录",
+	"No record matches the previous IP, updating the first one": "没有记录的值是上次的 IP，更新第一条记录",
+	"Cloudflare API token is valid and has DNS edit permission": "Cloudflare API Token 有效，具有 DNS 编辑权限",
+	"Cloudflare API token is valid (cannot read its permissions, skipping the permission check)": "Cloudflare API Token 有效（无法读取 Token 的权限，跳过权限检查）",
+	"New value is visible on all nameservers":                                                    "所有权威服务器上都已查到新的值",
+	"Skipping disabled record":                                                                   "跳过已停用的记录",
+	"Skipping record not visible on public resolvers":                                            "跳过公共 DNS 上查不到的记录",
+	"Set record status":                                                                          "已设置记录状态",
+	"Set record weight":                                                                          "已设置记录权重",
+	"Summary: ok":                                                                                "结果：成功",
+	"Summary: failed":                                                                            "结果：失败",
+	"PostUpdate failed":                                                                          "PostUpdate 命令执行失败",
+	"Failed to push metrics":                                                                     "推送监控指标失败",
+	"Failed to send notification":                                                                "发送通知失败",
+	"Failed to write audit log":                                                                  "写入审计日志失败",
+
+	// 更新结果
+	"ok":                                  "成功",
+	"failed":                              "失败",
+	"disabled":                            "已停用",
+	"(none)":                              "（无）",
+	" (unchanged)":                        "（未变化）",
+	"%d updated, %d unchanged, %d failed": "%d 条已更新，%d 条未变化，%d 条失败",
+	", %d disabled":                       "，%d 条已停用",
+	" (see errors above)":                 "（错误见上方）",
+	"Last check:   %s\n":                  "上次检查：%s\n",
+	"Last success: %s\n":                  "上次成功：%s\n",
+	"Last change:  %s\n":                  "上次变化：%s\n",
+	"Pending:      %s\n":                  "待发布：%s\n",
+	"never":                               "从未",
+	"%s (%s ago)":                         "%s（%s前）",
+	"failed to read status file: %v":      "读取状态输出文件失败：%v",
+	"StatusFile or StateFile is not configured":        "没有配置 StatusFile 或 StateFile",
+	"no successful check in %s":                        "%s 内没有成功的检查",
+	"last %d check(s) failed":                          "最近 %d 次检查失败",
+	"last check succeeded":                             "上次检查成功",
+	"%s -> %s, pending since %s, %d failed attempt(s)": "%s -> %s，自 %s 起等待发布，已失败 %d 次",
+	"Last error:   %s\n":                               "最近错误：%s\n",
+	"Added %s %s %s (%s)\n":                            "已新建 %s %s %s (%s)\n",
+	"Records to delete:":                               "将要删除的记录：",
+	"  %s %s, all values (%s)\n":                       "  %s %s，所有值 (%s)\n",
+	"Cancelled":                                        "已取消",
+	"Delete these records?":                            "删除这些记录？",
+	"%s: OK\n":                                         "%s：检查通过\n",
+	"%s: access OK\n":                                  "%s：可以访问\n",
+	"No history in the selected period":                "所选时间段内没有历史记录",
+	"  changes:\t%d in %s (%.2f per day)\n":            "  变化次数：\t%[2]s 内 %[1]d 次（每天 %.2[3]f 次）\n",
+	"  distinct values:\t%d\n":                         "  不同的值：\t%d\n",
+	"  failed updates:\t%d\n":                          "  更新失败：\t%d\n",
+	"  last change:\t%s (%s ago)\n":                    "  上次变化：\t%s（%s前）\n",
+	"  between changes:\tavg %s, median %s, shortest %s, longest %s\n": "  变化间隔：\t平均 %s，中位数 %s，最短 %s，最长 %s\n",
+	"  changes per day:":                                                       "  每天变化次数：",
+	"Config already uses Records, nothing to migrate":                          "配置已经使用 Records，不需要迁移",
+	"Migrated %s, the original is saved as %s.bak\n":                           "已迁移 %s，原文件保存为 %s.bak\n",
+	"Records/Domains are configured, removing the unused single-record fields": "已配置 Records/Domains，删除不再使用的单条记录字段",
+	"Config passphrase: ":                                                      "配置文件口令：",
+
+	// 终端仪表盘
+	"Dashboard failed":         "仪表盘运行失败",
+	"checking...":              "正在检查...",
+	"failed %d times in a row": "连续失败 %d 次",
+	"last check %s":            "上次检查 %s",
+	"next check in %s":         "%s后再次检查",
+	"RECORD\tTYPE\tPROVIDER\tVALUE\tLAST CHANGE\tSTATUS": "记录\t类型\t服务商\t值\t上次变化\t状态",
+	"%s ago":                 "%s前",
+	"updated":                "已更新",
+	"Recent log:":            "最近的日志：",
+	"u: update now  q: quit": "u：立即检查  q：退出",
+
+	// list、history 和 verify 的表格标题
+	"RECORD\tTYPE\tPROVIDER\tVALUE\tUPDATED\tENABLED": "记录\t类型\t服务商\t值\t更新时间\t启用",
+	"ZONE\tPROVIDER":                                                 "域名\t服务商",
+	"ID\tRECORD\tTYPE\tVALUE\tTTL\tPROVIDER":                         "ID\t记录\t类型\t值\tTTL\t服务商",
+	"TIME\tRECORD\tTYPE\tPROVIDER\tOLD\tNEW\tRESULT\tLATENCY\tERROR": "时间\t记录\t类型\t服务商\t原值\t新值\t结果\t耗时\t错误",
+	"RECORD\tTYPE\tRESOLVER\tEXPECTED\tFOUND\tRESULT":                "记录\t类型\t解析服务器\t期望值\t查询结果\t结果",
+
+	// doctor
+	"Check the config, credentials, IP sources and records and suggest fixes": "检查配置、凭据、IP 来源和记录，并给出处理建议",
+	"Some checks failed":       "部分检查没有通过",
+	"pass":                     "通过",
+	"warn":                     "警告",
+	"fail":                     "失败",
+	"%d of %d checks passed\n": "%d/%d 项检查通过\n",
+	"Fix the config file; aliddns config validate reports problems with line numbers":       "修改配置文件；aliddns config validate 会报告问题所在的行",
+	"Create a config file with aliddns init, or give -domain and -record":                   "用 aliddns init 生成配置文件，或者用 -domain 和 -record 指定记录",
+	"no config file, using flags and environment variables":                                 "没有配置文件，使用命令行参数和环境变量",
+	"Fix AllowCIDRs/DenyCIDRs in the config":                                                "修改配置中的 AllowCIDRs/DenyCIDRs",
+	"Fix IPSources/IPv6Sources in the config":                                               "修改配置中的 IPSources/IPv6Sources",
+	"Fix IPProxy, DNSServers and TLS in the config":                                         "修改配置中的 IPProxy、DNSServers 和 TLS",
+	"Make sure the source is reachable, or configure another source in IPSources":           "确认这个来源可以访问，或在 IPSources 中配置其他来源",
+	"Make sure this host has IPv6 connectivity, or configure another source in IPv6Sources": "确认本机可以通过 IPv6 上网，或在 IPv6Sources 中配置其他来源",
+	"global address %s":                   "公网地址 %s",
+	"no global IPv6 address on this host": "本机没有公网 IPv6 地址",
+	"AAAA records need IPv6: enable IPv6 (prefix delegation) on the router, or use an IPv6 source that runs elsewhere": "AAAA 记录需要 IPv6：在路由器上开启 IPv6（前缀委派），或使用其他设备提供的 IPv6 来源",
+	"no global IPv6 address on this host, not needed without AAAA records":                                             "本机没有公网 IPv6 地址，没有 AAAA 记录时不需要",
+	"API responded slowly": "接口响应较慢",
+	"Check the network path to the provider API, or use a closer endpoint":    "检查到服务商接口的网络，或使用更近的接入地址",
+	"does not exist yet, will be created by the first update (CreateMissing)": "还不存在，第一次更新时会创建（CreateMissing）",
+	"record does not exist": "记录不存在",
+	"Create it with aliddns add %s VALUE, or set CreateMissing to create it on update":         "用 aliddns add %s 值 新建，或配置 CreateMissing 在更新时创建",
+	"Check the API token, it needs Zone.DNS edit permission for this zone":                     "检查 API Token，需要这个域名的 Zone.DNS 编辑权限",
+	"Check AccessKeyID/AccessKeySecret, the RAM user needs AliyunPVTZFullAccess":               "检查 AccessKeyID/AccessKeySecret，RAM 用户需要 AliyunPVTZFullAccess 权限",
+	"Check AccessKeyID/AccessKeySecret, the RAM user needs AliyunDNSFullAccess":                "检查 AccessKeyID/AccessKeySecret，RAM 用户需要 AliyunDNSFullAccess 权限",
+	"Make sure the domain belongs to this account and DomainName/Record are spelled correctly": "确认域名在这个账号下，DomainName/Record 拼写正确",
+	"Check the network: DNS resolution, proxy and firewall rules for the provider API":         "检查网络：服务商接口的域名解析、代理和防火墙规则",
+	"Configure the credentials in the config file, environment variables or flags":             "在配置文件、环境变量或命令行参数中配置凭据",
+
+	// 生成配置文件
+	"%s already exists, overwrite?": "%s 已存在，是否覆盖？",
+	"DNS provider":                  "DNS 服务商",
+	"Aliyun DNS":                    "阿里云云解析",
+	"Aliyun PrivateZone (intranet)": "阿里云 PrivateZone（内网）",
+	"Cloudflare API token (needs Zone.DNS edit permission)":   "Cloudflare API Token（需要 Zone.DNS 编辑权限）",
+	"Could not list domains (%v), please enter it manually\n": "无法列出域名（%v），请手动填写\n",
+	"Could not list records: %v\n":                            "无法列出记录：%v\n",
+	"Domain":                                                  "域名",
+	"Domain name (e.g. example.com)":                          "域名（例如 example.com）",
+	"New record":                                              "新建记录",
+	"Record to update":                                        "要更新的记录",
+	"Record name (e.g. home, or @ for the domain itself)":     "主机记录（例如 home，域名本身填 @）",
+	"Address type":                                            "地址类型",
+	"Both":                                                    "两者都要",
+	"Check interval for daemon mode (e.g. 5m, empty to run once)": "常驻模式的检查间隔（例如 5m，留空只运行一次）",
+	"Config written to %s, run: aliddns -c %s\n":                  "配置已写入 %s，运行：aliddns -c %s\n",
+	"Choose": "请选择",
+	"Please enter a number between 1 and %d\n": "请输入 1 到 %d 之间的数字\n",
+
+	// 通知
+	"DNS update failed %d times in a row": "DNS 更新连续失败 %d 次",
+	"No DNS record changed":               "DNS 记录没有变化",
+	"All records are up to date":          "所有记录都是最新的",
+	"DNS record updated":                  "DNS 记录已更新",
+	"%d DNS records updated":              "%d 条 DNS 记录已更新",
+}
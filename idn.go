@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// 国际化域名（例如 中文.com）的转换规则。不使用 STD3 规则，允许 _acme-challenge 这样带下划线的名称
+var idnProfile = idna.New(idna.MapForLookup(), idna.BidiRule(), idna.StrictDomainName(false), idna.VerifyDNSLength(true))
+
+// 把包含非 ASCII 字符的名称转换为 punycode（xn--），纯 ASCII 的名称（包括 @ 和通配符）保持不变
+func toASCIIName(name string) (string, error) {
+	if isASCII(name) {
+		return name, nil
+	}
+	ascii, err := idnProfile.ToASCII(name)
+	if err != nil {
+		return "", fmt.Errorf("invalid internationalized name %q: %w", name, err)
+	}
+	return ascii, nil
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// 把配置中的域名、记录名和主机名类记录的值统一转换为 punycode，API 调用和比较都使用转换后的名称
+func (c *Config) normalizeIDN() error {
+	names := []*string{&c.DomainName, &c.Record, &c.CFDomainName, &c.CFRecordName}
+	for i := range c.Records {
+		names = append(names, c.Records[i].idnNames()...)
+	}
+	for i := range c.Domains {
+		names = append(names, &c.Domains[i].DomainName)
+		for j := range c.Domains[i].Records {
+			names = append(names, c.Domains[i].Records[j].idnNames()...)
+		}
+	}
+
+	for _, name := range names {
+		ascii, err := toASCIIName(*name)
+		if err != nil {
+			return err
+		}
+		*name = ascii
+	}
+	return nil
+}
+
+// 记录中需要转换的名称
+func (r *RecordConfig) idnNames() []*string {
+	names := []*string{&r.DomainName, &r.Record}
+	switch strings.ToUpper(r.RecordType) {
+	case "CNAME", "NS", "MX":
+		names = append(names, &r.Value)
+	}
+	return names
+}
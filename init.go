@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
+)
+
+// init 向导生成的配置，只包含向导询问的字段，按填写的顺序输出
+type initConfig struct {
+	AccessKeyID     string         `json:"AccessKeyID,omitempty" yaml:"AccessKeyID,omitempty"`
+	AccessKeySecret string         `json:"AccessKeySecret,omitempty" yaml:"AccessKeySecret,omitempty"`
+	CFAPIToken      string         `json:"CF_API_TOKEN,omitempty" yaml:"CF_API_TOKEN,omitempty"`
+	Records         []initRecord   `json:"Records" yaml:"Records"`
+	DualStack       bool           `json:"DualStack,omitempty" yaml:"DualStack,omitempty"`
+	IPSources       []SourceConfig `json:"IPSources,omitempty" yaml:"IPSources,omitempty"`
+	Interval        string         `json:"Interval,omitempty" yaml:"Interval,omitempty"`
+}
+
+type initRecord struct {
+	Provider   string `json:"Provider" yaml:"Provider"`
+	DomainName string `json:"DomainName" yaml:"DomainName"`
+	Record     string `json:"Record" yaml:"Record"`
+	RecordType string `json:"RecordType" yaml:"RecordType"`
+}
+
+// 交互式生成配置文件：aliddns -c config.json init。
+// 依次询问服务商、凭据、域名和记录，凭据可用时列出账号下的域名和记录供选择
+func runInit(filename string) error {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext == ".toml" || ext == ".age" {
+		return fmt.Errorf("init writes JSON or YAML, use a .json or .yaml file name")
+	}
+
+	in := &prompter{reader: bufio.NewReader(os.Stdin)}
+	if _, err := os.Stat(filename); err == nil {
+		if !in.confirm(trf("%s already exists, overwrite?", filename), false) {
+			return nil
+		}
+	}
+
+	providers := []string{"aliyun", "cloudflare", "pvtz"}
+	providerName := providers[in.choose("DNS provider", []string{"Aliyun DNS", "Cloudflare", "Aliyun PrivateZone (intranet)"}, 0)]
+
+	var out initConfig
+	var config Config
+	if providerName == "cloudflare" {
+		out.CFAPIToken = in.secret("Cloudflare API token (needs Zone.DNS edit permission)")
+		config.CFAPIToken = out.CFAPIToken
+	} else {
+		out.AccessKeyID = in.ask("Aliyun AccessKeyID", "")
+		out.AccessKeySecret = in.secret("Aliyun AccessKeySecret")
+		config.AccessKeyID, config.AccessKeySecret = out.AccessKeyID, out.AccessKeySecret
+	}
+
+	// 能连上服务商时从账号中选择，否则手动填写
+	var lister zoneLister
+	ctx, cancel := config.runContext()
+	defer cancel()
+	if p, err := newProvider(ctx, config, providerName); err == nil {
+		lister, _ = p.(zoneLister)
+	}
+
+	rec := initRecord{Provider: providerName, RecordType: "A"}
+	var existing []zoneRecord
+	if lister != nil {
+		zones, err := lister.listZones()
+		switch {
+		case err != nil:
+			fmt.Print(trf("Could not list domains (%v), please enter it manually\n", err))
+		case len(zones) > 0:
+			rec.DomainName = zones[in.choose("Domain", zones, 0)]
+			if existing, err = lister.listRecords(rec.DomainName); err != nil {
+				fmt.Print(trf("Could not list records: %v\n", err))
+			}
+		}
+	}
+	if rec.DomainName == "" {
+		rec.DomainName = in.ask("Domain name (e.g. example.com)", "")
+	}
+
+	// 已有的 A/AAAA 记录可以直接选择，也可以填写新的记录名
+	var options []string
+	var addresses []zoneRecord
+	for _, r := range existing {
+		if isAddressType(r.RecordType) {
+			addresses = append(addresses, r)
+			options = append(options, fmt.Sprintf("%s %s %s", r.Record, r.RecordType, r.Value))
+		}
+	}
+	if len(addresses) > 0 {
+		options = append(options, "New record")
+		if i := in.choose("Record to update", options, len(options)-1); i < len(addresses) {
+			rec.Record, rec.RecordType = addresses[i].Record, addresses[i].RecordType
+		}
+	}
+	if rec.Record == "" {
+		rec.Record = in.ask("Record name (e.g. home, or @ for the domain itself)", "@")
+		switch in.choose("Address type", []string{"IPv4 (A)", "IPv6 (AAAA)", "Both"}, 0) {
+		case 1:
+			rec.RecordType = "AAAA"
+		case 2:
+			out.DualStack = true
+		}
+	}
+	out.Records = []initRecord{rec}
+
+	// 内网 DNS 发布局域网地址
+	if providerName == "pvtz" {
+		out.IPSources = []SourceConfig{{Type: "local"}}
+	}
+	out.Interval = in.ask("Check interval for daemon mode (e.g. 5m, empty to run once)", "")
+
+	var data []byte
+	var err error
+	if ext == ".yaml" || ext == ".yml" {
+		data, err = yaml.Marshal(out)
+	} else {
+		data, err = json.MarshalIndent(out, "", "    ")
+	}
+	if err != nil {
+		return err
+	}
+
+	// 写入前按运行时的规则检查一遍
+	var check Config
+	jsonData, _ := json.Marshal(out)
+	if err := json.Unmarshal(jsonData, &check); err != nil {
+		return err
+	}
+	if problems := check.validate(); len(problems) > 0 {
+		return fmt.Errorf("generated config is invalid: %s", strings.Join(problems, "; "))
+	}
+
+	// 配置文件中有凭据，只允许当前用户读写
+	if err := ioutil.WriteFile(filename, data, 0600); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	fmt.Print(trf("Config written to %s, run: aliddns -c %s\n", filename, filename))
+	return nil
+}
+
+// 从终端读取回答
+type prompter struct {
+	reader *bufio.Reader
+}
+
+// 询问一个值，直接回车时使用默认值
+func (p *prompter) ask(question, def string) string {
+	for {
+		if def != "" {
+			fmt.Printf("%s [%s]: ", tr(question), def)
+		} else {
+			fmt.Printf("%s: ", tr(question))
+		}
+		line, err := p.reader.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if line == "" {
+			line = def
+		}
+		// 没有默认值的问题必须填写，输入结束时不再重复询问
+		if line != "" || def != "" || err != nil {
+			return line
+		}
+	}
+}
+
+// 询问凭据，在终端中输入时不回显
+func (p *prompter) secret(question string) string {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return p.ask(question, "")
+	}
+	for {
+		fmt.Printf("%s: ", tr(question))
+		b, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Println()
+		if s := strings.TrimSpace(string(b)); s != "" || err != nil {
+			return s
+		}
+	}
+}
+
+// 从列表中选择一项，返回下标
+func (p *prompter) choose(question string, options []string, def int) int {
+	fmt.Println(tr(question) + ":")
+	for i, option := range options {
+		fmt.Printf("  %d) %s\n", i+1, tr(option))
+	}
+	for {
+		answer := p.ask("Choose", strconv.Itoa(def+1))
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(options) {
+			return n - 1
+		}
+		fmt.Print(trf("Please enter a number between 1 and %d\n", len(options)))
+	}
+}
+
+// 询问是或否
+func (p *prompter) confirm(question string, def bool) bool {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	fmt.Printf("%s (%s): ", tr(question), hint)
+	line, _ := p.reader.ReadString('\n')
+	answer := strings.ToLower(strings.TrimSpace(line))
+	if answer == "" {
+		return def
+	}
+	return answer == "y" || answer == "yes"
+}
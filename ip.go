@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+)
+
+// ip 命令检测到的地址
+type detectedIP struct {
+	IPv4 string `json:"IPv4,omitempty"`
+	IPv6 string `json:"IPv6,omitempty"`
+}
+
+// 只运行 IP 检测并输出地址，不调用服务商接口：aliddns -c config.json ip [-4] [-6]。
+// 使用全局的 IPSources/IPv6Sources 和地址过滤，默认检测配置的记录用到的地址族
+func runIP(u *updater, args []string, format string) error {
+	fs := flag.NewFlagSet("ip", flag.ExitOnError)
+	only4 := fs.Bool("4", false, "Only detect the IPv4 address")
+	only6 := fs.Bool("6", false, "Only detect the IPv6 address")
+	fs.StringVar(&format, "o", format, "Output format: table, json or yaml")
+	fs.Parse(args)
+	if err := checkOutputFormat(format); err != nil {
+		return withExitCode(exitConfig, err)
+	}
+
+	detect4, detect6 := *only4, *only6
+	if !detect4 && !detect6 {
+		for _, rec := range u.config.records() {
+			detect4 = detect4 || rec.RecordType == "A"
+			detect6 = detect6 || rec.RecordType == "AAAA"
+		}
+		if !detect6 {
+			detect4 = true
+		}
+	}
+
+	ctx, cancel := u.config.runContext()
+	defer cancel()
+	var result detectedIP
+	var err error
+	if detect4 {
+		if result.IPv4, err = u.detect(ctx, nil, 4); err != nil {
+			return withExitCode(exitDetect, fmt.Errorf("failed to get external IPv4 address: %w", err))
+		}
+	}
+	if detect6 {
+		if result.IPv6, err = u.detect(ctx, nil, 6); err != nil {
+			return withExitCode(exitDetect, fmt.Errorf("failed to get external IPv6 address: %w", err))
+		}
+	}
+
+	// 表格格式每行一个地址，方便在脚本中使用：IP=$(aliddns ip -4)
+	return writeFormatted(format, result, func(w io.Writer) error {
+		for _, ip := range []string{result.IPv4, result.IPv6} {
+			if ip != "" {
+				fmt.Fprintln(w, ip)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// IP 地址过滤器，按 CIDR 白名单/黑名单过滤检测到的地址
+type ipFilter struct {
+	allow []netip.Prefix
+	deny  []netip.Prefix
+}
+
+// 解析 CIDR 列表
+func parsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, c := range cidrs {
+		p, err := netip.ParsePrefix(strings.TrimSpace(c))
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", c, err)
+		}
+		prefixes = append(prefixes, p.Masked())
+	}
+	return prefixes, nil
+}
+
+// 创建 IP 过滤器
+func newIPFilter(allow, deny []string) (*ipFilter, error) {
+	allowPrefixes, err := parsePrefixes(allow)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse AllowCIDRs: %w", err)
+	}
+	denyPrefixes, err := parsePrefixes(deny)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse DenyCIDRs: %w", err)
+	}
+	return &ipFilter{allow: allowPrefixes, deny: denyPrefixes}, nil
+}
+
+// 检查地址是否允许发布，黑名单优先；白名单为空时允许所有地址
+func (f *ipFilter) check(ip string) error {
+	addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+	if err != nil {
+		return fmt.Errorf("invalid IP address %q: %w", ip, err)
+	}
+	addr = addr.Unmap()
+
+	for _, p := range f.deny {
+		if p.Contains(addr) {
+			return fmt.Errorf("IP address %s is in denied range %s", addr, p)
+		}
+	}
+
+	if len(f.allow) == 0 {
+		return nil
+	}
+	for _, p := range f.allow {
+		if p.Contains(addr) {
+			return nil
+		}
+	}
+	return fmt.Errorf("IP address %s is not in any allowed range", addr)
+}
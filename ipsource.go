@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// 默认的外网 IP 查询地址
+const defaultIPURL = "http://icanhazip.com"
+
+// IP 来源配置
+type SourceConfig struct {
+	Type      string `json:"Type"`      // http（默认）、file、tailscale、interface 或 local
+	URL       string `json:"URL"`       // http 来源的查询地址
+	Path      string `json:"Path"`      // file 来源的文件路径
+	Socket    string `json:"Socket"`    // tailscale 来源的 tailscaled 套接字路径
+	Interface string `json:"Interface"` // interface 来源的网卡名称，例如 wg0
+}
+
+// 记录类型对应的地址族，AAAA 记录使用 IPv6，其余使用 IPv4
+func familyOf(recordType string) int {
+	if strings.EqualFold(recordType, "AAAA") {
+		return 6
+	}
+	return 4
+}
+
+// 检查地址是否属于指定的地址族
+func checkFamily(ip string, family int) error {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return fmt.Errorf("invalid IP address %q: %w", ip, err)
+	}
+	if family == 6 && !addr.Is6() || family == 4 && !addr.Unmap().Is4() {
+		return fmt.Errorf("%s is not an IPv%d address", ip, family)
+	}
+	return nil
+}
+
+// IP 来源
+type ipSource interface {
+	getIP(ctx context.Context) (string, error)
+	String() string
+}
+
+// 通过 HTTP 接口查询外网 IP，按地址族限定连接使用 IPv4 或 IPv6
+type httpSource struct {
+	url    string
+	family int
+	client *http.Client // 多次检查共用，可以复用连接
+}
+
+func (s *httpSource) getIP(ctx context.Context) (string, error) {
+	return getExternalIP(ctx, s.client, s.url)
+}
+
+func (s *httpSource) String() string {
+	return "http " + s.url
+}
+
+// 从文件读取 IP，文件由路由器脚本或其他工具写入
+type fileSource struct {
+	path string
+}
+
+func (s *fileSource) getIP(ctx context.Context) (string, error) {
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read IP file: %w", err)
+	}
+	ip := strings.TrimSpace(string(data))
+	if ip == "" {
+		return "", fmt.Errorf("IP file %s is empty", s.path)
+	}
+	return ip, nil
+}
+
+func (s *fileSource) String() string {
+	return "file " + s.path
+}
+
+// 默认的 tailscaled 本地 API 套接字
+const defaultTailscaleSocket = "/var/run/tailscale/tailscaled.sock"
+
+// 通过 tailscaled 本地 API 读取本机的 tailnet 地址
+type tailscaleSource struct {
+	socket string
+	family int
+	client *http.Client
+}
+
+// 连接本机套接字的开销很小，不保留空闲连接：记录单独配置的来源每次检查都重新创建，
+// 保留的连接和对应的 goroutine 在常驻模式下会不断累积
+func newTailscaleSource(socket string, family int) *tailscaleSource {
+	return &tailscaleSource{
+		socket: socket,
+		family: family,
+		client: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", socket)
+				},
+				DisableKeepAlives: true,
+			},
+		},
+	}
+}
+
+func (s *tailscaleSource) getIP(ctx context.Context) (string, error) {
+	// tailscaled 会校验 Host，必须使用 local-tailscaled.sock
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://local-tailscaled.sock/localapi/v0/status", nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to query tailscaled: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("tailscaled returned status %d", resp.StatusCode)
+	}
+
+	var status struct {
+		Self struct {
+			TailscaleIPs []string `json:"TailscaleIPs"`
+		} `json:"Self"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return "", fmt.Errorf("failed to decode tailscaled status: %w", err)
+	}
+
+	for _, ip := range status.Self.TailscaleIPs {
+		if checkFamily(ip, s.family) == nil {
+			return ip, nil
+		}
+	}
+	return "", fmt.Errorf("no tailnet IPv%d address assigned", s.family)
+}
+
+func (s *tailscaleSource) String() string {
+	return "tailscale " + s.socket
+}
+
+// 读取本机网卡（例如 WireGuard 等 VPN 网卡）上的地址
+type interfaceSource struct {
+	name   string
+	family int
+}
+
+func (s *interfaceSource) getIP(ctx context.Context) (string, error) {
+	iface, err := net.InterfaceByName(s.name)
+	if err != nil {
+		return "", fmt.Errorf("failed to find interface: %w", err)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return "", fmt.Errorf("failed to list interface addresses: %w", err)
+	}
+
+	for _, a := range addrs {
+		ipNet, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if ipNet.IP.IsLinkLocalUnicast() || (ipNet.IP.To4() != nil) != (s.family == 4) {
+			continue
+		}
+		return ipNet.IP.String(), nil
+	}
+	return "", fmt.Errorf("no IPv%d address on interface %s", s.family, s.name)
+}
+
+func (s *interfaceSource) String() string {
+	return "interface " + s.name
+}
+
+// 默认路由出口网卡上的局域网地址，用于内网解析
+type localSource struct {
+	family int
+}
+
+func (s *localSource) getIP(ctx context.Context) (string, error) {
+	// UDP 的 Dial 不发送数据，只按路由表选出源地址
+	network, target := "udp4", "223.5.5.5:53"
+	if s.family == 6 {
+		network, target = "udp6", "[2400:3200::1]:53"
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, target)
+	if err != nil {
+		return "", fmt.Errorf("failed to find local address: %w", err)
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP.String(), nil
+}
+
+func (s *localSource) String() string {
+	return "local"
+}
+
+// 根据配置创建指定地址族的 IP 来源，未配置时使用默认的 HTTP 查询地址。
+// http 来源通过 transport 连接，transport 须按地址族限定（见 familyTransport），为 nil 时使用默认设置
+func newIPSources(configs []SourceConfig, family int, transport *http.Transport) ([]ipSource, error) {
+	if transport == nil {
+		transport = familyTransport(http.DefaultTransport.(*http.Transport).Clone(), family)
+	}
+	client := &http.Client{Transport: transport}
+	if len(configs) == 0 {
+		return []ipSource{&httpSource{url: defaultIPURL, family: family, client: client}}, nil
+	}
+
+	sources := make([]ipSource, 0, len(configs))
+	for _, c := range configs {
+		switch strings.ToLower(c.Type) {
+		case "", "http":
+			url := c.URL
+			if url == "" {
+				url = defaultIPURL
+			}
+			sources = append(sources, &httpSource{url: url, family: family, client: client})
+		case "file":
+			if c.Path == "" {
+				return nil, fmt.Errorf("file IP source requires Path")
+			}
+			sources = append(sources, &fileSource{path: c.Path})
+		case "tailscale":
+			socket := c.Socket
+			if socket == "" {
+				socket = defaultTailscaleSocket
+			}
+			sources = append(sources, newTailscaleSource(socket, family))
+		case "interface":
+			if c.Interface == "" {
+				return nil, fmt.Errorf("interface IP source requires Interface")
+			}
+			sources = append(sources, &interfaceSource{name: c.Interface, family: family})
+		case "local":
+			sources = append(sources, &localSource{family: family})
+		default:
+			return nil, fmt.Errorf("unknown IP source type %q", c.Type)
+		}
+	}
+	return sources, nil
+}
+
+// 依次尝试各个来源，返回第一个属于该地址族并通过过滤的地址。每个来源最多等待 timeout
+func detectIP(ctx context.Context, sources []ipSource, family int, filter *ipFilter, timeout time.Duration) (string, error) {
+	var errs detectError
+	for _, s := range sources {
+		start := time.Now()
+		sctx, cancel := context.WithTimeout(ctx, timeout)
+		ip, err := s.getIP(sctx)
+		cancel()
+		latency := time.Since(start)
+		metrics.observeSourceLatency(s.String(), latency, err != nil)
+		slog.Debug("IP source responded", "source", s.String(), "latency", latency, "error", err)
+		if err == nil {
+			err = checkFamily(ip, family)
+		}
+		if err == nil {
+			err = filter.check(ip)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s, err))
+			continue
+		}
+		return ip, nil
+	}
+	if err := ctx.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return "", errs
+}
+
+// 所有来源都失败时的错误，保留每个来源的错误，用于判断是否可以重试
+type detectError []error
+
+func (e detectError) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return "no usable IP address: " + strings.Join(msgs, "; ")
+}
+
+func (e detectError) Unwrap() []error {
+	return e
+}
+
+// 获取本地外网 IP 地址，client 的连接限定了地址族
+func getExternalIP(ctx context.Context, client *http.Client, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get external IP: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get external IP: %w", &httpStatusError{code: resp.StatusCode})
+	}
+
+	var ip bytes.Buffer
+	if _, err := io.Copy(&ip, resp.Body); err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return strings.TrimSpace(ip.String()), nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+)
+
+// journald 接收日志的套接字
+const journalSocket = "/run/systemd/journal/socket"
+
+// 通过 journald 原生协议写入的日志，属性作为单独的字段保存，可以用 journalctl 按字段过滤，
+// 例如 journalctl RECORD=home.example.com
+type journaldSink struct {
+	conn *net.UnixConn
+}
+
+func newJournaldSink() (*journaldSink, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journalSocket, Net: "unixgram"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to journald: %w", err)
+	}
+	return &journaldSink{conn: conn}, nil
+}
+
+func (s *journaldSink) send(level slog.Level, msg string, attrs []slog.Attr) error {
+	priority := "6"
+	switch {
+	case level >= slog.LevelError:
+		priority = "3"
+	case level >= slog.LevelWarn:
+		priority = "4"
+	case level < slog.LevelInfo:
+		priority = "7"
+	}
+
+	var b bytes.Buffer
+	writeJournalField(&b, "MESSAGE", msg)
+	writeJournalField(&b, "PRIORITY", priority)
+	writeJournalField(&b, "SYSLOG_IDENTIFIER", "aliddns")
+	for _, a := range attrs {
+		if key := journalFieldName(a.Key); key != "" {
+			writeJournalField(&b, key, a.Value.String())
+		}
+	}
+	_, err := s.conn.Write(b.Bytes())
+	return err
+}
+
+// 单行的值写成 KEY=value，含换行的值写成 KEY、8 字节小端长度和值
+func writeJournalField(b *bytes.Buffer, key, value string) {
+	b.WriteString(key)
+	if !strings.Contains(value, "\n") {
+		b.WriteString("=" + value + "\n")
+		return
+	}
+	b.WriteByte('\n')
+	binary.Write(b, binary.LittleEndian, uint64(len(value)))
+	b.WriteString(value + "\n")
+}
+
+// 字段名只能包含大写字母、数字和下划线，不能以下划线或数字开头
+func journalFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, key)
+	name = strings.TrimLeft(name, "_0123456789")
+	// 避免覆盖上面写入的字段
+	switch name {
+	case "MESSAGE", "PRIORITY", "SYSLOG_IDENTIFIER":
+		return "ATTR_" + name
+	}
+	return name
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime"
+)
+
+type journaldSink struct{}
+
+func newJournaldSink() (*journaldSink, error) {
+	return nil, fmt.Errorf("journald is not supported on %s", runtime.GOOS)
+}
+
+func (s *journaldSink) send(level slog.Level, msg string, attrs []slog.Attr) error {
+	return nil
+}
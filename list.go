@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// list 命令：不带参数时列出配置的记录；zones 列出凭据可以看到的域名，
+// records 列出域名下的全部记录，用于查找要填写到配置中的记录名
+func runList(config Config, args []string, format string) error {
+	if len(args) == 0 {
+		return listConfigured(config, format)
+	}
+	switch args[0] {
+	case "zones":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: list zones")
+		}
+		return listZones(config, format)
+	case "records":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: list records ZONE")
+		}
+		return listZoneRecords(config, args[1], format)
+	default:
+		return fmt.Errorf("unknown list type %q, use zones or records", args[0])
+	}
+}
+
+// 配置的一条记录和上次发布的值
+type listedRecord struct {
+	Record   string     `json:"Record"` // 完整域名
+	Type     string     `json:"Type"`
+	Provider string     `json:"Provider"`
+	Value    string     `json:"Value,omitempty"`
+	Updated  *time.Time `json:"Updated,omitempty"`
+	Enabled  bool       `json:"Enabled"`
+}
+
+// 列出配置的记录和状态文件中记录的上次发布的值，不调用服务商接口
+func listConfigured(config Config, format string) error {
+	state, err := loadState(config.StateFile)
+	if err != nil {
+		return fmt.Errorf("failed to read state: %w", err)
+	}
+
+	records := []listedRecord{}
+	for _, rec := range config.records() {
+		r := listedRecord{Record: rec.fqdn(), Type: rec.RecordType, Provider: rec.Provider, Enabled: true}
+		if rs, ok := state.Records[rec.key()]; ok {
+			r.Value, r.Enabled, r.Updated = rs.IP, !rs.Disabled, optionalTime(rs.Updated)
+		}
+		records = append(records, r)
+	}
+
+	return writeFormatted(format, records, func(out io.Writer) error {
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, tr("RECORD\tTYPE\tPROVIDER\tVALUE\tUPDATED\tENABLED"))
+		for _, r := range records {
+			value, updated, enabled := "-", "-", "yes"
+			if r.Value != "" {
+				value = r.Value
+			}
+			if r.Updated != nil {
+				updated = r.Updated.Local().Format("2006-01-02 15:04:05")
+			}
+			if !r.Enabled {
+				enabled = "no"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Record, r.Type, r.Provider, value, updated, enabled)
+		}
+		return w.Flush()
+	})
+}
+
+// 要查询的服务商：配置的记录用到的服务商，-provider 指定时只有这一个
+func listProviders(ctx context.Context, config Config, zone string) ([]zoneLister, []string, error) {
+	var names []string
+	seen := make(map[string]bool)
+	for _, rec := range config.records() {
+		if !seen[rec.Provider] {
+			seen[rec.Provider] = true
+			names = append(names, rec.Provider)
+		}
+	}
+	// 查询记录时优先使用配置中管理这个域名的服务商
+	if zone != "" {
+		for _, rec := range config.records() {
+			if rec.DomainName == zone {
+				names = []string{rec.Provider}
+				break
+			}
+		}
+	}
+
+	var listers []zoneLister
+	for _, name := range names {
+		p, err := newProvider(ctx, config, name)
+		if err != nil {
+			return nil, nil, err
+		}
+		lister, ok := p.(zoneLister)
+		if !ok {
+			return nil, nil, fmt.Errorf("provider %s cannot list zones", name)
+		}
+		listers = append(listers, lister)
+	}
+	return listers, names, nil
+}
+
+// 服务商账号下的一个域名
+type listedZone struct {
+	Zone     string `json:"Zone"`
+	Provider string `json:"Provider"`
+}
+
+// 列出每个服务商账号下的域名
+func listZones(config Config, format string) error {
+	ctx, cancel := config.runContext()
+	defer cancel()
+	listers, names, err := listProviders(ctx, config, "")
+	if err != nil {
+		return err
+	}
+	zones := []listedZone{}
+	for i, lister := range listers {
+		found, err := lister.listZones()
+		if err != nil {
+			return fmt.Errorf("failed to list %s zones: %w", names[i], err)
+		}
+		for _, zone := range found {
+			zones = append(zones, listedZone{Zone: zone, Provider: names[i]})
+		}
+	}
+
+	return writeFormatted(format, zones, func(out io.Writer) error {
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, tr("ZONE\tPROVIDER"))
+		for _, z := range zones {
+			fmt.Fprintf(w, "%s\t%s\n", z.Zone, z.Provider)
+		}
+		return w.Flush()
+	})
+}
+
+// 服务商中域名下的一条记录
+type listedZoneRecord struct {
+	ID       string `json:"ID"`
+	Record   string `json:"Record"`
+	Type     string `json:"Type"`
+	Value    string `json:"Value"`
+	TTL      int    `json:"TTL"`
+	Provider string `json:"Provider"`
+}
+
+// 列出域名下的全部记录，包括记录 ID 和 TTL
+func listZoneRecords(config Config, zone, format string) error {
+	zone, err := toASCIIName(zone)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := config.runContext()
+	defer cancel()
+	listers, names, err := listProviders(ctx, config, zone)
+	if err != nil {
+		return err
+	}
+
+	// 域名不在配置中时逐个服务商查询，只要有一个查到就不报错
+	records := []listedZoneRecord{}
+	var errs []string
+	for i, lister := range listers {
+		found, err := lister.listRecords(zone)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", names[i], err))
+			continue
+		}
+		for _, r := range found {
+			records = append(records, listedZoneRecord{ID: r.ID, Record: r.Record, Type: r.RecordType, Value: r.Value, TTL: r.TTL, Provider: names[i]})
+		}
+	}
+	if len(errs) == len(listers) {
+		return fmt.Errorf("failed to list records in %s: %s", zone, strings.Join(errs, "; "))
+	}
+
+	return writeFormatted(format, records, func(out io.Writer) error {
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, tr("ID\tRECORD\tTYPE\tVALUE\tTTL\tPROVIDER"))
+		for _, r := range records {
+			ttl := strconv.Itoa(r.TTL)
+			if r.Provider == "cloudflare" && r.TTL == 1 {
+				ttl = "auto"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", r.ID, r.Record, r.Type, r.Value, ttl, r.Provider)
+		}
+		return w.Flush()
+	})
+}
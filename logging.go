@@ -0,0 +1,120 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// 日志选项，级别、格式和日志文件可以用环境变量设置默认值
+type logOptions struct {
+	level  string
+	format string
+
+	// 日志文件，留空时输出到标准错误。超过 maxSize（MB）后轮转，
+	// 轮转后的文件保留 maxAge 天、最多 maxBackups 个，0 表示不限制
+	file       string
+	maxSize    int
+	maxAge     int
+	maxBackups int
+	compress   bool
+
+	// 写入 syslog（local 或远程地址）或 journald，与 file 只能选一个
+	syslog   string
+	journald bool
+}
+
+// 当前的日志级别，只运行一次并输出结果时调高到 warn，见 output.go
+var logLevel = new(slog.LevelVar)
+
+// 注册日志相关的命令行参数
+func registerLogFlags() *logOptions {
+	o := &logOptions{}
+	flag.StringVar(&o.level, "log-level", os.Getenv(envPrefix+"LOG_LEVEL"), "Log level: debug, info, warn or error (default info)")
+	flag.StringVar(&o.format, "log-format", os.Getenv(envPrefix+"LOG_FORMAT"), "Log format: text or json (default text)")
+	flag.StringVar(&o.file, "log-file", os.Getenv(envPrefix+"LOG_FILE"), "Write logs to this file instead of stderr, rotated by size")
+	flag.IntVar(&o.maxSize, "log-max-size", 10, "Rotate the log file when it reaches this size in MB")
+	flag.IntVar(&o.maxAge, "log-max-age", 30, "Delete rotated log files older than this many days (0 keeps them)")
+	flag.IntVar(&o.maxBackups, "log-max-backups", 5, "Number of rotated log files to keep (0 keeps all)")
+	flag.BoolVar(&o.compress, "log-compress", false, "Compress rotated log files with gzip")
+	flag.StringVar(&o.syslog, "log-syslog", os.Getenv(envPrefix+"LOG_SYSLOG"), "Send logs to syslog: local, or a remote address such as udp://host:514")
+	journald, _ := strconv.ParseBool(os.Getenv(envPrefix + "LOG_JOURNALD"))
+	flag.BoolVar(&o.journald, "log-journald", journald, "Send logs to the systemd journal")
+	return o
+}
+
+// 初始化日志：级别为 debug、info（默认）、warn 或 error，格式为 text（默认）或 json。
+// 同时接管标准库 log 包的输出
+func setupLogging(o *logOptions) error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(o.level)); o.level != "" && err != nil {
+		return fmt.Errorf("invalid log level %q, use debug, info, warn or error", o.level)
+	}
+	logLevel.Set(level)
+
+	outputs := 0
+	for _, set := range []bool{o.file != "", o.syslog != "", o.journald} {
+		if set {
+			outputs++
+		}
+	}
+	if outputs > 1 {
+		return fmt.Errorf("only one of -log-file, -log-syslog and -log-journald can be used")
+	}
+
+	// syslog 和 journald 自带时间和级别，不使用 -log-format
+	var sink logSink
+	var err error
+	switch {
+	case o.syslog != "":
+		sink, err = newSyslogSink(o.syslog)
+	case o.journald:
+		sink, err = newJournaldSink()
+	}
+	if err != nil {
+		return err
+	}
+	if sink != nil {
+		slog.SetDefault(slog.New(translateHandler{newSinkHandler(sink, logLevel)}))
+		return nil
+	}
+
+	var out io.Writer = os.Stderr
+	if o.file != "" {
+		if o.maxSize <= 0 || o.maxAge < 0 || o.maxBackups < 0 {
+			return fmt.Errorf("invalid log rotation options")
+		}
+		out = &lumberjack.Logger{
+			Filename:   o.file,
+			MaxSize:    o.maxSize,
+			MaxAge:     o.maxAge,
+			MaxBackups: o.maxBackups,
+			LocalTime:  true,
+			Compress:   o.compress,
+		}
+	}
+
+	opts := &slog.HandlerOptions{Level: logLevel}
+	var handler slog.Handler
+	switch strings.ToLower(o.format) {
+	case "", "text":
+		handler = slog.NewTextHandler(out, opts)
+	case "json":
+		handler = slog.NewJSONHandler(out, opts)
+	default:
+		return fmt.Errorf("invalid log format %q, use text or json", o.format)
+	}
+	slog.SetDefault(slog.New(translateHandler{handler}))
+	return nil
+}
+
+// 日志中的记录显示为 "服务商 主机记录/域名 (类型)"，JSON 格式下也不展开整个结构体
+func (r RecordConfig) LogValue() slog.Value {
+	return slog.StringValue(r.String())
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// syslog 和 journald 的输出端，按级别发送一条日志，attrs 为已展开的属性
+type logSink interface {
+	send(level slog.Level, msg string, attrs []slog.Attr) error
+}
+
+// 把日志交给 syslog 或 journald 的 Handler。时间和主机名由接收端记录，这里不再输出
+type sinkHandler struct {
+	level slog.Leveler
+	sink  logSink
+	attrs []slog.Attr // WithAttrs 添加的属性，键已带上分组前缀
+	group string      // WithGroup 的分组前缀，例如 "a.b."
+}
+
+func newSinkHandler(sink logSink, level slog.Leveler) *sinkHandler {
+	return &sinkHandler{level: level, sink: sink}
+}
+
+func (h *sinkHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *sinkHandler) Handle(_ context.Context, r slog.Record) error {
+	attrs := append([]slog.Attr(nil), h.attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = appendAttr(attrs, h.group, a)
+		return true
+	})
+	return h.sink.send(r.Level, r.Message, attrs)
+}
+
+func (h *sinkHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.attrs = append([]slog.Attr(nil), h.attrs...)
+	for _, a := range attrs {
+		h2.attrs = appendAttr(h2.attrs, h.group, a)
+	}
+	return &h2
+}
+
+func (h *sinkHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.group = h.group + name + "."
+	return &h2
+}
+
+// 展开属性：解析 LogValuer，分组展开为 "组.键"，忽略空属性
+func appendAttr(attrs []slog.Attr, prefix string, a slog.Attr) []slog.Attr {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return attrs
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			attrs = appendAttr(attrs, prefix, ga)
+		}
+		return attrs
+	}
+	return append(attrs, slog.Attr{Key: prefix + a.Key, Value: a.Value})
+}
+
+// 与文本格式相同的一行：消息后跟 key=value，值中有空格或引号时加引号
+func formatLogLine(msg string, attrs []slog.Attr) string {
+	var b strings.Builder
+	b.WriteString(msg)
+	for _, a := range attrs {
+		v := a.Value.String()
+		if v == "" || strings.ContainsAny(v, " \t\r\n\"=") {
+			v = strconv.Quote(v)
+		}
+		fmt.Fprintf(&b, " %s=%s", a.Key, v)
+	}
+	return b.String()
+}
@@ -1,136 +1,568 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"flag"
 	"fmt"
-	"io"
-	"io/ioutil"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"strconv"
 	"strings"
-
-	"github.com/aliyun/alibaba-cloud-sdk-go/services/alidns"
+	"time"
 )
 
-// 配置结构体
-type Config struct {
-	AccessKeyID     string `json:"AccessKeyID"`
-	AccessKeySecret string `json:"AccessKeySecret"`
-	DomainName      string `json:"DomainName"`
-	Record          string `json:"Record"`
-	RecordType      string `json:"RecordType"`
-}
-
-// 错误处理辅助函数
+// 错误处理辅助函数，出错时记录日志并按错误类型退出，见 exitcode.go
 func handleError(err error, message string) {
 	if err != nil {
-		log.Fatalf("%s: %v", message, err)
+		slog.Error(message, "error", err)
+		os.Exit(exitCode(err))
 	}
 }
 
-// 获取本地外网 IP 地址
-func getExternalIP() (string, error) {
-	resp, err := http.Get("http://icanhazip.com")
-	if err != nil {
-		return "", fmt.Errorf("failed to get external IP: %w", err)
-	}
-	defer resp.Body.Close()
+// 更新器，保存检测和更新所需的配置、IP 来源和状态
+type updater struct {
+	config     Config
+	sources    map[int][]ipSource
+	transports map[int]*http.Transport // 按地址族查询外网 IP 使用的 Transport，多次检查共用
+	filter     *ipFilter
+	state      *State
+	notifiers  []notifier
+	pushers    []metricsPusher
 
-	var ip bytes.Buffer
-	if _, err := io.Copy(&ip, resp.Body); err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
-	}
+	lastStatus *Status // 上次检查的结果
+	daemon     bool    // 常驻运行，没有 StateFile 时失败次数也在内存中累计
 
-	return ip.String(), nil
+	// 配置中的记录键到查找所属域名后的键（未配置域名的记录两者不同），重新加载配置时用来找出删除的记录
+	recordKeys map[string]string
 }
 
-// 读取配置文件
-func loadConfig(filename string) (Config, error) {
-	var config Config
-	data, err := ioutil.ReadFile(filename)
+// 根据配置创建更新器
+func newUpdater(config Config) (*updater, error) {
+	u := &updater{config: config, sources: make(map[int][]ipSource), recordKeys: make(map[string]string)}
+	var err error
+	if u.filter, err = newIPFilter(config.AllowCIDRs, config.DenyCIDRs); err != nil {
+		return nil, fmt.Errorf("error loading IP filter: %w", err)
+	}
+	// 查询外网 IP 和服务商接口各用一个 Transport，常驻模式下多次检查共用，可以复用连接
+	ipTransport, err := config.newTransport(config.IPProxy)
 	if err != nil {
-		return config, fmt.Errorf("failed to read config file: %w", err)
+		return nil, fmt.Errorf("error loading network settings: %w", err)
 	}
-	if err := json.Unmarshal(data, &config); err != nil {
-		return config, fmt.Errorf("failed to unmarshal config: %w", err)
+	u.transports = map[int]*http.Transport{4: familyTransport(ipTransport, 4), 6: familyTransport(ipTransport, 6)}
+	if u.config.apiTransport, err = config.newTransport(config.APIProxy); err != nil {
+		return nil, fmt.Errorf("error loading network settings: %w", err)
 	}
-	return config, nil
+	if u.sources[4], err = newIPSources(config.IPSources, 4, u.transports[4]); err != nil {
+		return nil, fmt.Errorf("error loading IP sources: %w", err)
+	}
+	if u.sources[6], err = newIPSources(config.IPv6Sources, 6, u.transports[6]); err != nil {
+		return nil, fmt.Errorf("error loading IPv6 sources: %w", err)
+	}
+	if u.state, err = loadState(config.StateFile); err != nil {
+		return nil, fmt.Errorf("error loading state: %w", err)
+	}
+	if u.notifiers, err = newNotifiers(config); err != nil {
+		return nil, fmt.Errorf("error loading notifications: %w", err)
+	}
+	if u.pushers, err = newMetricsPushers(config); err != nil {
+		return nil, fmt.Errorf("error loading metrics export: %w", err)
+	}
+	return u, nil
 }
 
-// 更新 DNS 记录
-func updateDNSRecord(client *alidns.Client, config Config, newIP string) (string, error) {
-	// 查询当前的 DNS 记录
-	describeRequest := alidns.CreateDescribeDomainRecordsRequest()
-	describeRequest.DomainName = config.DomainName
-	describeResponse, err := client.DescribeDomainRecords(describeRequest)
+// 常驻模式的检查间隔，命令行参数优先于配置文件
+func daemonInterval(config Config, flagInterval time.Duration) (time.Duration, error) {
+	if flagInterval != 0 || config.Interval == "" {
+		return flagInterval, nil
+	}
+	d, err := time.ParseDuration(config.Interval)
 	if err != nil {
-		return "", fmt.Errorf("failed to describe domain records: %w", err)
+		return 0, fmt.Errorf("invalid Interval: %w", err)
 	}
+	return d, nil
+}
+
+// 执行一次检测和更新，每条记录独立比较和更新，互不影响
+func (u *updater) runOnce() (err error) {
+	start := time.Now()
+	var result checkResult
+	defer func() {
+		result.duration = time.Since(start)
+		result.failed = err != nil
+		metrics.observeCheck(result.duration, result.failed)
+		health.observeCheck(err)
+		pushMetrics(u.pushers, result)
+	}()
 
-	var recordID, currentIP string
-	for _, r := range describeResponse.DomainRecords.Record {
-		if r.RR == config.Record && r.Type == config.RecordType {
-			recordID = r.RecordId
-			currentIP = r.Value // 获取当前记录的 IP
-			break
+	// STS 临时凭据从文件读取时每次重新读取，使用轮换后的凭据
+	if u.config.SecurityTokenFile != "" {
+		if err := u.config.reloadSecretFiles(); err != nil {
+			return withExitCode(exitConfig, err)
 		}
 	}
 
-	if recordID == "" {
-		return "", fmt.Errorf("record %s not found in domain %s", config.Record, config.DomainName)
+	// 整次检查超过 RunTimeout 后取消所有未完成的请求，避免网络异常时卡住常驻循环
+	ctx, cancel := u.config.runContext()
+	defer cancel()
+
+	// 每种地址族（以及每组记录单独配置的来源）每次只检测一次，共用同一组来源的记录共用结果
+	ips := make(map[string]string)
+	detectErrs := make(map[string]error)
+	status := &Status{}
+	detect := func(configs []SourceConfig, family int) (string, error) {
+		key := fmt.Sprintf("%d %v", family, configs)
+		if _, done := ips[key]; !done {
+			ips[key], detectErrs[key] = u.detect(ctx, configs, family)
+			if len(configs) == 0 && family == 4 {
+				status.IP = ips[key]
+			}
+			if len(configs) == 0 && family == 6 {
+				status.IPv6 = ips[key]
+			}
+		}
+		return ips[key], detectErrs[key]
 	}
+	providers := make(map[string]provider)
+
+	// 按服务商收集更新任务，同一服务商的多条记录可以批量更新
+	jobs := make(map[string][]*updateJob)
+	var providerNames []string
 
-	// 检查当前 IP 和新 IP 是否相同
-	if currentIP == newIP {
-		fmt.Printf("IP address is already up to date: %s\n", currentIP) // 打印当前 IP
-		return currentIP, nil                                           // 返回当前 IP 地址，无需更新
+	// 出错的记录同时写入状态输出文件，退出码按第一条出错的记录决定
+	var errs []string
+	failCode := exitOK
+	fail := func(rec RecordConfig, code int, msg string) {
+		errs = append(errs, fmt.Sprintf("%s: %s", rec, msg))
+		if rs := u.state.Records[rec.key()]; rs != nil && rs.Pending != nil {
+			rs.Pending.failed(msg)
+		}
+		status.Records = append(status.Records, newRecordStatus(rec, u.state.Records[rec.key()], msg))
+		if failCode == exitOK {
+			failCode = code
+		}
 	}
+	for _, rec := range u.config.records() {
+		configKey := rec.key()
+		rec, err := u.prepare(ctx, providers, rec)
+		if err == nil {
+			u.recordKeys[configKey] = rec.key()
+		}
+		if err != nil {
+			fail(rec, exitCode(err), err.Error())
+			continue
+		}
 
-	// 更新 DNS 记录
-	updateRequest := alidns.CreateUpdateDomainRecordRequest()
-	updateRequest.RecordId = recordID
-	updateRequest.RR = config.Record
-	updateRequest.Type = config.RecordType
-	updateRequest.Value = newIP
+		rs := u.state.record(rec.key())
+		if rs.Disabled {
+			slog.Info("Skipping disabled record", "record", rec)
+			status.Records = append(status.Records, newRecordStatus(rec, rs, ""))
+			continue
+		}
 
-	// 尝试更新 DNS 记录，并处理可能的错误
-	_, err = client.UpdateDomainRecord(updateRequest)
-	if err != nil {
-		// 未知类型错误处理，用错误信息的字符串进行匹配
-		if strings.Contains(err.Error(), "DomainRecordDuplicate") {
-			fmt.Printf("The DNS record already exists with the same value: %s\n", newIP)
-			return currentIP, nil // 返回当前 IP 地址，因为记录已经存在
+		// 地址类记录使用检测到的地址，其余类型使用配置的值
+		newValue := rec.Value
+		var weighted []weightedValue
+		if isAddressType(rec.RecordType) {
+			family := familyOf(rec.RecordType)
+			var err error
+			if len(rec.Weights) == 0 {
+				newValue, err = detect(rec.IPSources, family)
+			} else {
+				// 加权解析：每个出口各自检测地址
+				values := make([]string, len(rec.Weights))
+				for i, w := range rec.Weights {
+					if values[i], err = detect(w.IPSources, family); err != nil {
+						break
+					}
+					weighted = append(weighted, weightedValue{value: values[i], weight: w.Weight})
+				}
+				newValue = joinWeightedValues(values)
+			}
+			if err != nil {
+				fail(rec, exitDetect, fmt.Sprintf("failed to get external IP: %v", err))
+				continue
+			}
+		} else if len(rec.Weights) > 0 {
+			fail(rec, exitConfig, "Weights only apply to A and AAAA records")
+			continue
+		}
+		if err := validateJob(rec, newValue, weighted); err != nil {
+			fail(rec, exitConfig, err.Error())
+			continue
 		}
-		return "", fmt.Errorf("failed to update domain record: %w", err)
+
+		if _, ok := jobs[rec.Provider]; !ok {
+			providerNames = append(providerNames, rec.Provider)
+		}
+		jobs[rec.Provider] = append(jobs[rec.Provider], &updateJob{
+			rec:      rec,
+			newValue: newValue,
+			oldValue: rs.IP,
+			weighted: weighted,
+			ids:      recordIDs{zoneID: rs.ZoneID, recordID: rs.RecordID},
+			proxied:  rs.Proxied,
+		})
+	}
+
+	// 调用更新函数
+	var changed []*updateJob
+	for _, name := range providerNames {
+		runJobs(providers[name], u.runPreUpdate(jobs[name]))
+
+		for _, j := range jobs[name] {
+			u.runPostUpdate(j)
+			if j.err != nil {
+				metrics.observeAPIError(name)
+				result.apiErrors++
+				code := exitCode(j.err)
+				if code == exitError {
+					code = exitUpdate
+				}
+				// 地址变化了却没能发布时记下新值，之后按计划重试；地址又变回已发布的值时不再需要重试
+				if rs := u.state.record(j.rec.key()); j.newValue != rs.IP {
+					rs.markPending(j.newValue)
+				} else {
+					rs.Pending = nil
+				}
+				fail(j.rec, code, fmt.Sprintf("failed to update DNS record: %v", j.err))
+				continue
+			}
+
+			rs := u.state.record(j.rec.key())
+			if rs.Pending != nil {
+				slog.Info("Pending update applied", "record", j.rec, "value", j.newValue, "since", rs.Pending.Since, "attempts", rs.Pending.Attempts)
+				rs.Pending = nil
+			}
+			if j.current != j.newValue {
+				rs.Updated = time.Now()
+				u.state.LastChange = rs.Updated
+				changed = append(changed, j)
+			}
+			rs.IP = j.newValue
+			metrics.observeRecord(j.rec, j.newValue, j.current != j.newValue, rs.Updated)
+			rstatus := newRecordStatus(j.rec, rs, "")
+			rstatus.changed, rstatus.previous = j.current != j.newValue, j.current
+			status.Records = append(status.Records, rstatus)
+			if cp, ok := providers[name].(idCachingProvider); ok {
+				ids := cp.cachedIDs(j.rec)
+				rs.ZoneID, rs.RecordID = ids.zoneID, ids.recordID
+			}
+			if pp, ok := providers[name].(proxyingProvider); ok {
+				j.proxied = pp.isProxied(j.rec)
+				rs.Proxied = j.proxied
+			}
+
+			slog.Info("Current IP", "record", j.rec, "value", j.current)
+		}
+	}
+
+	result.updates = len(changed)
+
+	// 检查修改过的记录是否已经生效
+	if u.config.Verify != nil && len(changed) > 0 {
+		if verifyErrs := u.verify(ctx, changed); len(verifyErrs) > 0 {
+			errs = append(errs, verifyErrs...)
+			if failCode == exitOK {
+				failCode = exitUpdate
+			}
+		}
+	}
+	if len(changed) > 0 {
+		sendNotifications(u.notifiers, changeEvent(changed))
+	}
+
+	// 用到多个服务商时按服务商汇总每条记录的结果
+	if len(providerNames) > 1 {
+		printSummary(providerNames, jobs)
+	}
+
+	// 记录连续失败的次数，成功后重新计数；每个渠道按自己的策略决定是否通知
+	if len(errs) > 0 {
+		u.state.Failures++
+		sendNotifications(u.notifiers, notifyEvent{Error: strings.Join(errs, "; "), Failures: u.state.Failures,
+			untracked: u.config.StateFile == "" && !u.daemon})
+	} else {
+		u.state.Failures = 0
+		u.state.LastSuccess = time.Now()
+		if len(changed) == 0 {
+			sendNotifications(u.notifiers, notifyEvent{})
+		}
+	}
+
+	if err := u.state.save(u.config.StateFile); err != nil {
+		errs = append(errs, err.Error())
+	}
+	var attempted []*updateJob
+	for _, name := range providerNames {
+		attempted = append(attempted, jobs[name]...)
+	}
+	if err := appendHistory(u.config.HistoryFile, attempted); err != nil {
+		errs = append(errs, err.Error())
 	}
 
-	return currentIP, nil
+	status.Updated = time.Now()
+	status.OK = len(errs) == 0
+	status.LastError = strings.Join(errs, "; ")
+	status.Failures = u.state.Failures
+	if !u.state.LastSuccess.IsZero() {
+		status.LastSuccess = &u.state.LastSuccess
+	}
+	if !u.state.LastChange.IsZero() {
+		status.LastChange = &u.state.LastChange
+	}
+	metrics.observeFreshness(u.state.LastSuccess, u.state.LastChange, u.config.staleAfter())
+	if err := status.save(u.config.StatusFile, u.config.staleAfter()); err != nil {
+		errs = append(errs, err.Error())
+	}
+	u.lastStatus = status
+	if len(errs) > 0 {
+		if failCode == exitOK {
+			failCode = exitError
+		}
+		return withExitCode(failCode, fmt.Errorf("%s", strings.Join(errs, "; ")))
+	}
+	return nil
+}
+
+// 调用接口前检查要发布的值与记录类型是否匹配，加权解析逐个检查每个地址
+func validateJob(rec RecordConfig, value string, weighted []weightedValue) error {
+	if len(weighted) == 0 {
+		return validateRecordValue(rec, value)
+	}
+	for _, w := range weighted {
+		if err := validateRecordValue(rec, w.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 获取记录的服务商（同名服务商只创建一次），未配置域名时根据完整记录名查找所属的域名
+func (u *updater) prepare(ctx context.Context, providers map[string]provider, rec RecordConfig) (RecordConfig, error) {
+	p, ok := providers[rec.Provider]
+	if !ok {
+		var err error
+		if p, err = newProvider(ctx, u.config, rec.Provider); err != nil {
+			return rec, err
+		}
+		providers[rec.Provider] = p
+	}
+	return resolveZone(p, rec)
+}
+
+// 检测要发布的地址。记录单独配置了 IP 来源时使用自己的来源，不经过全局的地址过滤，
+// 否则使用全局来源，按 CIDR 白名单/黑名单过滤
+func (u *updater) detect(ctx context.Context, configs []SourceConfig, family int) (string, error) {
+	sources, filter := u.sources[family], u.filter
+	if len(configs) > 0 {
+		var err error
+		if sources, err = newIPSources(configs, family, u.transports[family]); err != nil {
+			return "", err
+		}
+		filter = &ipFilter{}
+	}
+
+	// 所有来源都遇到临时错误（例如网络短暂中断）时按 Retry 配置重试
+	var ip string
+	err := u.config.retryPolicy().do(ctx, fmt.Sprintf("IPv%d detection", family), func() error {
+		var err error
+		ip, err = detectIP(ctx, sources, family, filter, u.config.requestTimeout())
+		return err
+	})
+	return ip, err
+}
+
+// 记录每个服务商的更新结果
+func printSummary(providerNames []string, jobs map[string][]*updateJob) {
+	for _, name := range providerNames {
+		for _, j := range jobs[name] {
+			if j.err != nil {
+				slog.Error("Summary: failed", "record", j.rec, "error", j.err)
+			} else {
+				slog.Info("Summary: ok", "record", j.rec, "value", j.newValue)
+			}
+		}
+	}
 }
 
 func main() {
 	// 定义命令行参数
 	configPath := flag.String("c", "config.json", "Path to the config file")
+	interval := flag.Duration("i", 0, "Run as a daemon and check every interval (e.g. 5m)")
+	txtName := flag.String("txt", "", "Set a TXT record with this name instead of updating addresses (e.g. _acme-challenge)")
+	txtValue := flag.String("value", "", "TXT record value, read from stdin when empty")
+	txtClear := flag.Bool("clear", false, "Delete the TXT record given by -txt (only the one matching -value when set)")
+	ageKey := flag.String("age-key", os.Getenv(envPrefix+"AGE_KEY_FILE"), "age identity file for decrypting an encrypted config (passphrase is prompted when empty)")
+	profile := flag.String("profile", os.Getenv(envPrefix+"PROFILE"), "Name of the profile to use from the config's Profiles")
+	quietDefault, _ := strconv.ParseBool(os.Getenv(envPrefix + "QUIET"))
+	quiet := flag.Bool("quiet", quietDefault, "Print nothing when no record changed and no error occurred (for cron)")
+	detailedExitCode := flag.Bool("detailed-exit-code", false, "Exit with 10 instead of 0 when an update run changed no record")
+	output := flag.String("o", formatTable, "Output format of list, status, history and version: table, json or yaml")
+	lang := flag.String("lang", os.Getenv(envPrefix+"LANG"), "Interface language: auto, en or zh (default auto, detected from LANG)")
+	logOpts := registerLogFlags()
+	overrides := registerOverrideFlags()
+	flag.Usage = usage
 	flag.Parse()
+	// 命令名之后的全局参数也要在初始化日志之前解析
+	command, args, err := parseCommand()
+	langErr := setLanguage(*lang)
+	handleError(withExitCode(exitConfig, setupLogging(logOpts)), "Invalid logging options")
+	handleError(withExitCode(exitConfig, langErr), "Invalid arguments")
+	handleError(withExitCode(exitConfig, err), "Invalid arguments")
+	handleError(withExitCode(exitConfig, checkOutputFormat(*output)), "Invalid arguments")
+
+	if command == "version" {
+		handleError(runVersion(*output), "Failed to print version")
+		return
+	}
 
-	// 读取配置文件
-	config, err := loadConfig(*configPath)
-	handleError(err, "Error loading config")
+	// 读取配置文件，没有用 -c 指定时配置文件可以不存在，只使用环境变量
+	configSet := false
+	flag.Visit(func(f *flag.Flag) {
+		configSet = configSet || f.Name == "c"
+	})
+	opts := loadOptions{optional: !configSet, age: newAgeKeys(*ageKey), profile: *profile}
 
-	// 获取本地外网 IP 地址
-	newIP, err := getExternalIP()
-	handleError(err, "Error getting external IP")
+	// 检查或迁移配置文件：aliddns -c config.json config validate|migrate；生成配置文件：aliddns -c config.json init
+	switch command {
+	case "config":
+		handleError(withExitCode(exitConfig, runConfigCommand(args, *configPath, opts, overrides)), "Config check failed")
+		return
+	case "init":
+		handleError(runInit(*configPath), "Failed to create config")
+		return
+	case "doctor":
+		handleError(runDoctor(*configPath, opts, overrides, *output), "Some checks failed")
+		return
+	}
 
-	// 创建阿里云 DNS 客户端
-	client, err := alidns.NewClientWithAccessKey("cn-hangzhou", config.AccessKeyID, config.AccessKeySecret)
-	handleError(err, "Failed to create client")
+	config, err := loadConfig(*configPath, opts)
+	handleError(withExitCode(exitConfig, err), "Error loading config")
+	handleError(withExitCode(exitConfig, config.applyFlags(overrides)), "Invalid command-line arguments")
 
-	fmt.Printf("New IP to update: %s\n", newIP) // 打印新 IP
+	// 不使用配置文件时（例如 CI 中临时更新一条记录），记录和凭据全部来自命令行参数或环境变量
+	switch command {
+	case "", "update", "daemon", "verify":
+		if _, err := os.Stat(*configPath); !configSet && os.IsNotExist(err) {
+			handleError(withExitCode(exitConfig, config.requireRecords(*configPath)), "Nothing to update")
+		}
+	}
 
-	// 调用更新函数
-	currentIP, err := updateDNSRecord(client, config, newIP)
-	handleError(err, "Failed to update DNS record")
+	// 查看记录、更新历史、变化频率和运行状态：aliddns -c config.json list|history|stats|status
+	switch command {
+	case "list":
+		handleError(runList(config, args, *output), "Failed to list records")
+		return
+	case "history":
+		handleError(runHistory(config, args, *output), "Failed to show history")
+		return
+	case "stats":
+		handleError(runStats(config, args), "Failed to show statistics")
+		return
+	case "status":
+		os.Exit(runStatus(config, *output))
+	case "ip", "verify":
+		u, err := newUpdater(config)
+		handleError(withExitCode(exitConfig, err), "Initialization failed")
+		setOutboundTransport(u.config.apiTransport)
+		if command == "ip" {
+			handleError(runIP(u, args, *output), "Failed to detect IP address")
+		} else {
+			handleError(runVerify(u, args, *output), "DNS does not match the detected IP")
+		}
+		return
+	}
+
+	// 更新前先检查 Cloudflare API Token，Token 无效或权限不足时直接退出
+	if config.CFAPIToken != "" {
+		handleError(verifyCloudflareToken(context.Background(), config), "Cloudflare API token check failed")
+	}
+
+	if *txtName != "" {
+		handleError(runTXT(config, *txtName, *txtValue, *txtClear), "Failed to update TXT record")
+		return
+	}
+
+	u, err := newUpdater(config)
+	handleError(withExitCode(exitConfig, err), "Initialization failed")
+	setOutboundTransport(u.config.apiTransport)
+
+	// 启用或停用记录：aliddns -c config.json disable [主机记录...]
+	if command == "enable" || command == "disable" {
+		handleError(u.setEnabled(args, command == "enable"), "Failed to "+command+" records")
+		return
+	}
+	if command == "delete" {
+		handleError(runDelete(u, args), "Failed to delete records")
+		return
+	}
+	if command == "add" {
+		handleError(runAdd(u, args), "Failed to add record")
+		return
+	}
+
+	// 没有命令时按检查间隔决定常驻运行还是只运行一次；update 总是只运行一次，daemon 和 tui 必须有检查间隔
+	d, err := daemonInterval(config, *interval)
+	handleError(withExitCode(exitConfig, err), "Invalid interval")
+	switch {
+	case command == "update":
+		d = 0
+	case (command == "daemon" || command == "tui") && d <= 0:
+		handleError(withExitCode(exitConfig, fmt.Errorf("%s requires -i or Interval in the config", command)), "Invalid interval")
+	}
+
+	if d > 0 {
+		// 配置文件修改后重新加载，检查通过后才替换正在使用的配置。
+		// 重新加载时复用启动时输入的口令，常驻循环中不会等待终端输入
+		opts.age.noPrompt = true
+		reload := func() (*updater, time.Duration, error) {
+			config, err := loadConfig(*configPath, opts)
+			if err != nil {
+				return nil, 0, err
+			}
+			if err := config.applyFlags(overrides); err != nil {
+				return nil, 0, err
+			}
+			if problems := config.validate(); len(problems) > 0 {
+				return nil, 0, fmt.Errorf("%s", strings.Join(problems, "; "))
+			}
+			if config.CFAPIToken != "" {
+				if err := verifyCloudflareToken(context.Background(), config); err != nil {
+					return nil, 0, err
+				}
+			}
+			d, err := daemonInterval(config, *interval)
+			if err != nil {
+				return nil, 0, err
+			}
+			if d <= 0 {
+				return nil, 0, fmt.Errorf("Interval is required in daemon mode")
+			}
+			u, err := newUpdater(config)
+			return u, d, err
+		}
+		// 监听地址只在启动时读取，修改后需要重启
+		if config.Listen != "" {
+			handleError(startHTTPServer(config.Listen), "Failed to start HTTP server")
+		}
+		if command == "tui" {
+			handleError(runTUI(u, d, *configPath, reload, logOpts), "Dashboard failed")
+			return
+		}
+		runDaemon(u, d, *configPath, reload, daemonHooks{})
+		return
+	}
 
-	fmt.Printf("Current IP: %s\n", currentIP) // 打印当前 IP
+	// 输出每条记录的结果时，普通日志只保留警告和错误，-log-level 指定的级别优先
+	printer := newResultPrinter(*quiet)
+	if printer.enabled() && logOpts.level == "" {
+		logLevel.Set(slog.LevelWarn)
+	}
+	err = u.runOnce()
+	printer.print(u.lastStatus)
+	handleError(err, "Update failed")
+	if *detailedExitCode && !u.lastStatus.anyChanged() {
+		os.Exit(exitUnchanged)
+	}
 }
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// 发送到 Matrix 房间，URL 为 homeserver 地址，Token 为账号的 access token。
+// Room 可以是房间 ID（!abc:example.org）或别名（#ddns:example.org）
+type matrixNotifier struct {
+	homeserver string
+	room       string
+	token      string
+}
+
+func newMatrixNotifier(c NotifyConfig) (*matrixNotifier, error) {
+	if c.URL == "" || c.Room == "" || c.Token == "" {
+		return nil, fmt.Errorf("matrix notification requires URL, Room and Token")
+	}
+	return &matrixNotifier{homeserver: strings.TrimSuffix(c.URL, "/"), room: c.Room, token: c.Token}, nil
+}
+
+func (n *matrixNotifier) notify(e notifyEvent) error {
+	// 别名先解析为房间 ID，之后直接使用
+	if strings.HasPrefix(n.room, "#") {
+		var result struct {
+			RoomID string `json:"room_id"`
+		}
+		if err := n.request("GET", "/directory/room/"+url.PathEscape(n.room), nil, &result); err != nil {
+			return fmt.Errorf("failed to resolve room %s: %w", n.room, err)
+		}
+		n.room = result.RoomID
+	}
+
+	title, text := notifyMessage(e)
+	msg := map[string]string{"msgtype": "m.text", "body": title + "\n" + text}
+	// 事务 ID 用于服务器去重，每条消息不同
+	txnID := fmt.Sprintf("aliddns-%d", time.Now().UnixNano())
+	return n.request("PUT", "/rooms/"+url.PathEscape(n.room)+"/send/m.room.message/"+txnID, msg, nil)
+}
+
+// 调用 Client-Server API，返回错误时带上 Matrix 的错误码
+func (n *matrixNotifier) request(method, path string, body, result interface{}) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, n.homeserver+"/_matrix/client/v3"+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+n.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := outboundClient()
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var matrixErr struct {
+			ErrCode string `json:"errcode"`
+			Error   string `json:"error"`
+		}
+		if json.Unmarshal(data, &matrixErr) == nil && matrixErr.ErrCode != "" {
+			return fmt.Errorf("status %d: %s: %s", resp.StatusCode, matrixErr.ErrCode, matrixErr.Error)
+		}
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if result != nil {
+		return json.Unmarshal(data, result)
+	}
+	return nil
+}
+
+func (n *matrixNotifier) String() string {
+	return "matrix " + n.room
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 检查耗时直方图的分桶（秒）
+var checkDurationBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// 计算耗时分位数时保留的最近样本数
+const latencySamples = 100
+
+// 一条记录的标签
+type metricRecord struct {
+	provider string
+	record   string // 完整域名
+	typ      string
+}
+
+// 运行指标，常驻模式下通过 /metrics 以 Prometheus 文本格式输出。
+// 配置重新加载后更新器会重建，所以指标放在全局变量中
+type metricsRegistry struct {
+	mu sync.Mutex
+
+	checks        int
+	checkFailures int
+	updates       map[metricRecord]int
+	apiErrors     map[string]int // 按服务商统计
+	lastChange    map[metricRecord]time.Time
+	values        map[metricRecord]string // 记录当前的值
+
+	durationBuckets []int // 与 checkDurationBuckets 对应，累计计数
+	durationSum     float64
+
+	// 上次成功的检查和上次修改记录的时间，超过 staleAfter 没有成功时 aliddns_stale 为 1
+	lastSuccess time.Time
+	lastUpdate  time.Time
+	staleAfter  time.Duration
+
+	// 每个 IP 来源和每个服务商接口的耗时
+	sourceLatency   map[string]*latencyStats
+	providerLatency map[string]*latencyStats
+}
+
+// 耗时统计，分位数按最近的 latencySamples 个样本计算，次数和总和为累计值
+type latencyStats struct {
+	samples []time.Duration // 环形缓冲
+	next    int
+	count   int
+	errors  int
+	sum     time.Duration
+}
+
+func (s *latencyStats) add(d time.Duration, failed bool) {
+	if len(s.samples) < latencySamples {
+		s.samples = append(s.samples, d)
+	} else {
+		s.samples[s.next] = d
+		s.next = (s.next + 1) % latencySamples
+	}
+	s.count++
+	s.sum += d
+	if failed {
+		s.errors++
+	}
+}
+
+// 最近样本的分位数，q 为 0 时是最小值，1 时是最大值
+func (s *latencyStats) quantile(q float64) time.Duration {
+	sorted := append([]time.Duration(nil), s.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[int(q*float64(len(sorted)-1)+0.5)]
+}
+
+var metrics = &metricsRegistry{
+	updates:         make(map[metricRecord]int),
+	apiErrors:       make(map[string]int),
+	lastChange:      make(map[metricRecord]time.Time),
+	values:          make(map[metricRecord]string),
+	durationBuckets: make([]int, len(checkDurationBuckets)),
+	sourceLatency:   make(map[string]*latencyStats),
+	providerLatency: make(map[string]*latencyStats),
+}
+
+func metricRecordOf(rec RecordConfig) metricRecord {
+	return metricRecord{provider: rec.Provider, record: rec.fqdn(), typ: rec.RecordType}
+}
+
+// 记录一次检查的耗时和结果
+func (m *metricsRegistry) observeCheck(d time.Duration, failed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checks++
+	if failed {
+		m.checkFailures++
+	}
+	seconds := d.Seconds()
+	m.durationSum += seconds
+	for i, le := range checkDurationBuckets {
+		if seconds <= le {
+			m.durationBuckets[i]++
+		}
+	}
+}
+
+// 记录更新成功后的当前值，changed 表示这次修改了记录。updated 为上次修改的时间（来自状态文件）
+func (m *metricsRegistry) observeRecord(rec RecordConfig, value string, changed bool, updated time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := metricRecordOf(rec)
+	if changed {
+		m.updates[key]++
+	}
+	if !updated.IsZero() {
+		m.lastChange[key] = updated
+	}
+	m.values[key] = value
+}
+
+// 记录上次成功和上次修改的时间（来自状态，跨多次运行保留）
+func (m *metricsRegistry) observeFreshness(lastSuccess, lastUpdate time.Time, staleAfter time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastSuccess, m.lastUpdate, m.staleAfter = lastSuccess, lastUpdate, staleAfter
+}
+
+// 记录一次 IP 检测的耗时，source 为来源的名称
+func (m *metricsRegistry) observeSourceLatency(source string, d time.Duration, failed bool) {
+	m.observeLatency(m.sourceLatency, source, d, failed)
+}
+
+// 记录一次服务商接口请求的耗时
+func (m *metricsRegistry) observeProviderLatency(provider string, d time.Duration, failed bool) {
+	m.observeLatency(m.providerLatency, provider, d, failed)
+}
+
+func (m *metricsRegistry) observeLatency(stats map[string]*latencyStats, name string, d time.Duration, failed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := stats[name]
+	if !ok {
+		s = &latencyStats{}
+		stats[name] = s
+	}
+	s.add(d, failed)
+}
+
+// 记录服务商接口调用失败
+func (m *metricsRegistry) observeAPIError(provider string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.apiErrors[provider]++
+}
+
+// /metrics 的处理函数
+func (m *metricsRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.write(w)
+}
+
+// 以 Prometheus 文本格式输出所有指标
+func (m *metricsRegistry) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	header := func(name, typ, help string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	}
+
+	header("aliddns_checks_total", "counter", "Number of update checks performed.")
+	fmt.Fprintf(w, "aliddns_checks_total %d\n", m.checks)
+	header("aliddns_check_failures_total", "counter", "Number of update checks that had at least one error.")
+	fmt.Fprintf(w, "aliddns_check_failures_total %d\n", m.checkFailures)
+
+	header("aliddns_updates_total", "counter", "Number of times a DNS record was changed.")
+	for _, key := range sortedMetricRecords(m.updates) {
+		fmt.Fprintf(w, "aliddns_updates_total%s %d\n", key.labels(), m.updates[key])
+	}
+
+	header("aliddns_api_errors_total", "counter", "Number of failed record updates by DNS provider.")
+	providers := make([]string, 0, len(m.apiErrors))
+	for p := range m.apiErrors {
+		providers = append(providers, p)
+	}
+	sort.Strings(providers)
+	for _, p := range providers {
+		fmt.Fprintf(w, "aliddns_api_errors_total{provider=%s} %d\n", quoteLabel(p), m.apiErrors[p])
+	}
+
+	header("aliddns_last_change_timestamp_seconds", "gauge", "Unix time of the last change of a DNS record.")
+	for _, key := range sortedMetricRecords(m.lastChange) {
+		fmt.Fprintf(w, "aliddns_last_change_timestamp_seconds%s %d\n", key.labels(), m.lastChange[key].Unix())
+	}
+
+	if !m.lastSuccess.IsZero() {
+		header("aliddns_last_success_timestamp_seconds", "gauge", "Unix time of the last check without errors.")
+		fmt.Fprintf(w, "aliddns_last_success_timestamp_seconds %d\n", m.lastSuccess.Unix())
+	}
+	if !m.lastUpdate.IsZero() {
+		header("aliddns_last_update_timestamp_seconds", "gauge", "Unix time of the last change of any DNS record.")
+		fmt.Fprintf(w, "aliddns_last_update_timestamp_seconds %d\n", m.lastUpdate.Unix())
+	}
+	if m.staleAfter > 0 {
+		stale := 0
+		if m.lastSuccess.IsZero() || time.Since(m.lastSuccess) > m.staleAfter {
+			stale = 1
+		}
+		header("aliddns_stale", "gauge", "1 if there was no successful check within StaleAfter.")
+		fmt.Fprintf(w, "aliddns_stale %d\n", stale)
+	}
+
+	header("aliddns_record_info", "gauge", "Current value of a DNS record.")
+	for _, key := range sortedMetricRecords(m.values) {
+		labels := key.labels()
+		fmt.Fprintf(w, "aliddns_record_info%s,value=%s} 1\n", labels[:len(labels)-1], quoteLabel(m.values[key]))
+	}
+
+	writeLatency(w, "aliddns_source_latency_seconds", "source", "Latency of IP sources (quantile 0 is the minimum), over the last 100 requests.", m.sourceLatency)
+	writeLatency(w, "aliddns_provider_latency_seconds", "provider", "Latency of DNS provider API requests (quantile 0 is the minimum), over the last 100 requests.", m.providerLatency)
+
+	header("aliddns_check_duration_seconds", "histogram", "Time taken by an update check.")
+	for i, le := range checkDurationBuckets {
+		fmt.Fprintf(w, "aliddns_check_duration_seconds_bucket{le=\"%g\"} %d\n", le, m.durationBuckets[i])
+	}
+	fmt.Fprintf(w, "aliddns_check_duration_seconds_bucket{le=\"+Inf\"} %d\n", m.checks)
+	fmt.Fprintf(w, "aliddns_check_duration_seconds_sum %g\n", m.durationSum)
+	fmt.Fprintf(w, "aliddns_check_duration_seconds_count %d\n", m.checks)
+}
+
+// 以 summary 输出耗时：最小值、中位数、p95 和最大值，以及累计的总和、次数和失败次数
+func writeLatency(w io.Writer, name, label, help string, stats map[string]*latencyStats) {
+	if len(stats) == 0 {
+		return
+	}
+	names := make([]string, 0, len(stats))
+	for n := range stats {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s summary\n", name, help, name)
+	for _, n := range names {
+		s := stats[n]
+		l := label + "=" + quoteLabel(n)
+		for _, q := range []float64{0, 0.5, 0.95, 1} {
+			fmt.Fprintf(w, "%s{%s,quantile=\"%g\"} %g\n", name, l, q, s.quantile(q).Seconds())
+		}
+		fmt.Fprintf(w, "%s_sum{%s} %g\n", name, l, s.sum.Seconds())
+		fmt.Fprintf(w, "%s_count{%s} %d\n", name, l, s.count)
+	}
+	errName := strings.TrimSuffix(name, "_latency_seconds") + "_request_errors_total"
+	fmt.Fprintf(w, "# HELP %s Number of failed requests.\n# TYPE %s counter\n", errName, errName)
+	for _, n := range names {
+		fmt.Fprintf(w, "%s{%s=%s} %d\n", errName, label, quoteLabel(n), stats[n].errors)
+	}
+}
+
+// 记录服务商接口每个请求的耗时，由 newProviderTransport 放在审计日志外层
+type timedTransport struct {
+	base     http.RoundTripper
+	provider string
+}
+
+func (t *timedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	d := time.Since(start)
+	metrics.observeProviderLatency(t.provider, d, err != nil || resp.StatusCode >= 500)
+	slog.Debug("Provider API request", "provider", t.provider, "path", req.URL.Path, "latency", d)
+	return resp, err
+}
+
+func (k metricRecord) labels() string {
+	return fmt.Sprintf("{provider=%s,record=%s,type=%s}", quoteLabel(k.provider), quoteLabel(k.record), quoteLabel(k.typ))
+}
+
+// 标签值加引号，并转义反斜杠、引号和换行
+func quoteLabel(v string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v) + `"`
+}
+
+// 按服务商、域名和类型排序，输出顺序保持稳定
+func sortedMetricRecords[V any](m map[metricRecord]V) []metricRecord {
+	keys := make([]metricRecord, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.provider != b.provider {
+			return a.provider < b.provider
+		}
+		if a.record != b.record {
+			return a.record < b.record
+		}
+		return a.typ < b.typ
+	})
+	return keys
+}
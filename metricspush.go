@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 推送指标的配置，没有 Prometheus 时把每次检查的结果发送到 StatsD 或 InfluxDB
+type MetricsConfig struct {
+	Type    string `json:"Type"`    // statsd 或 influxdb
+	Address string `json:"Address"` // StatsD 的地址，例如 "127.0.0.1:8125"（UDP）
+	Prefix  string `json:"Prefix"`  // StatsD 指标名的前缀、InfluxDB 的 measurement，默认 aliddns
+
+	// InfluxDB 的写入地址，1.x 为 http://host:8086/write?db=ddns，2.x 为 http://host:8086/api/v2/write?org=home&bucket=ddns。
+	// 2.x 的 API Token 填在 Token 中，1.x 开启认证时填 Username/Password
+	URL      string `json:"URL"`
+	Token    string `json:"Token"`
+	Username string `json:"Username"`
+	Password string `json:"Password"`
+
+	// InfluxDB 的附加标签，例如 {"host": "router"}
+	Tags map[string]string `json:"Tags"`
+}
+
+// 一次检查的结果
+type checkResult struct {
+	duration  time.Duration
+	updates   int // 修改的记录数
+	apiErrors int // 更新失败的记录数
+	failed    bool
+}
+
+// 推送指标的目标
+type metricsPusher interface {
+	push(r checkResult) error
+	String() string
+}
+
+// 根据配置创建推送目标
+func newMetricsPushers(config Config) ([]metricsPusher, error) {
+	pushers := make([]metricsPusher, 0, len(config.Metrics))
+	for _, c := range config.Metrics {
+		if c.Prefix == "" {
+			c.Prefix = "aliddns"
+		}
+		switch strings.ToLower(c.Type) {
+		case "statsd":
+			if c.Address == "" {
+				return nil, fmt.Errorf("statsd metrics require Address, e.g. 127.0.0.1:8125")
+			}
+			if _, _, err := net.SplitHostPort(c.Address); err != nil {
+				return nil, fmt.Errorf("invalid statsd Address %q: %w", c.Address, err)
+			}
+			pushers = append(pushers, &statsdPusher{address: c.Address, prefix: c.Prefix})
+		case "influxdb", "influx":
+			if _, err := url.ParseRequestURI(c.URL); err != nil {
+				return nil, fmt.Errorf("influxdb metrics require a valid URL: %q", c.URL)
+			}
+			pushers = append(pushers, &influxPusher{config: c})
+		case "":
+			return nil, fmt.Errorf("metrics export requires Type")
+		default:
+			return nil, fmt.Errorf("unknown metrics type %q, use statsd or influxdb", c.Type)
+		}
+	}
+	return pushers, nil
+}
+
+// 推送到所有目标，失败只记录日志
+func pushMetrics(pushers []metricsPusher, r checkResult) {
+	for _, p := range pushers {
+		if err := p.push(r); err != nil {
+			slog.Warn("Failed to push metrics", "target", p.String(), "error", err)
+		}
+	}
+}
+
+// 通过 UDP 发送 StatsD 计数器和计时器，一次检查的所有指标放在同一个数据包中
+type statsdPusher struct {
+	address string
+	prefix  string
+}
+
+func (p *statsdPusher) push(r checkResult) error {
+	failures := 0
+	if r.failed {
+		failures = 1
+	}
+	lines := []string{
+		fmt.Sprintf("%s.checks:1|c", p.prefix),
+		fmt.Sprintf("%s.updates:%d|c", p.prefix, r.updates),
+		fmt.Sprintf("%s.failures:%d|c", p.prefix, failures),
+		fmt.Sprintf("%s.api_errors:%d|c", p.prefix, r.apiErrors),
+		fmt.Sprintf("%s.check_duration:%d|ms", p.prefix, r.duration.Milliseconds()),
+	}
+	conn, err := net.DialTimeout("udp", p.address, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(strings.Join(lines, "\n")))
+	return err
+}
+
+func (p *statsdPusher) String() string {
+	return "statsd " + p.address
+}
+
+// 以 InfluxDB 行协议写入一个点，每次检查一行
+type influxPusher struct {
+	config MetricsConfig
+}
+
+func (p *influxPusher) push(r checkResult) error {
+	c := p.config
+	failures := 0
+	if r.failed {
+		failures = 1
+	}
+	line := escapeInflux(c.Prefix, ", ")
+	keys := make([]string, 0, len(c.Tags))
+	for k := range c.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		line += "," + escapeInflux(k, ",= ") + "=" + escapeInflux(c.Tags[k], ",= ")
+	}
+	line += fmt.Sprintf(" checks=1i,updates=%di,failures=%di,api_errors=%di,duration_ms=%di %d\n",
+		r.updates, failures, r.apiErrors, r.duration.Milliseconds(), time.Now().UnixNano())
+
+	req, err := http.NewRequest("POST", c.URL, strings.NewReader(line))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Token "+c.Token)
+	} else if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+
+	client := outboundClient()
+	resp, err := client.Do(req)
+	if err != nil {
+		return hideURL(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func (p *influxPusher) String() string {
+	if u, err := url.Parse(p.config.URL); err == nil {
+		return "influxdb " + u.Host
+	}
+	return "influxdb"
+}
+
+// 行协议中 measurement、标签名和标签值里的特殊字符需要用反斜杠转义
+func escapeInflux(s, special string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(special, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
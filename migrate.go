@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// 旧版单条记录的字段：阿里云为 DomainName/Record/RecordType，Cloudflare 为 DOMAIN_NAME/RECORD_NAME
+var flatRecordKeys = []string{"DomainName", "Record", "RecordType", "DOMAIN_NAME", "RECORD_NAME"}
+
+// 迁移后的记录，字段按固定顺序输出
+type migratedRecord struct {
+	Provider   string `json:"Provider" yaml:"Provider" toml:"Provider"`
+	DomainName string `json:"DomainName,omitempty" yaml:"DomainName,omitempty" toml:"DomainName,omitempty"`
+	Record     string `json:"Record,omitempty" yaml:"Record,omitempty" toml:"Record,omitempty"`
+	RecordType string `json:"RecordType,omitempty" yaml:"RecordType,omitempty" toml:"RecordType,omitempty"`
+}
+
+// 把旧版的单条记录配置转换为 Records 列表：aliddns -c config.json config migrate [-w]。
+// 默认输出到标准输出，-w 时原文件备份为 .bak 后写回
+func runMigrate(filename string, opts loadOptions, args []string) error {
+	fs := flag.NewFlagSet("config migrate", flag.ExitOnError)
+	write := fs.Bool("w", false, "Write the result back to the config file (the original is kept as .bak)")
+	fs.Parse(args)
+
+	if *write {
+		if isRemoteConfig(filename) || strings.HasSuffix(filename, ".age") {
+			return fmt.Errorf("-w only works with a local, unencrypted config file")
+		}
+		if info, err := os.Stat(filename); err == nil && info.IsDir() {
+			return fmt.Errorf("-w does not work with a config directory, migrate each file instead")
+		}
+	}
+
+	data, err := readConfigJSON(filename, opts)
+	if err != nil {
+		return err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	if !migrateConfig(m) {
+		fmt.Fprintln(os.Stderr, tr("Config already uses Records, nothing to migrate"))
+		return nil
+	}
+
+	out, err := encodeConfig(configFormatName(strings.TrimSuffix(filename, ".age")), m)
+	if err != nil {
+		return err
+	}
+	if !*write {
+		_, err := os.Stdout.Write(out)
+		return err
+	}
+
+	original, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filename+".bak", original, 0600); err != nil {
+		return fmt.Errorf("failed to back up config: %w", err)
+	}
+	if err := ioutil.WriteFile(filename, out, 0600); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	fmt.Fprint(os.Stderr, trf("Migrated %s, the original is saved as %s.bak\n", filename, filename))
+	return nil
+}
+
+// 把单条记录的字段移到 Records 中，和运行时的规则一致：填写了 Cloudflare 凭据时使用 Cloudflare 的字段。
+// 已经配置了 Records 或 Domains 时单条记录的字段不生效，直接删除。返回是否有修改
+func migrateConfig(m map[string]interface{}) bool {
+	str := func(key string) string {
+		s, _ := m[key].(string)
+		return s
+	}
+	hasFlat := false
+	for _, key := range flatRecordKeys {
+		if _, ok := m[key]; ok {
+			hasFlat = true
+		}
+	}
+	if !hasFlat {
+		return false
+	}
+
+	if !isEmptyList(m["Records"]) || !isEmptyList(m["Domains"]) {
+		fmt.Fprintln(os.Stderr, tr("Records/Domains are configured, removing the unused single-record fields"))
+	} else if str("CF_API_TOKEN") != "" || str("CF_API_TOKEN_FILE") != "" || str("CF_API_KEY") != "" {
+		m["Records"] = []migratedRecord{{Provider: "cloudflare", DomainName: str("DOMAIN_NAME"), Record: str("RECORD_NAME"), RecordType: str("RecordType")}}
+	} else {
+		m["Records"] = []migratedRecord{{Provider: "aliyun", DomainName: str("DomainName"), Record: str("Record"), RecordType: str("RecordType")}}
+	}
+	for _, key := range flatRecordKeys {
+		delete(m, key)
+	}
+	return true
+}
+
+func isEmptyList(v interface{}) bool {
+	list, _ := v.([]interface{})
+	return len(list) == 0
+}
+
+// 按文件扩展名输出配置，字段按 Config 中定义的顺序排列
+func encodeConfig(filename string, m map[string]interface{}) ([]byte, error) {
+	keys := configKeyOrder(m)
+	var buf bytes.Buffer
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		doc := &yaml.Node{Kind: yaml.MappingNode}
+		for _, k := range keys {
+			var value yaml.Node
+			if err := value.Encode(m[k]); err != nil {
+				return nil, err
+			}
+			doc.Content = append(doc.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: k}, &value)
+		}
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(doc); err != nil {
+			return nil, err
+		}
+	case ".toml":
+		if err := toml.NewEncoder(&buf).Encode(m); err != nil {
+			return nil, err
+		}
+	default:
+		buf.WriteString("{\n")
+		for i, k := range keys {
+			value, err := json.MarshalIndent(m[k], "    ", "    ")
+			if err != nil {
+				return nil, err
+			}
+			name, _ := json.Marshal(k)
+			fmt.Fprintf(&buf, "    %s: %s", name, value)
+			if i < len(keys)-1 {
+				buf.WriteString(",")
+			}
+			buf.WriteString("\n")
+		}
+		buf.WriteString("}\n")
+	}
+	return buf.Bytes(), nil
+}
+
+// 字段的输出顺序：先按 Config 结构体中的顺序，其余字段按名称排序
+func configKeyOrder(m map[string]interface{}) []string {
+	var keys []string
+	seen := make(map[string]bool)
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		tag := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if _, ok := m[tag]; ok && !seen[tag] {
+			keys = append(keys, tag)
+			seen[tag] = true
+		}
+	}
+
+	var rest []string
+	for k := range m {
+		if !seen[k] {
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+	return append(keys, rest...)
+}
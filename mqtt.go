@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// 默认的 MQTT 主题前缀
+const defaultMQTTTopic = "aliddns"
+
+// 发布到 MQTT，方便 Home Assistant 等家庭自动化系统在公网地址变化时执行操作。
+// 每条记录的当前值发布到 <Topic>/<完整域名>/<类型>（默认保留消息，新订阅者可以立即拿到），
+// 变化和失败事件以 JSON 发布到 <Topic>/events，字段与通用 Webhook 相同
+type mqttNotifier struct {
+	broker   string
+	topic    string
+	qos      byte
+	retain   bool
+	clientID string
+	username string
+	password string
+}
+
+func newMQTTNotifier(c NotifyConfig) (*mqttNotifier, error) {
+	if c.URL == "" {
+		return nil, fmt.Errorf("mqtt notification requires URL, e.g. tcp://192.168.1.2:1883")
+	}
+	if c.QoS < 0 || c.QoS > 2 {
+		return nil, fmt.Errorf("invalid mqtt QoS %d, use 0, 1 or 2", c.QoS)
+	}
+	n := &mqttNotifier{
+		broker:   c.URL,
+		topic:    strings.TrimSuffix(c.Topic, "/"),
+		qos:      byte(c.QoS),
+		retain:   c.Retain == nil || *c.Retain,
+		clientID: c.ClientID,
+		username: c.Username,
+		password: c.Password,
+	}
+	if n.topic == "" {
+		n.topic = defaultMQTTTopic
+	}
+	if n.clientID == "" {
+		host, _ := os.Hostname()
+		n.clientID = "aliddns-" + host
+	}
+	return n, nil
+}
+
+// 每次通知时连接，发布完成后断开
+func (n *mqttNotifier) notify(e notifyEvent) error {
+	opts := mqtt.NewClientOptions().
+		AddBroker(n.broker).
+		SetClientID(n.clientID).
+		SetUsername(n.username).
+		SetPassword(n.password).
+		SetConnectTimeout(30 * time.Second).
+		SetAutoReconnect(false)
+	client := mqtt.NewClient(opts)
+	if err := waitMQTT(client.Connect()); err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", n.broker, err)
+	}
+	defer client.Disconnect(250)
+
+	for _, c := range e.Changes {
+		topic := n.topic + "/" + c.Record + "/" + c.Type
+		if err := waitMQTT(client.Publish(topic, n.qos, n.retain, c.NewValue)); err != nil {
+			return fmt.Errorf("failed to publish %s: %w", topic, err)
+		}
+	}
+	for _, data := range webhookEvents(e) {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		if err := waitMQTT(client.Publish(n.topic+"/events", n.qos, false, payload)); err != nil {
+			return fmt.Errorf("failed to publish %s/events: %w", n.topic, err)
+		}
+	}
+	return nil
+}
+
+// 等待操作完成，超时按失败处理
+func waitMQTT(token mqtt.Token) error {
+	if !token.WaitTimeout(30 * time.Second) {
+		return fmt.Errorf("timed out")
+	}
+	return token.Error()
+}
+
+func (n *mqttNotifier) String() string {
+	return "mqtt " + n.broker
+}
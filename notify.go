@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/url"
+	"strings"
+)
+
+// 通知配置，记录的值变化或连续更新失败后发送通知
+type NotifyConfig struct {
+	Type string `json:"Type"` // wecom、discord、smtp、webhook、ntfy、pushover、bark、serverchan、feishu、matrix、mqtt、sms、healthchecks、pushdeer 或 grafana
+	URL  string `json:"URL"`  // Webhook 地址，例如企业微信、飞书群机器人或 Discord 频道的地址；ntfy、Bark、PushDeer、Matrix 和 MQTT 为服务器地址
+
+	// 通知策略，见 notifypolicy.go：发送哪些事件（change、failure、always），
+	// 连续失败多少次后通知（默认 3），以及免打扰时段，例如 "23:00-07:00"
+	On         []string `json:"On"`
+	Failures   int      `json:"Failures"`
+	QuietHours string   `json:"QuietHours"`
+
+	// 通用 Webhook 的请求方法（默认 POST）、请求头和请求体模板，见 webhook.go
+	Method  string            `json:"Method"`
+	Headers map[string]string `json:"Headers"`
+	Body    string            `json:"Body"`
+
+	// 企业微信应用消息，不使用群机器人时填写
+	CorpID     string `json:"CorpID"`
+	CorpSecret string `json:"CorpSecret"`
+	AgentID    int    `json:"AgentID"`
+	ToUser     string `json:"ToUser"` // 接收人，多个用 | 分隔，默认 @all
+
+	// SMTP 邮件
+	Host     string   `json:"Host"`
+	Port     int      `json:"Port"`     // 默认按 Security 选择 587、465 或 25
+	Security string   `json:"Security"` // starttls（默认）、ssl 或 none
+	Username string   `json:"Username"` // 留空时不登录，ntfy 和 MQTT 也使用这两个字段
+	Password string   `json:"Password"`
+	From     string   `json:"From"` // 发件人，默认为 Username；短信为 Twilio 的发送号码
+	To       []string `json:"To"`   // 收件人；短信为手机号
+
+	// ntfy 的主题（MQTT 为主题前缀）、优先级（1-5 或 min、low、default、high、max）、标签和访问令牌。
+	// Grafana 注释的附加标签也填在 Tags 中，服务账号的 Token 填在 Token 中
+	Topic    string   `json:"Topic"`
+	Priority string   `json:"Priority"`
+	Tags     []string `json:"Tags"`
+	Token    string   `json:"Token"`
+
+	// Pushover 的用户 Key，应用的 API Token 填在 Token 中，Priority 为 -2 到 1
+	User string `json:"User"`
+
+	// Bark 的设备 Key、Server酱的 SendKey、PushDeer 的 PushKey
+	Key string `json:"Key"`
+
+	// 飞书机器人的签名密钥，未开启签名校验时留空
+	Secret string `json:"Secret"`
+
+	// Matrix 的房间 ID 或别名，access token 填在 Token 中
+	Room string `json:"Room"`
+
+	// MQTT 的 QoS、记录的当前值是否作为保留消息发布（默认是）和客户端 ID
+	QoS      int    `json:"QoS"`
+	Retain   *bool  `json:"Retain"`
+	ClientID string `json:"ClientID"`
+
+	// 短信服务商：aliyun（默认）或 twilio。阿里云短信填签名和模板，AccessKey 留空时使用阿里云 DNS 的凭据；
+	// Twilio 的 Account SID 填在 Username 中，Auth Token 填在 Token 中
+	Provider        string `json:"Provider"`
+	SignName        string `json:"SignName"`
+	TemplateCode    string `json:"TemplateCode"`
+	AccessKeyID     string `json:"AccessKeyID"`
+	AccessKeySecret string `json:"AccessKeySecret"`
+}
+
+// 一条记录的变化
+type recordChange struct {
+	Record   string // 完整域名
+	Type     string
+	Provider string
+	OldValue string // 记录原来的值，新建的记录为空
+	NewValue string
+}
+
+// 要通知的事件：记录的值发生变化、更新失败，或者都没有（每次运行都通知的渠道会收到）
+type notifyEvent struct {
+	Changes  []recordChange
+	Error    string // 最近一次失败的错误
+	Failures int    // 连续失败的次数
+
+	untracked bool // 失败次数不能跨次累计（一次性运行且没有 StateFile），每次失败都按达到阈值处理
+}
+
+// 通知渠道
+type notifier interface {
+	notify(e notifyEvent) error
+	String() string
+}
+
+// 根据配置创建通知渠道
+func newNotifiers(config Config) ([]notifier, error) {
+	notifiers := make([]notifier, 0, len(config.Notify))
+	for _, c := range config.Notify {
+		var n notifier
+		var err error
+		switch strings.ToLower(c.Type) {
+		case "wecom":
+			n, err = newWeComNotifier(c)
+		case "discord":
+			n, err = newDiscordNotifier(c)
+		case "smtp":
+			n, err = newSMTPNotifier(c)
+		case "webhook":
+			n, err = newWebhookNotifier(c)
+		case "ntfy":
+			n, err = newNtfyNotifier(c)
+		case "pushover":
+			n, err = newPushoverNotifier(c)
+		case "bark":
+			n, err = newBarkNotifier(c)
+		case "serverchan":
+			n, err = newServerChanNotifier(c)
+		case "feishu", "lark":
+			n, err = newFeishuNotifier(c)
+		case "matrix":
+			n, err = newMatrixNotifier(c)
+		case "mqtt":
+			n, err = newMQTTNotifier(c)
+		case "sms":
+			n, err = newSMSNotifier(c, config)
+		case "healthchecks":
+			n, err = newHealthchecksNotifier(c)
+		case "pushdeer":
+			n, err = newPushDeerNotifier(c)
+		case "grafana":
+			n, err = newGrafanaNotifier(c)
+		case "":
+			err = fmt.Errorf("notification requires Type")
+		default:
+			err = fmt.Errorf("unknown notification type %q", c.Type)
+		}
+		if err != nil {
+			return nil, err
+		}
+		p, err := newNotifyPolicy(n, c)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, p)
+	}
+	return notifiers, nil
+}
+
+// 把更新过的记录转换为通知事件
+func changeEvent(jobs []*updateJob) notifyEvent {
+	changes := make([]recordChange, 0, len(jobs))
+	for _, j := range jobs {
+		changes = append(changes, recordChange{
+			Record:   j.rec.fqdn(),
+			Type:     j.rec.RecordType,
+			Provider: j.rec.Provider,
+			OldValue: j.current,
+			NewValue: j.newValue,
+		})
+	}
+	return notifyEvent{Changes: changes}
+}
+
+// 发送到所有通知渠道，发送失败只记录日志，不影响更新结果
+func sendNotifications(notifiers []notifier, e notifyEvent) {
+	for _, n := range notifiers {
+		if err := n.notify(e); err != nil {
+			slog.Warn("Failed to send notification", "channel", n.String(), "error", err)
+		}
+	}
+}
+
+// 通知的标题和正文，变化的记录每条一行，失败时为错误信息
+func notifyMessage(e notifyEvent) (string, string) {
+	if e.Error != "" {
+		return trf("DNS update failed %d times in a row", e.Failures), e.Error
+	}
+	if len(e.Changes) == 0 {
+		return tr("No DNS record changed"), tr("All records are up to date")
+	}
+
+	title := tr("DNS record updated")
+	if len(e.Changes) > 1 {
+		title = trf("%d DNS records updated", len(e.Changes))
+	}
+	lines := make([]string, 0, len(e.Changes))
+	for _, c := range e.Changes {
+		old := c.OldValue
+		if old == "" {
+			old = tr("(none)")
+		}
+		lines = append(lines, fmt.Sprintf("%s (%s): %s -> %s", c.Record, c.Type, old, c.NewValue))
+	}
+	return title, strings.Join(lines, "\n")
+}
+
+// 以 JSON 发送请求，返回状态码不是 2xx 时报错。result 不为空时解析返回的 JSON
+func postJSON(endpoint string, body interface{}, result interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	client := outboundClient()
+	resp, err := client.Post(endpoint, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return hideURL(err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	if result != nil {
+		if err := json.Unmarshal(respBody, result); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return nil
+}
+
+// 通知地址中常带有密钥（例如群机器人的 key），请求失败时的错误中去掉地址
+func hideURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("%s request failed: %w", urlErr.Op, urlErr.Err)
+	}
+	return err
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 默认连续失败多少次后发送失败通知，恢复前只通知一次
+const defaultNotifyFailures = 3
+
+// 渠道的通知策略，决定哪些事件发送到这个渠道
+type notifyPolicy struct {
+	notifier
+	change   bool // 记录的值发生变化
+	failure  bool // 连续失败达到 failures 次
+	always   bool // 每次运行都通知，包括没有变化和每次失败
+	failures int
+
+	// 免打扰时段，一天中的第几分钟，quietFrom == quietTo 时不设置
+	quietFrom, quietTo int
+}
+
+// 按 On、Failures 和 QuietHours 包装通知渠道。On 默认为 change 和 failure，
+// 短信默认只有 failure，healthchecks 默认为 always，Grafana 注释默认只有 change
+func newNotifyPolicy(n notifier, c NotifyConfig) (*notifyPolicy, error) {
+	p := &notifyPolicy{notifier: n, failures: c.Failures}
+	on := c.On
+	if len(on) == 0 {
+		on = []string{"change", "failure"}
+		switch strings.ToLower(c.Type) {
+		case "sms":
+			on = []string{"failure"}
+		case "healthchecks":
+			on = []string{"always"}
+		case "grafana":
+			on = []string{"change"}
+		}
+	}
+	for _, event := range on {
+		switch strings.ToLower(event) {
+		case "change":
+			p.change = true
+		case "failure":
+			p.failure = true
+		case "always":
+			p.always = true
+		default:
+			return nil, fmt.Errorf("unknown notification event %q, use change, failure or always", event)
+		}
+	}
+	if p.failures < 0 {
+		return nil, fmt.Errorf("invalid Failures %d", c.Failures)
+	}
+	if p.failures == 0 {
+		p.failures = defaultNotifyFailures
+	}
+	if c.QuietHours != "" {
+		var err error
+		if p.quietFrom, p.quietTo, err = parseQuietHours(c.QuietHours); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func (p *notifyPolicy) notify(e notifyEvent) error {
+	if !p.wants(e, time.Now()) {
+		return nil
+	}
+	return p.notifier.notify(e)
+}
+
+// 失败通知不受免打扰时段限制，避免错过告警
+func (p *notifyPolicy) wants(e notifyEvent, now time.Time) bool {
+	if e.Error != "" {
+		return p.always || p.failure && (e.Failures == p.failures || e.untracked)
+	}
+	if p.quiet(now) {
+		return false
+	}
+	if len(e.Changes) > 0 {
+		return p.change || p.always
+	}
+	return p.always
+}
+
+// 是否在免打扰时段内，时段可以跨过午夜，例如 23:00-07:00
+func (p *notifyPolicy) quiet(now time.Time) bool {
+	if p.quietFrom == p.quietTo {
+		return false
+	}
+	minute := now.Hour()*60 + now.Minute()
+	if p.quietFrom < p.quietTo {
+		return minute >= p.quietFrom && minute < p.quietTo
+	}
+	return minute >= p.quietFrom || minute < p.quietTo
+}
+
+// 解析 "23:00-07:00" 形式的时段，使用本地时间
+func parseQuietHours(s string) (int, int, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid QuietHours %q, use e.g. 23:00-07:00", s)
+	}
+	var minutes [2]int
+	for i, part := range parts {
+		t, err := time.Parse("15:04", strings.TrimSpace(part))
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid QuietHours %q, use e.g. 23:00-07:00", s)
+		}
+		minutes[i] = t.Hour()*60 + t.Minute()
+	}
+	return minutes[0], minutes[1], nil
+}
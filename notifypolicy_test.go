@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNotifyPolicyWants(t *testing.T) {
+	changes := []recordChange{{Record: "home.example.com", Type: "A", NewValue: "203.0.113.7"}}
+	day := time.Date(2024, 12, 20, 12, 0, 0, 0, time.Local)
+	night := time.Date(2024, 12, 20, 23, 30, 0, 0, time.Local)
+	defaults := notifyPolicy{change: true, failure: true, failures: 3}
+	quiet := defaults
+	quiet.quietFrom, quiet.quietTo = 23*60, 7*60
+
+	tests := []struct {
+		name   string
+		policy notifyPolicy
+		event  notifyEvent
+		now    time.Time
+		want   bool
+	}{
+		{"change", defaults, notifyEvent{Changes: changes}, day, true},
+		{"no change", defaults, notifyEvent{}, day, false},
+		{"change not wanted", notifyPolicy{failure: true, failures: 3}, notifyEvent{Changes: changes}, day, false},
+		{"always without change", notifyPolicy{always: true, failures: 3}, notifyEvent{}, day, true},
+		{"failure below threshold", defaults, notifyEvent{Error: "boom", Failures: 2}, day, false},
+		{"failure at threshold", defaults, notifyEvent{Error: "boom", Failures: 3}, day, true},
+		{"failure after threshold", defaults, notifyEvent{Error: "boom", Failures: 4}, day, false},
+		{"untracked failure", defaults, notifyEvent{Error: "boom", Failures: 1, untracked: true}, day, true},
+		{"failure not wanted", notifyPolicy{change: true, failures: 3}, notifyEvent{Error: "boom", Failures: 3}, day, false},
+		{"always on every failure", notifyPolicy{always: true, failures: 3}, notifyEvent{Error: "boom", Failures: 5}, day, true},
+		{"change in quiet hours", quiet, notifyEvent{Changes: changes}, night, false},
+		{"change outside quiet hours", quiet, notifyEvent{Changes: changes}, day, true},
+		{"failure in quiet hours", quiet, notifyEvent{Error: "boom", Failures: 3}, night, true},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.wants(tt.event, tt.now); got != tt.want {
+			t.Errorf("%s: wants = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// 默认的 ntfy 服务器
+const defaultNtfyServer = "https://ntfy.sh"
+
+// 发布到 ntfy 的主题，可以是 ntfy.sh 或自建的服务器
+type ntfyNotifier struct {
+	server   string
+	topic    string
+	priority string
+	tags     []string
+	token    string
+	username string
+	password string
+}
+
+func newNtfyNotifier(c NotifyConfig) (*ntfyNotifier, error) {
+	if c.Topic == "" {
+		return nil, fmt.Errorf("ntfy notification requires Topic")
+	}
+	n := &ntfyNotifier{
+		server:   strings.TrimSuffix(c.URL, "/"),
+		topic:    c.Topic,
+		priority: strings.ToLower(c.Priority),
+		tags:     c.Tags,
+		token:    c.Token,
+		username: c.Username,
+		password: c.Password,
+	}
+	if n.server == "" {
+		n.server = defaultNtfyServer
+	}
+	switch n.priority {
+	case "", "1", "2", "3", "4", "5", "min", "low", "default", "high", "max", "urgent":
+	default:
+		return nil, fmt.Errorf("invalid ntfy Priority %q, use 1-5 or min, low, default, high, max", c.Priority)
+	}
+	return n, nil
+}
+
+// 标题、优先级和标签通过请求头传递；失败通知默认使用高优先级并加上 warning 标签
+func (n *ntfyNotifier) notify(e notifyEvent) error {
+	title, text := notifyMessage(e)
+	req, err := http.NewRequest("POST", n.server+"/"+n.topic, strings.NewReader(text))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Title", "aliDDNS: "+title)
+
+	priority, tags := n.priority, n.tags
+	if e.Error != "" {
+		if priority == "" {
+			priority = "high"
+		}
+		tags = append([]string{"warning"}, tags...)
+	}
+	if priority != "" {
+		req.Header.Set("Priority", priority)
+	}
+	if len(tags) > 0 {
+		req.Header.Set("Tags", strings.Join(tags, ","))
+	}
+	if n.token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.token)
+	} else if n.username != "" {
+		req.SetBasicAuth(n.username, n.password)
+	}
+
+	client := outboundClient()
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func (n *ntfyNotifier) String() string {
+	return "ntfy " + n.topic
+}
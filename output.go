@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"golang.org/x/term"
+)
+
+// 终端颜色
+const (
+	colorReset = "\033[0m"
+	colorRed   = "\033[31m"
+	colorGreen = "\033[32m"
+	colorGray  = "\033[90m"
+)
+
+// 只运行一次时在标准输出显示每条记录的结果。连接终端时用颜色和 ✓/✗ 标记并显示汇总；
+// quiet 时没有变化也没有错误就不输出，cron 只在有事发生时才发邮件
+type resultPrinter struct {
+	out    io.Writer
+	pretty bool // 标准输出是终端
+	color  bool // 设置了 NO_COLOR 时不使用颜色
+	quiet  bool
+}
+
+func newResultPrinter(quiet bool) *resultPrinter {
+	pretty := term.IsTerminal(int(os.Stdout.Fd()))
+	return &resultPrinter{
+		out:    os.Stdout,
+		pretty: pretty,
+		color:  pretty && os.Getenv("NO_COLOR") == "",
+		quiet:  quiet,
+	}
+}
+
+// 是否输出结果。输出结果时普通日志只保留警告和错误，避免重复
+func (p *resultPrinter) enabled() bool {
+	return p.pretty || p.quiet
+}
+
+// 输出本次检查的结果，status 为空（检查前就失败了）时不输出
+func (p *resultPrinter) print(status *Status) {
+	if status == nil || !p.enabled() {
+		return
+	}
+	var changed, unchanged, failed, disabled int
+	for _, r := range status.Records {
+		switch {
+		case r.Error != "":
+			failed++
+		case r.Disabled:
+			disabled++
+		case r.changed:
+			changed++
+		default:
+			unchanged++
+		}
+	}
+	if p.quiet && changed == 0 && failed == 0 {
+		return
+	}
+
+	w := tabwriter.NewWriter(p.out, 0, 0, 2, ' ', 0)
+	for _, r := range status.Records {
+		// quiet 时只列出有变化或出错的记录
+		if p.quiet && !r.changed && r.Error == "" {
+			continue
+		}
+		mark, color, detail := "✓", colorGreen, r.Value
+		switch {
+		case r.Error != "":
+			mark, color, detail = "✗", colorRed, r.Error
+		case r.Disabled:
+			mark, color, detail = "-", colorGray, tr("disabled")
+		case r.changed:
+			old := r.previous
+			if old == "" {
+				old = tr("(none)")
+			}
+			arrow := " -> "
+			if p.pretty {
+				arrow = " → "
+			}
+			detail = old + arrow + r.Value
+		default:
+			color = colorGray
+			detail += tr(" (unchanged)")
+		}
+		if !p.pretty {
+			mark = tr(map[string]string{"✓": "ok", "✗": "failed", "-": "disabled"}[mark])
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", p.paint(color, mark), r.Record, r.Type, r.Provider, detail)
+	}
+	w.Flush()
+
+	if p.pretty {
+		summary := trf("%d updated, %d unchanged, %d failed", changed, unchanged, failed)
+		if disabled > 0 {
+			summary += trf(", %d disabled", disabled)
+		}
+		if status.LastError != "" && failed == 0 {
+			summary += tr(" (see errors above)")
+		}
+		fmt.Fprintln(p.out, summary)
+	}
+}
+
+func (p *resultPrinter) paint(color, s string) string {
+	if !p.color {
+		return s
+	}
+	return color + s + colorReset
+}
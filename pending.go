@@ -0,0 +1,95 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+)
+
+// 待发布的更新重试的间隔：第一次失败后 30 秒，之后每次加倍，最长 10 分钟。
+// 常驻模式下检查间隔更短时按检查间隔
+const (
+	pendingRetryMin = 30 * time.Second
+	pendingRetryMax = 10 * time.Minute
+)
+
+// 地址变化后没能发布的新值，例如服务商接口不可用时。保存在状态文件中，
+// 常驻模式下按计划重试直到成功，重启后继续，服务商故障期间的变化不会丢失
+type PendingUpdate struct {
+	Value     string    `json:"Value"`
+	Since     time.Time `json:"Since"`    // 第一次发布失败的时间
+	Attempts  int       `json:"Attempts"` // 失败的次数
+	LastError string    `json:"LastError,omitempty"`
+	NextRetry time.Time `json:"NextRetry"` // 常驻模式下次重试的时间
+}
+
+// 地址变化了却没能发布：记下新值，值变化时重新计时
+func (rs *RecordState) markPending(value string) {
+	if rs.Pending == nil || rs.Pending.Value != value {
+		rs.Pending = &PendingUpdate{Value: value, Since: time.Now()}
+	}
+}
+
+// 待发布的记录本次检查失败（发布失败，或者检测地址、准备阶段就已出错），推迟下次重试的时间
+func (p *PendingUpdate) failed(msg string) {
+	p.Attempts++
+	p.LastError = msg
+
+	d := pendingRetryMin
+	for i := 1; i < p.Attempts && d < pendingRetryMax; i++ {
+		d *= 2
+	}
+	if d > pendingRetryMax {
+		d = pendingRetryMax
+	}
+	p.NextRetry = time.Now().Add(d)
+}
+
+// 常驻模式重新加载配置后，清除从配置中删除的记录的待发布更新。
+// 只比较新旧两份配置，命令行参数只更新部分记录时不会清除其余记录的待发布更新
+func dropRemovedPending(old, next *updater) {
+	current := make(map[string]bool)
+	for _, rec := range next.config.records() {
+		current[rec.key()] = true
+	}
+	for _, rec := range old.config.records() {
+		key := rec.key()
+		if current[key] {
+			continue
+		}
+		if resolved, ok := old.recordKeys[key]; ok {
+			key = resolved
+		}
+		if rs := next.state.Records[key]; rs != nil && rs.Pending != nil {
+			slog.Info("Dropping pending update of removed record", "record", rec, "value", rs.Pending.Value)
+			rs.Pending = nil
+		}
+	}
+}
+
+// 最早需要重试的待发布更新的时间，没有待发布的更新时返回 false。只看当前配置中的记录，
+// recordKeys 见 updater。不早于 pendingRetryMin 之后，状态中的时间已经过去时也不会连续重试
+func (s *State) nextPendingRetry(recordKeys map[string]string) (time.Time, bool) {
+	var next time.Time
+	for _, key := range recordKeys {
+		rs := s.Records[key]
+		if rs == nil || rs.Pending == nil || rs.Disabled {
+			continue
+		}
+		if next.IsZero() || rs.Pending.NextRetry.Before(next) {
+			next = rs.Pending.NextRetry
+		}
+	}
+	if next.IsZero() {
+		return next, false
+	}
+	if earliest := time.Now().Add(pendingRetryMin); next.Before(earliest) {
+		next = earliest
+	}
+	return next, true
+}
+
+// status 命令中显示的一条待发布更新
+func (p *PendingUpdate) describe(record string) string {
+	return trf("%s -> %s, pending since %s, %d failed attempt(s)",
+		record, p.Value, p.Since.Local().Format("2006-01-02 15:04:05"), p.Attempts)
+}
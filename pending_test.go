@@ -0,0 +1,94 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPendingUpdateFailed(t *testing.T) {
+	tests := []struct {
+		attempts int // 之前失败的次数
+		want     time.Duration
+	}{
+		{0, 30 * time.Second},
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{4, 8 * time.Minute},
+		{5, 10 * time.Minute},
+		{20, 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		p := &PendingUpdate{Value: "203.0.113.7", Attempts: tt.attempts}
+		before := time.Now()
+		p.failed("boom")
+		after := time.Now()
+
+		if p.Attempts != tt.attempts+1 || p.LastError != "boom" {
+			t.Errorf("attempts %d: got Attempts %d, LastError %q", tt.attempts, p.Attempts, p.LastError)
+		}
+		if p.NextRetry.Before(before.Add(tt.want)) || p.NextRetry.After(after.Add(tt.want)) {
+			t.Errorf("attempts %d: NextRetry in %v, want %v", tt.attempts, p.NextRetry.Sub(before), tt.want)
+		}
+	}
+}
+
+func TestNextPendingRetry(t *testing.T) {
+	now := time.Now()
+	pending := func(next time.Time) *RecordState {
+		return &RecordState{Pending: &PendingUpdate{Value: "203.0.113.7", NextRetry: next}}
+	}
+	tests := []struct {
+		name    string
+		records map[string]*RecordState
+		keys    map[string]string
+		ok      bool
+		want    time.Time // 为零时检查不早于 pendingRetryMin 之后
+	}{
+		{
+			name:    "nothing pending",
+			records: map[string]*RecordState{"a": {IP: "203.0.113.7"}},
+			keys:    map[string]string{"a": "a"},
+		},
+		{
+			name:    "disabled",
+			records: map[string]*RecordState{"a": {Disabled: true, Pending: &PendingUpdate{NextRetry: now.Add(time.Hour)}}},
+			keys:    map[string]string{"a": "a"},
+		},
+		{
+			name:    "record not in config",
+			records: map[string]*RecordState{"old": pending(now.Add(time.Hour))},
+			keys:    map[string]string{"a": "a"},
+		},
+		{
+			name:    "earliest of resolved keys",
+			records: map[string]*RecordState{"a.resolved": pending(now.Add(2 * time.Hour)), "b": pending(now.Add(time.Hour))},
+			keys:    map[string]string{"a": "a.resolved", "b": "b"},
+			ok:      true,
+			want:    now.Add(time.Hour),
+		},
+		{
+			name:    "overdue",
+			records: map[string]*RecordState{"a": pending(now.Add(-time.Hour))},
+			keys:    map[string]string{"a": "a"},
+			ok:      true,
+		},
+	}
+	for _, tt := range tests {
+		s := &State{Records: tt.records}
+		before := time.Now()
+		got, ok := s.nextPendingRetry(tt.keys)
+		if ok != tt.ok {
+			t.Errorf("%s: ok = %v, want %v", tt.name, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if !tt.want.IsZero() && !got.Equal(tt.want) {
+			t.Errorf("%s: next = %v, want %v", tt.name, got, tt.want)
+		}
+		if got.Before(before.Add(pendingRetryMin)) {
+			t.Errorf("%s: next = %v, earlier than %v", tt.name, got, pendingRetryMin)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 配置文件中的命名配置，例如 {"Profiles": {"home": {...}, "work": {...}}}。
+// 选中的配置中的字段覆盖顶层的同名字段，顶层的字段作为所有配置共用的默认值
+const profilesKey = "Profiles"
+
+// 按名称选出一个配置并与顶层字段合并，返回合并后的 JSON。没有指定名称时只使用顶层字段。
+// 在展开 ${VAR} 之前选择，未选中的配置引用的环境变量可以不设置
+func selectProfile(data []byte, name string) ([]byte, error) {
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(data, &top); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	raw, ok := top[profilesKey]
+	if !ok {
+		if name != "" {
+			return nil, fmt.Errorf("profile %q not found: config has no %s", name, profilesKey)
+		}
+		return data, nil
+	}
+	delete(top, profilesKey)
+
+	var profiles map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &profiles); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", profilesKey, err)
+	}
+	if name != "" {
+		profile, ok := profiles[name]
+		if !ok {
+			names := make([]string, 0, len(profiles))
+			for n := range profiles {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("profile %q not found, available: %s", name, strings.Join(names, ", "))
+		}
+		for k, v := range profile {
+			top[k] = v
+		}
+	}
+	return json.Marshal(top)
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 记录或域名不存在，以及凭据无效或没有权限，用于区分退出码，见 exitcode.go
+var (
+	errRecordNotFound = errors.New("record not found")
+	errZoneNotFound   = errors.New("zone not found")
+	errAuth           = errors.New("authentication failed")
+)
+
+// DNS 服务商
+type provider interface {
+	// 把记录更新为新地址，返回记录原来的值。oldIP 为上次发布的地址，可能为空
+	updateRecord(rec RecordConfig, newIP, oldIP string) (string, error)
+	// 新建记录，已有相同值的记录时不重复创建
+	createRecord(rec RecordConfig, value string) error
+	// 删除同名同类型的记录，value 不为空时只删除值相同的记录
+	deleteRecords(rec RecordConfig, value string) error
+	// 启用或停用记录，value 为重新启用时使用的值
+	setEnabled(rec RecordConfig, enabled bool, value string) error
+}
+
+// 一条记录的更新任务
+type updateJob struct {
+	rec      RecordConfig
+	newValue string // 要发布的值
+	oldValue string // 上次发布的值，可能为空
+
+	weighted []weightedValue // 加权解析的各个地址，为空时只发布 newValue
+	ids      recordIDs       // 状态文件中缓存的记录 ID
+
+	current string        // 记录原来的值
+	proxied bool          // 更新后的记录经过 Cloudflare 代理，解析到的是 Cloudflare 的地址
+	latency time.Duration // 调用服务商接口的耗时，批量更新时为整批的耗时
+	err     error
+}
+
+// 缓存的记录 ID，阿里云只有 RecordID，Cloudflare 还需要 ZoneID
+type recordIDs struct {
+	zoneID   string
+	recordID string
+}
+
+// 支持缓存记录 ID 的服务商，ID 有效时省去每次查询记录
+type idCachingProvider interface {
+	// 用缓存的 ID 直接更新，ID 无效或不适用时返回 false，由调用方回退到查询后更新
+	updateByID(j *updateJob) bool
+	// 上次更新时用到的记录 ID，没有或有多条记录时返回空值
+	cachedIDs(rec RecordConfig) recordIDs
+}
+
+// 是否可以用缓存的 ID 直接更新：只更新一条记录，并且知道上次发布的值
+func canUpdateByID(j *updateJob) bool {
+	policy := j.rec.MultiRecordPolicy
+	return j.ids.recordID != "" && j.oldValue != "" && len(j.weighted) == 0 && (policy == "" || policy == policyFirst)
+}
+
+// 加权解析中的一个地址
+type weightedValue struct {
+	value  string
+	weight int
+}
+
+// 支持加权解析的服务商
+type weightedProvider interface {
+	// 让同名记录与各个地址一一对应并设置权重，返回记录原来的值
+	updateWeighted(rec RecordConfig, values []weightedValue) (string, error)
+}
+
+// 加权解析的地址列表在状态中的表示，排序后用逗号连接
+func joinWeightedValues(values []string) string {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
+
+// 支持批量更新的服务商，同一服务商有多条记录需要更新时使用
+type batchProvider interface {
+	// 批量更新，结果写回每个任务
+	updateBatch(jobs []*updateJob)
+}
+
+// 执行同一服务商的更新任务，有缓存的记录 ID 时直接更新，支持批量更新时合并请求，加权解析的记录单独更新
+func runJobs(p provider, jobs []*updateJob) {
+	var plain []*updateJob
+	for _, j := range jobs {
+		start := time.Now()
+		if len(j.weighted) == 0 {
+			if cp, ok := p.(idCachingProvider); ok && canUpdateByID(j) && cp.updateByID(j) {
+				j.latency = time.Since(start)
+				continue
+			}
+			plain = append(plain, j)
+			continue
+		}
+		wp, ok := p.(weightedProvider)
+		if !ok {
+			j.err = fmt.Errorf("provider %s does not support weighted records", j.rec.Provider)
+			continue
+		}
+		j.current, j.err = wp.updateWeighted(j.rec, j.weighted)
+		j.latency = time.Since(start)
+	}
+
+	if bp, ok := p.(batchProvider); ok && len(plain) > 1 {
+		start := time.Now()
+		bp.updateBatch(plain)
+		for _, j := range plain {
+			j.latency = time.Since(start)
+		}
+		return
+	}
+	for _, j := range plain {
+		start := time.Now()
+		j.current, j.err = p.updateRecord(j.rec, j.newValue, j.oldValue)
+		j.latency = time.Since(start)
+	}
+}
+
+// 记录可以经过代理的服务商（Cloudflare），更新后报告记录实际是否经过代理
+type proxyingProvider interface {
+	isProxied(rec RecordConfig) bool
+}
+
+// 可以根据完整记录名查找所属域名（Zone）的服务商
+type zoneFinder interface {
+	findZone(fqdn string) (string, error)
+}
+
+// 可以只读地检查凭据和域名是否可用的服务商，用于 config validate -online
+type accessChecker interface {
+	checkAccess(rec RecordConfig) error
+}
+
+// 可以列出账号下的域名和记录的服务商，用于 init 向导中选择和 list 命令
+type zoneLister interface {
+	listZones() ([]string, error)
+	listRecords(domain string) ([]zoneRecord, error)
+}
+
+// 服务商中已有的一条记录
+type zoneRecord struct {
+	ID         string
+	Record     string // 主机记录，Cloudflare 为完整域名
+	RecordType string
+	Value      string
+	TTL        int
+}
+
+// 未配置 DomainName 时根据完整记录名查找所属的域名，并把记录名转换为服务商需要的形式
+func resolveZone(p provider, rec RecordConfig) (RecordConfig, error) {
+	if rec.DomainName != "" {
+		return rec, nil
+	}
+	zf, ok := p.(zoneFinder)
+	if !ok || !strings.Contains(rec.Record, ".") {
+		return rec, fmt.Errorf("DomainName is required unless Record is a full domain name")
+	}
+	zone, err := zf.findZone(rec.Record)
+	if err != nil {
+		return rec, fmt.Errorf("failed to find the zone of %s: %w", rec.Record, err)
+	}
+	rec.DomainName = zone
+	rec.Record = providerRecordName(rec.Provider, rec.Record, zone)
+	return rec, nil
+}
+
+// 依次尝试完整域名的各级后缀（从长到短，不含顶级域），返回第一个 lookup 成功的结果
+func findZoneBySuffix(fqdn string, lookup func(name string) error) (string, error) {
+	labels := strings.Split(fqdn, ".")
+	var lastErr error
+	for i := 0; i < len(labels)-1; i++ {
+		candidate := strings.Join(labels[i:], ".")
+		if lastErr = lookup(candidate); lastErr == nil {
+			return candidate, nil
+		}
+	}
+	return "", lastErr
+}
+
+// 根据名称创建服务商，ctx 结束后取消服务商的所有请求
+func newProvider(ctx context.Context, config Config, name string) (provider, error) {
+	switch name {
+	case "aliyun":
+		return newAliyunProvider(ctx, config)
+	case "cloudflare":
+		return newCloudflareProvider(ctx, config)
+	case "pvtz":
+		return newPvtzProvider(ctx, config)
+	default:
+		return nil, fmt.Errorf("unknown provider %q", name)
+	}
+}
+
+// 同名同类型有多条记录（轮询解析）时的处理策略
+const (
+	policyFirst    = "first"    // 只更新第一条（默认）
+	policyAll      = "all"      // 更新所有记录
+	policyMatch    = "match"    // 只更新值为上次发布地址的记录
+	policyCollapse = "collapse" // 删除多余的记录，只保留一条
+)
+
+// 按策略选出需要更新的记录下标，返回的列表不为空
+func selectTargets(values []string, oldIP, policy string) ([]int, error) {
+	switch policy {
+	case "", policyFirst, policyCollapse:
+		return []int{0}, nil
+	case policyAll:
+		targets := make([]int, len(values))
+		for i := range values {
+			targets[i] = i
+		}
+		return targets, nil
+	case policyMatch:
+		var targets []int
+		for i, v := range values {
+			if oldIP != "" && v == oldIP {
+				targets = append(targets, i)
+			}
+		}
+		if len(targets) == 0 {
+			// 不知道上次的地址或者没有匹配的记录时，退回到只更新第一条
+			slog.Warn("No record matches the previous IP, updating the first one", "previous", oldIP)
+			return []int{0}, nil
+		}
+		return targets, nil
+	default:
+		return nil, fmt.Errorf("unknown MultiRecordPolicy %q", policy)
+	}
+}
+
+// 从重复的记录中选出要保留的一条：优先保留值已经是新地址的记录，否则保留第一条
+func canonicalIndex(values []string, newIP string) int {
+	for i, v := range values {
+		if v == newIP {
+			return i
+		}
+	}
+	return 0
+}
+
+// 生成记录备注，支持 {date}、{host} 和 {ip} 占位符
+func remarkText(rec RecordConfig, value string) string {
+	host, _ := os.Hostname()
+	return strings.NewReplacer(
+		"{date}", time.Now().Format("2006-01-02 15:04"),
+		"{host}", host,
+		"{ip}", value,
+	).Replace(rec.Remark)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// 默认的 PushDeer 服务器
+const defaultPushDeerServer = "https://api2.pushdeer.com"
+
+// PushDeer 推送，Key 为 PushKey，自建服务器时 URL 填服务器地址
+type pushDeerNotifier struct {
+	server string
+	key    string
+}
+
+func newPushDeerNotifier(c NotifyConfig) (*pushDeerNotifier, error) {
+	if c.Key == "" {
+		return nil, fmt.Errorf("pushdeer notification requires Key")
+	}
+	n := &pushDeerNotifier{server: strings.TrimSuffix(c.URL, "/"), key: c.Key}
+	if n.server == "" {
+		n.server = defaultPushDeerServer
+	}
+	return n, nil
+}
+
+func (n *pushDeerNotifier) notify(e notifyEvent) error {
+	title, text := notifyMessage(e)
+	form := url.Values{
+		"pushkey": {n.key},
+		"text":    {"aliDDNS: " + title},
+		"desp":    {strings.ReplaceAll(text, "\n", "\n\n")},
+		"type":    {"markdown"},
+	}
+
+	client := outboundClient()
+	resp, err := client.PostForm(n.server+"/message/push", form)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Code  int    `json:"code"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("status %d: failed to decode response: %w", resp.StatusCode, err)
+	}
+	if result.Code != 0 {
+		return fmt.Errorf("code %d: %s", result.Code, result.Error)
+	}
+	return nil
+}
+
+func (n *pushDeerNotifier) String() string {
+	return "pushdeer"
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Pushover 消息接口
+const pushoverAPI = "https://api.pushover.net/1/messages.json"
+
+// Pushover 推送，Token 为应用的 API Token，User 为用户或群组的 Key
+type pushoverNotifier struct {
+	token    string
+	user     string
+	priority string
+}
+
+func newPushoverNotifier(c NotifyConfig) (*pushoverNotifier, error) {
+	if c.Token == "" || c.User == "" {
+		return nil, fmt.Errorf("pushover notification requires Token and User")
+	}
+	if c.Priority != "" {
+		if p, err := strconv.Atoi(c.Priority); err != nil || p < -2 || p > 1 {
+			return nil, fmt.Errorf("invalid pushover Priority %q, use -2 to 1", c.Priority)
+		}
+	}
+	return &pushoverNotifier{token: c.Token, user: c.User, priority: c.Priority}, nil
+}
+
+// 失败通知默认使用高优先级（1）
+func (n *pushoverNotifier) notify(e notifyEvent) error {
+	title, text := notifyMessage(e)
+	form := url.Values{
+		"token":   {n.token},
+		"user":    {n.user},
+		"title":   {"aliDDNS: " + title},
+		"message": {text},
+	}
+	priority := n.priority
+	if priority == "" && e.Error != "" {
+		priority = "1"
+	}
+	if priority != "" {
+		form.Set("priority", priority)
+	}
+
+	client := outboundClient()
+	resp, err := client.PostForm(pushoverAPI, form)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Status int      `json:"status"`
+		Errors []string `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("status %d: failed to decode response: %w", resp.StatusCode, err)
+	}
+	if result.Status != 1 {
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.Join(result.Errors, "; "))
+	}
+	return nil
+}
+
+func (n *pushoverNotifier) String() string {
+	return "pushover"
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/pvtz"
+)
+
+// 阿里云内网 DNS（PrivateZone），记录只在绑定的 VPC 内生效，用于内外网分别解析
+type pvtzProvider struct {
+	client  *pvtz.Client
+	zoneIDs map[string]string // Zone 名称到 ZoneId 的缓存
+}
+
+// 创建 PrivateZone 客户端，与阿里云 DNS 使用相同的凭据
+func newPvtzProvider(ctx context.Context, config Config) (*pvtzProvider, error) {
+	credential, err := aliyunCredential(config)
+	if err != nil {
+		return nil, err
+	}
+	transport, err := newProviderTransport(ctx, config, "pvtz")
+	if err != nil {
+		return nil, err
+	}
+	sdkConfig := sdk.NewConfig()
+	sdkConfig.Transport = transport
+	client, err := pvtz.NewClientWithOptions(config.aliyunRegion(), sdkConfig, credential)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create PrivateZone client: %w", err)
+	}
+	return &pvtzProvider{client: client, zoneIDs: make(map[string]string)}, nil
+}
+
+func (p *pvtzProvider) updateRecord(rec RecordConfig, newIP, oldIP string) (string, error) {
+	slog.Debug("New IP to update", "record", rec, "value", newIP)
+	matched, err := p.findRecords(rec)
+	if err != nil {
+		return "", err
+	}
+
+	if len(matched) == 0 {
+		if rec.CreateMissing {
+			return "", p.createRecord(rec, newIP)
+		}
+		return "", fmt.Errorf("%s record %s not found in private zone %s: %w", rec.RecordType, rec.Record, rec.DomainName, errRecordNotFound)
+	}
+
+	values := make([]string, len(matched))
+	for i, r := range matched {
+		values[i] = r.Value
+	}
+
+	// 删除重复的记录，只保留一条
+	if len(matched) > 1 && rec.MultiRecordPolicy == policyCollapse {
+		keep := canonicalIndex(values, newIP)
+		for i, r := range matched {
+			if i == keep {
+				continue
+			}
+			if err := p.deleteRecord(r.RecordId); err != nil {
+				return "", err
+			}
+			slog.Info("Deleted duplicate record", "type", r.Type, "rr", r.Rr, "value", r.Value)
+		}
+		matched = matched[keep : keep+1]
+		values = values[keep : keep+1]
+	}
+
+	targets, err := selectTargets(values, oldIP, rec.MultiRecordPolicy)
+	if err != nil {
+		return "", err
+	}
+
+	currentIP := matched[targets[0]].Value
+	for _, i := range targets {
+		if err := p.update(matched[i], rec, newIP); err != nil {
+			return "", err
+		}
+	}
+	return currentIP, nil
+}
+
+func (p *pvtzProvider) createRecord(rec RecordConfig, value string) error {
+	zoneID, err := p.zoneID(rec.DomainName)
+	if err != nil {
+		return err
+	}
+
+	addRequest := pvtz.CreateAddZoneRecordRequest()
+	addRequest.ZoneId = zoneID
+	addRequest.Rr = rec.Record
+	addRequest.Type = rec.RecordType
+	addRequest.Value = value
+	if rec.TTL > 0 {
+		addRequest.Ttl = requests.NewInteger(rec.TTL)
+	}
+	if rec.RecordType == "MX" && rec.Priority > 0 {
+		addRequest.Priority = requests.NewInteger(rec.Priority)
+	}
+	if rec.Remark != "" {
+		addRequest.Remark = remarkText(rec, value)
+	}
+
+	if _, err := p.client.AddZoneRecord(addRequest); err != nil {
+		return fmt.Errorf("failed to add private zone record: %w", err)
+	}
+	slog.Info("Created record", "record", rec, "value", value)
+	return nil
+}
+
+func (p *pvtzProvider) deleteRecords(rec RecordConfig, value string) error {
+	matched, err := p.findRecords(rec)
+	if err != nil {
+		return err
+	}
+	for _, r := range matched {
+		if value != "" && r.Value != value {
+			continue
+		}
+		if err := p.deleteRecord(r.RecordId); err != nil {
+			return err
+		}
+		slog.Info("Deleted record", "type", r.Type, "rr", r.Rr, "value", r.Value)
+	}
+	return nil
+}
+
+func (p *pvtzProvider) setEnabled(rec RecordConfig, enabled bool, _ string) error {
+	matched, err := p.findRecords(rec)
+	if err != nil {
+		return err
+	}
+	if len(matched) == 0 {
+		return fmt.Errorf("%s record %s not found in private zone %s: %w", rec.RecordType, rec.Record, rec.DomainName, errRecordNotFound)
+	}
+
+	status := "DISABLE"
+	if enabled {
+		status = "ENABLE"
+	}
+	for _, r := range matched {
+		statusRequest := pvtz.CreateSetZoneRecordStatusRequest()
+		statusRequest.RecordId = requests.NewInteger64(r.RecordId)
+		statusRequest.Status = status
+		if _, err := p.client.SetZoneRecordStatus(statusRequest); err != nil {
+			return fmt.Errorf("failed to set private zone record status: %w", err)
+		}
+		slog.Info("Set record status", "type", r.Type, "rr", r.Rr, "status", status, "value", r.Value)
+	}
+	return nil
+}
+
+// 查询 Zone 的 ID，结果会缓存
+func (p *pvtzProvider) zoneID(name string) (string, error) {
+	if id, ok := p.zoneIDs[name]; ok {
+		return id, nil
+	}
+
+	for page := 1; ; page++ {
+		describeRequest := pvtz.CreateDescribeZonesRequest()
+		describeRequest.Keyword = name
+		describeRequest.SearchMode = "EXACT"
+		describeRequest.PageNumber = requests.NewInteger(page)
+		describeRequest.PageSize = requests.NewInteger(100)
+		describeResponse, err := p.client.DescribeZones(describeRequest)
+		if err != nil {
+			return "", fmt.Errorf("failed to describe private zones: %w", err)
+		}
+
+		for _, z := range describeResponse.Zones.Zone {
+			if z.ZoneName == name {
+				p.zoneIDs[name] = z.ZoneId
+				return z.ZoneId, nil
+			}
+		}
+		if page >= describeResponse.TotalPages {
+			return "", fmt.Errorf("private zone %s: %w", name, errZoneNotFound)
+		}
+	}
+}
+
+// 从长到短尝试记录名的各级后缀，找到账号下存在的内网 Zone
+func (p *pvtzProvider) findZone(fqdn string) (string, error) {
+	return findZoneBySuffix(fqdn, func(name string) error {
+		_, err := p.zoneID(name)
+		return err
+	})
+}
+
+// 列出账号下的全部内网 Zone
+func (p *pvtzProvider) listZones() ([]string, error) {
+	var names []string
+	for page := 1; ; page++ {
+		describeRequest := pvtz.CreateDescribeZonesRequest()
+		describeRequest.PageNumber = requests.NewInteger(page)
+		describeRequest.PageSize = requests.NewInteger(100)
+		describeResponse, err := p.client.DescribeZones(describeRequest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe private zones: %w", err)
+		}
+
+		for _, z := range describeResponse.Zones.Zone {
+			names = append(names, z.ZoneName)
+		}
+		if page >= describeResponse.TotalPages {
+			return names, nil
+		}
+	}
+}
+
+// 列出内网 Zone 下的全部记录
+func (p *pvtzProvider) listRecords(domain string) ([]zoneRecord, error) {
+	zoneID, err := p.zoneID(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []zoneRecord
+	for page := 1; ; page++ {
+		describeRequest := pvtz.CreateDescribeZoneRecordsRequest()
+		describeRequest.ZoneId = zoneID
+		describeRequest.PageNumber = requests.NewInteger(page)
+		describeRequest.PageSize = requests.NewInteger(100)
+		describeResponse, err := p.client.DescribeZoneRecords(describeRequest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe private zone records: %w", err)
+		}
+
+		for _, r := range describeResponse.Records.Record {
+			result = append(result, zoneRecord{ID: strconv.FormatInt(r.RecordId, 10), Record: r.Rr, RecordType: r.Type, Value: r.Value, TTL: r.Ttl})
+		}
+		if page >= describeResponse.TotalPages {
+			return result, nil
+		}
+	}
+}
+
+// 查询一次记录，检查凭据是否有效、内网 Zone 是否存在
+func (p *pvtzProvider) checkAccess(rec RecordConfig) error {
+	_, err := p.findRecords(rec)
+	return err
+}
+
+// 查询主机记录和类型都匹配的记录
+func (p *pvtzProvider) findRecords(rec RecordConfig) ([]pvtz.Record, error) {
+	zoneID, err := p.zoneID(rec.DomainName)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []pvtz.Record
+	for page := 1; ; page++ {
+		describeRequest := pvtz.CreateDescribeZoneRecordsRequest()
+		describeRequest.ZoneId = zoneID
+		describeRequest.Keyword = rec.Record
+		describeRequest.SearchMode = "EXACT"
+		describeRequest.PageNumber = requests.NewInteger(page)
+		describeRequest.PageSize = requests.NewInteger(100)
+		describeResponse, err := p.client.DescribeZoneRecords(describeRequest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe private zone records: %w", err)
+		}
+
+		for _, r := range describeResponse.Records.Record {
+			if r.Rr == rec.Record && r.Type == rec.RecordType {
+				matched = append(matched, r)
+			}
+		}
+		if page >= describeResponse.TotalPages {
+			return matched, nil
+		}
+	}
+}
+
+// 把一条已有的记录更新为新地址
+func (p *pvtzProvider) update(r pvtz.Record, rec RecordConfig, newIP string) error {
+	if r.Value == newIP && !rec.force {
+		slog.Info("IP address is already up to date", "rr", r.Rr, "value", r.Value)
+		return nil
+	}
+
+	updateRequest := pvtz.CreateUpdateZoneRecordRequest()
+	updateRequest.RecordId = requests.NewInteger64(r.RecordId)
+	updateRequest.Rr = rec.Record
+	updateRequest.Type = rec.RecordType
+	updateRequest.Value = newIP
+	if rec.TTL > 0 {
+		updateRequest.Ttl = requests.NewInteger(rec.TTL)
+	}
+	if rec.RecordType == "MX" && rec.Priority > 0 {
+		updateRequest.Priority = requests.NewInteger(rec.Priority)
+	}
+	if _, err := p.client.UpdateZoneRecord(updateRequest); err != nil {
+		return fmt.Errorf("failed to update private zone record: %w", err)
+	}
+
+	if rec.Remark == "" {
+		return nil
+	}
+	remarkRequest := pvtz.CreateUpdateRecordRemarkRequest()
+	remarkRequest.RecordId = requests.NewInteger64(r.RecordId)
+	remarkRequest.Remark = remarkText(rec, newIP)
+	if _, err := p.client.UpdateRecordRemark(remarkRequest); err != nil {
+		return fmt.Errorf("failed to update private zone record remark: %w", err)
+	}
+	return nil
+}
+
+// 删除记录
+func (p *pvtzProvider) deleteRecord(recordID int64) error {
+	deleteRequest := pvtz.CreateDeleteZoneRecordRequest()
+	deleteRequest.RecordId = requests.NewInteger64(recordID)
+	if _, err := p.client.DeleteZoneRecord(deleteRequest); err != nil {
+		return fmt.Errorf("failed to delete private zone record %d: %w", recordID, err)
+	}
+	return nil
+}
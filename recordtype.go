@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// 地址类记录的值来自 IP 检测，其余类型使用配置的 Value
+func isAddressType(recordType string) bool {
+	return recordType == "A" || recordType == "AAAA"
+}
+
+// 按记录类型检查记录值
+func validateRecordValue(rec RecordConfig, value string) error {
+	switch rec.RecordType {
+	case "A", "AAAA":
+		// A 记录只能填 IPv4 地址，AAAA 记录只能填 IPv6 地址，提前报错，不把服务商含糊的错误抛给用户
+		family := familyOf(rec.RecordType)
+		if err := checkFamily(value, family); err != nil {
+			return fmt.Errorf("%s records only accept IPv%d addresses: %w", rec.RecordType, family, err)
+		}
+		return nil
+	case "CNAME", "NS":
+		return validateHostname(value)
+	case "MX":
+		if rec.Priority < 0 || rec.Priority > 65535 {
+			return fmt.Errorf("invalid MX priority %d", rec.Priority)
+		}
+		return validateHostname(value)
+	case "SRV":
+		_, err := parseSRV(value)
+		return err
+	case "TXT":
+		if value == "" {
+			return fmt.Errorf("TXT record requires Value")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported record type %q", rec.RecordType)
+	}
+}
+
+// 检查主机名格式，CNAME、MX 等记录的值不能是 IP 地址
+func validateHostname(name string) error {
+	if name == "" {
+		return fmt.Errorf("record requires Value")
+	}
+	if _, err := netip.ParseAddr(name); err == nil {
+		return fmt.Errorf("%q is an IP address, not a hostname", name)
+	}
+
+	host := strings.TrimSuffix(name, ".")
+	if len(host) > 253 {
+		return fmt.Errorf("hostname %q is too long", name)
+	}
+	for _, label := range strings.Split(host, ".") {
+		if label == "" || len(label) > 63 {
+			return fmt.Errorf("invalid hostname %q", name)
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return fmt.Errorf("invalid character %q in hostname %q", c, name)
+			}
+		}
+	}
+	return nil
+}
+
+// SRV 记录的值，格式为 "优先级 权重 端口 目标地址"，例如 "0 5 5060 sip.example.com"
+type srvValue struct {
+	Priority int
+	Weight   int
+	Port     int
+	Target   string
+}
+
+func parseSRV(value string) (srvValue, error) {
+	var srv srvValue
+	fields := strings.Fields(value)
+	if len(fields) != 4 {
+		return srv, fmt.Errorf("SRV value %q must be \"priority weight port target\"", value)
+	}
+
+	nums := make([]int, 3)
+	for i := range nums {
+		n, err := strconv.Atoi(fields[i])
+		if err != nil || n < 0 || n > 65535 {
+			return srv, fmt.Errorf("invalid number %q in SRV value %q", fields[i], value)
+		}
+		nums[i] = n
+	}
+
+	srv = srvValue{Priority: nums[0], Weight: nums[1], Port: nums[2], Target: fields[3]}
+	if err := validateHostname(srv.Target); err != nil {
+		return srv, err
+	}
+	return srv, nil
+}
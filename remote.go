@@ -0,0 +1,243 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 远程配置：-c 可以是 http(s):// 地址（包括预签名的 S3/OSS 地址）、s3://bucket/key 或 oss://bucket/key，
+// 方便集中管理多台设备的配置。常驻模式下按 ETag 定期检查，内容变化后重新加载
+const remoteConfigPollInterval = time.Minute
+
+// 上次下载的远程配置，按 ETag 判断是否变化
+type remoteConfig struct {
+	etag string
+	data []byte
+}
+
+var (
+	remoteConfigMu sync.Mutex
+	remoteConfigs  = make(map[string]*remoteConfig)
+)
+
+func isRemoteConfig(name string) bool {
+	for _, prefix := range []string{"http://", "https://", "s3://", "oss://"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// 读取本地或远程的配置文件
+func readConfigSource(name string) ([]byte, error) {
+	if !isRemoteConfig(name) {
+		return ioutil.ReadFile(name)
+	}
+	data, _, err := fetchRemoteConfig(name)
+	return data, err
+}
+
+// 用于按扩展名识别格式的名称，远程配置去掉查询参数
+func configFormatName(name string) string {
+	if !isRemoteConfig(name) {
+		return name
+	}
+	if u, err := url.Parse(name); err == nil {
+		return u.Path
+	}
+	return name
+}
+
+// 下载远程配置，带上次的 ETag 请求，未变化（304）时使用缓存的内容。返回内容是否变化
+func fetchRemoteConfig(name string) ([]byte, bool, error) {
+	remoteConfigMu.Lock()
+	cached := remoteConfigs[name]
+	remoteConfigMu.Unlock()
+
+	req, err := newRemoteConfigRequest(name, cached)
+	if err != nil {
+		return nil, false, err
+	}
+
+	client := outboundClient()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		return cached.data, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("failed to download %s: status %d", name, resp.StatusCode)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, err
+	}
+
+	remoteConfigMu.Lock()
+	remoteConfigs[name] = &remoteConfig{etag: resp.Header.Get("ETag"), data: data}
+	remoteConfigMu.Unlock()
+	return data, cached == nil || string(cached.data) != string(data), nil
+}
+
+// 按地址的协议创建请求，S3 和 OSS 对象用各自的签名方式访问
+func newRemoteConfigRequest(name string, cached *remoteConfig) (*http.Request, error) {
+	u, err := url.Parse(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config URL: %w", err)
+	}
+
+	var req *http.Request
+	switch u.Scheme {
+	case "http", "https":
+		req, err = http.NewRequest("GET", name, nil)
+	case "oss":
+		req, err = newOSSRequest(u.Host, strings.TrimPrefix(u.Path, "/"))
+	case "s3":
+		req, err = newS3Request(u.Host, strings.TrimPrefix(u.Path, "/"))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cached != nil && cached.etag != "" {
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+	return req, nil
+}
+
+// 阿里云 OSS 对象，使用阿里云凭据环境变量和 OSS V1 签名。
+// Endpoint 默认为杭州，其他地域用 ALIDDNS_OSS_ENDPOINT 指定，例如 oss-cn-shanghai.aliyuncs.com
+func newOSSRequest(bucket, key string) (*http.Request, error) {
+	id, secret := os.Getenv(envPrefix+"ACCESS_KEY_ID"), os.Getenv(envPrefix+"ACCESS_KEY_SECRET")
+	if id == "" || secret == "" {
+		return nil, fmt.Errorf("oss:// config requires %sACCESS_KEY_ID and %sACCESS_KEY_SECRET", envPrefix, envPrefix)
+	}
+	endpoint := os.Getenv(envPrefix + "OSS_ENDPOINT")
+	if endpoint == "" {
+		endpoint = "oss-cn-hangzhou.aliyuncs.com"
+	}
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("https://%s.%s/%s", bucket, endpoint, key), nil)
+	if err != nil {
+		return nil, err
+	}
+	date := time.Now().UTC().Format(http.TimeFormat)
+	req.Header.Set("Date", date)
+
+	// 待签名字符串：方法、Content-MD5、Content-Type、日期、x-oss- 头和资源路径
+	ossHeaders := ""
+	if token := os.Getenv(envPrefix + "SECURITY_TOKEN"); token != "" {
+		req.Header.Set("x-oss-security-token", token)
+		ossHeaders = "x-oss-security-token:" + token + "\n"
+	}
+	stringToSign := "GET\n\n\n" + date + "\n" + ossHeaders + "/" + bucket + "/" + key
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write([]byte(stringToSign))
+	req.Header.Set("Authorization", "OSS "+id+":"+base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	return req, nil
+}
+
+// AWS S3 对象，使用 AWS 的凭据环境变量和 SigV4 签名。
+// 设置 AWS_ENDPOINT_URL 时访问兼容 S3 的服务（例如 MinIO），使用路径形式的地址
+func newS3Request(bucket, key string) (*http.Request, error) {
+	id, secret := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if id == "" || secret == "" {
+		return nil, fmt.Errorf("s3:// config requires AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	path := "/" + awsURIEncode(key)
+	endpoint := fmt.Sprintf("https://%s.s3.%s.amazonaws.com", bucket, region)
+	if custom := os.Getenv("AWS_ENDPOINT_URL"); custom != "" {
+		endpoint = strings.TrimSuffix(custom, "/")
+		path = "/" + awsURIEncode(bucket) + path
+	}
+	req, err := http.NewRequest("GET", endpoint+path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", "UNSIGNED-PAYLOAD")
+	headers := "host:" + req.URL.Host + "\nx-amz-content-sha256:UNSIGNED-PAYLOAD\nx-amz-date:" + amzDate + "\n"
+	signed := "host;x-amz-content-sha256;x-amz-date"
+	if token := os.Getenv("AWS_SESSION_TOKEN"); token != "" {
+		req.Header.Set("x-amz-security-token", token)
+		headers += "x-amz-security-token:" + token + "\n"
+		signed += ";x-amz-security-token"
+	}
+
+	canonical := strings.Join([]string{"GET", path, "", headers, signed, "UNSIGNED-PAYLOAD"}, "\n")
+	scope := day + "/" + region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonical))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	signingKey := hmacSHA256([]byte("AWS4"+secret), day)
+	signingKey = hmacSHA256(signingKey, region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", id, scope, signed, signature))
+	return req, nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// 按 SigV4 的规则编码路径，除不保留字符和 / 外都编码
+func awsURIEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || strings.IndexByte("-_.~/", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// 常驻模式下定期检查远程配置，内容变化时发送重新加载信号
+func pollRemoteConfig(name string, reload chan<- struct{}) {
+	ticker := time.NewTicker(remoteConfigPollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		_, changed, err := fetchRemoteConfig(name)
+		if err != nil {
+			slog.Warn("Failed to check remote config", "error", err)
+			continue
+		}
+		if changed {
+			select {
+			case reload <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// 用指定的 DNS 服务器解析出站连接的域名（服务商接口、IP 查询地址和代理），
+// 本机的 DNS 不可用时（这往往正是地址没有及时更新的原因）也能连上服务商。
+// 服务器可以是普通 DNS 服务器（默认 53 端口），也可以是 DoH 地址，例如 https://223.5.5.5/dns-query
+type customResolver struct {
+	servers []string
+	client  *http.Client // 查询 DoH，DoH 地址是域名时用本机 DNS 解析
+}
+
+// tlsConfig 为查询 DoH 使用的 TLS 设置，为 nil 时使用默认设置
+func newCustomResolver(servers []string, tlsConfig *tls.Config) (*customResolver, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	r := &customResolver{client: &http.Client{Transport: transport}}
+	for _, s := range servers {
+		s = strings.TrimSpace(s)
+		if strings.HasPrefix(s, "https://") {
+			if u, err := url.Parse(s); err != nil || u.Host == "" {
+				return nil, fmt.Errorf("invalid DoH URL %q", s)
+			}
+			r.servers = append(r.servers, s)
+			continue
+		}
+		addr := resolverAddrs([]string{s})[0]
+		if _, err := netip.ParseAddrPort(addr); err != nil {
+			return nil, fmt.Errorf("invalid DNS server %q, use an IP address or a https:// DoH URL", s)
+		}
+		r.servers = append(r.servers, addr)
+	}
+	return r, nil
+}
+
+// 依次向各个服务器查询域名的地址，返回第一个成功的结果
+func (r *customResolver) lookup(ctx context.Context, host string) ([]netip.Addr, error) {
+	var lastErr error
+	for _, s := range r.servers {
+		var addrs []netip.Addr
+		var err error
+		if strings.HasPrefix(s, "https://") {
+			addrs, err = r.lookupDoH(ctx, s, host)
+		} else {
+			addrs, err = resolverFor(s).LookupNetIP(ctx, "ip", host)
+		}
+		if err == nil && len(addrs) == 0 {
+			err = fmt.Errorf("no addresses returned by %s", s)
+		}
+		if err == nil {
+			return addrs, nil
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("failed to resolve %s with %s: %w", host, strings.Join(r.servers, ", "), lastErr)
+}
+
+// 通过 DoH（RFC 8484）查询 A 和 AAAA 记录
+func (r *customResolver) lookupDoH(ctx context.Context, server, host string) ([]netip.Addr, error) {
+	name, err := dnsmessage.NewName(strings.TrimSuffix(host, ".") + ".")
+	if err != nil {
+		return nil, fmt.Errorf("invalid host name %q: %w", host, err)
+	}
+
+	var addrs []netip.Addr
+	for _, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		msg := dnsmessage.Message{
+			Header:    dnsmessage.Header{RecursionDesired: true},
+			Questions: []dnsmessage.Question{{Name: name, Type: qtype, Class: dnsmessage.ClassINET}},
+		}
+		query, err := msg.Pack()
+		if err != nil {
+			return nil, fmt.Errorf("failed to build DNS query: %w", err)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, server, bytes.NewReader(query))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/dns-message")
+		req.Header.Set("Accept", "application/dns-message")
+
+		resp, err := r.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query %s: %w", server, err)
+		}
+		data, err := ioutil.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read DoH response: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to query %s: %w", server, &httpStatusError{code: resp.StatusCode})
+		}
+
+		var answer dnsmessage.Message
+		if err := answer.Unpack(data); err != nil {
+			return nil, fmt.Errorf("failed to decode DoH response: %w", err)
+		}
+		if answer.RCode != dnsmessage.RCodeSuccess {
+			return nil, fmt.Errorf("%s returned %s for %s", server, answer.RCode, host)
+		}
+		for _, a := range answer.Answers {
+			switch b := a.Body.(type) {
+			case *dnsmessage.AResource:
+				addrs = append(addrs, netip.AddrFrom4(b.A))
+			case *dnsmessage.AAAAResource:
+				addrs = append(addrs, netip.AddrFrom16(b.AAAA))
+			}
+		}
+	}
+	return addrs, nil
+}
+
+// 替代 http.Transport 默认的 DialContext：域名用指定的服务器解析，再依次连接解析到的地址。
+// network 为 tcp4/tcp6 时只连接对应地址族的地址
+func (r *customResolver) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	d := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := netip.ParseAddr(host); err == nil {
+		return d.DialContext(ctx, network, addr)
+	}
+
+	addrs, err := r.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	lastErr := fmt.Errorf("no address of %s matches %s", host, network)
+	for _, a := range addrs {
+		a = a.Unmap()
+		if network == "tcp4" && !a.Is4() || network == "tcp6" && !a.Is6() {
+			continue
+		}
+		conn, err := d.DialContext(ctx, network, net.JoinHostPort(a.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
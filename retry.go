@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 失败重试配置：网络错误、超时和服务端 5xx 错误按指数退避重试，其余错误（例如凭据无效）不重试
+type RetryConfig struct {
+	Attempts   int    `json:"Attempts"`   // 最多尝试的次数（含第一次），默认 3，1 表示不重试
+	Backoff    string `json:"Backoff"`    // 第一次重试前等待的时间，之后每次加倍，默认 "1s"
+	MaxBackoff string `json:"MaxBackoff"` // 等待时间的上限，默认 "30s"
+}
+
+// 默认的重试次数和等待时间
+const (
+	defaultRetryAttempts   = 3
+	defaultRetryBackoff    = time.Second
+	defaultRetryMaxBackoff = 30 * time.Second
+)
+
+type retryPolicy struct {
+	attempts   int
+	backoff    time.Duration
+	maxBackoff time.Duration
+}
+
+// 重试策略，配置已经检查过，无效的值按默认处理
+func (c Config) retryPolicy() retryPolicy {
+	p := retryPolicy{attempts: defaultRetryAttempts, backoff: defaultRetryBackoff, maxBackoff: defaultRetryMaxBackoff}
+	if c.Retry == nil {
+		return p
+	}
+	if c.Retry.Attempts > 0 {
+		p.attempts = c.Retry.Attempts
+	}
+	if d, err := time.ParseDuration(c.Retry.Backoff); err == nil && d > 0 {
+		p.backoff = d
+	}
+	if d, err := time.ParseDuration(c.Retry.MaxBackoff); err == nil && d > 0 {
+		p.maxBackoff = d
+	}
+	return p
+}
+
+// 第 n 次失败后的等待时间：backoff * 2^(n-1)，不超过上限，再随机取后一半，
+// 避免多个实例同时重试
+func (p retryPolicy) delay(n int) time.Duration {
+	d := p.backoff
+	for i := 1; i < n && d < p.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.maxBackoff {
+		d = p.maxBackoff
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// 执行 fn，遇到临时错误时等待后重试，ctx 结束后不再重试
+func (p retryPolicy) do(ctx context.Context, operation string, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.attempts || !isTransient(err) {
+			return err
+		}
+		d := p.delay(attempt)
+		slog.Warn("Transient error, retrying", "operation", operation, "attempt", attempt, "delay", d, "error", err)
+		if !sleepContext(ctx, d) {
+			return err
+		}
+	}
+}
+
+// 等待 d，ctx 先结束时返回 false
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// HTTP 接口返回了非 200 的状态码
+type httpStatusError struct {
+	code int
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("HTTP status %d", e.code)
+}
+
+// 服务端暂时不可用的状态码，值得重试
+func transientStatus(code int) bool {
+	return code >= 500 || code == http.StatusTooManyRequests
+}
+
+// 判断错误是否是临时的：连接失败、超时、连接被提前关闭和 5xx 状态码。
+// 整次检查被取消、域名不存在等错误重试也不会成功
+func isTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		return transientStatus(statusErr.code)
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// 判断服务商的响应是否表示被限流，返回服务商要求的等待时间（没有要求时为 0）
+type throttleFunc func(resp *http.Response, body []byte) (bool, time.Duration)
+
+// 各服务商识别限流响应的方法
+var throttleFuncs = map[string]throttleFunc{
+	"aliyun":     aliyunThrottled,
+	"pvtz":       aliyunThrottled,
+	"cloudflare": cloudflareThrottled,
+}
+
+// 响应头 Retry-After 要求的等待时间，可以是秒数或 HTTP 日期，没有时为 0
+func retryAfter(resp *http.Response) time.Duration {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// 会新建记录的请求：Cloudflare 的 POST .../dns_records 和阿里云的 Add* 接口（AddDomainRecord、AddZoneRecord）。
+// 请求可能已经送达时重试会产生重复的记录
+func createsRecord(req *http.Request) bool {
+	if req.Method != http.MethodPost {
+		return false
+	}
+	return strings.HasSuffix(req.URL.Path, "/dns_records") || strings.HasPrefix(req.URL.Query().Get("Action"), "Add")
+}
+
+// 请求还没有发出就失败了：解析域名、建立连接（包括连接代理）或 TLS 握手超时
+func failedBeforeSend(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && (opErr.Op == "dial" || opErr.Op == "proxyconnect") {
+		return true
+	}
+	return strings.Contains(err.Error(), "TLS handshake timeout")
+}
+
+// 服务商接口请求遇到临时错误时重试。放在 contextTransport 外层，每次尝试各有 RequestTimeout，
+// 整次检查的 ctx 结束后不再重试。请求体不能重新读取时不重试；新建记录的请求只在确定没有送达
+// （连接失败或被限流）时重试，超时等结果不明的错误不重试
+type retryTransport struct {
+	base      http.RoundTripper
+	ctx       context.Context
+	policy    retryPolicy
+	provider  string
+	throttled throttleFunc // 为 nil 时只按状态码判断
+}
+
+// 读出错误响应的内容交给 throttled 判断是否被限流，响应体替换为读出的内容
+func (t *retryTransport) checkThrottled(resp *http.Response) (bool, time.Duration) {
+	if t.throttled == nil || resp.StatusCode < 400 {
+		return false, 0
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false, 0
+	}
+	return t.throttled(resp, body)
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	create := createsRecord(req)
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(req)
+		var throttled bool
+		var wait time.Duration
+		if err == nil {
+			throttled, wait = t.checkThrottled(resp)
+		}
+		retry := throttled || isTransient(err) || err == nil && transientStatus(resp.StatusCode)
+		if create && !throttled {
+			retry = err != nil && failedBeforeSend(err) && isTransient(err) || err == nil && resp.StatusCode == http.StatusTooManyRequests
+		}
+		if !retry || !replayable || attempt >= t.policy.attempts || t.ctx.Err() != nil {
+			return resp, err
+		}
+
+		// 被限流时至少等待服务商要求的时间，等不到就在整次检查超时前放弃
+		d := t.policy.delay(attempt)
+		if wait > d {
+			d = wait
+		}
+		if deadline, ok := t.ctx.Deadline(); ok && time.Until(deadline) < d {
+			return resp, err
+		}
+		if err == nil {
+			err = &httpStatusError{code: resp.StatusCode}
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if throttled {
+			slog.Warn("Rate limited by the provider API, retrying", "provider", t.provider, "path", req.URL.Path, "attempt", attempt, "delay", d)
+		} else {
+			slog.Warn("Transient error, retrying", "provider", t.provider, "path", req.URL.Path, "attempt", attempt, "delay", d, "error", err)
+		}
+		if !sleepContext(t.ctx, d) {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestCreatesRecord(t *testing.T) {
+	tests := []struct {
+		method string
+		url    string
+		want   bool
+	}{
+		{"POST", "https://api.cloudflare.com/client/v4/zones/z1/dns_records", true},
+		{"PATCH", "https://api.cloudflare.com/client/v4/zones/z1/dns_records/r1", false},
+		{"PUT", "https://api.cloudflare.com/client/v4/zones/z1/dns_records/r1", false},
+		{"POST", "https://api.cloudflare.com/client/v4/zones/z1/dns_records/batch", false},
+		{"GET", "https://api.cloudflare.com/client/v4/zones/z1/dns_records", false},
+		{"POST", "https://alidns.aliyuncs.com/?Action=AddDomainRecord&DomainName=example.com", true},
+		{"POST", "https://alidns.aliyuncs.com/?Action=UpdateDomainRecord&RecordId=1", false},
+		{"GET", "https://alidns.aliyuncs.com/?Action=AddDomainRecord", false},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(tt.method, tt.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := createsRecord(req); got != tt.want {
+			t.Errorf("createsRecord(%s %s) = %v, want %v", tt.method, tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestFailedBeforeSend(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"dns", &net.DNSError{Err: "no such host", Name: "alidns.aliyuncs.com"}, true},
+		{"dial", refused, true},
+		{"dial in url.Error", &url.Error{Op: "Post", URL: "https://alidns.aliyuncs.com/", Err: refused}, true},
+		{"proxy", &net.OpError{Op: "proxyconnect", Net: "tcp", Err: errors.New("connection refused")}, true},
+		{"tls handshake", fmt.Errorf("failed: %w", errors.New("net/http: TLS handshake timeout")), true},
+		{"read", &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}, false},
+		{"timeout", context.DeadlineExceeded, false},
+		{"response timeout", errors.New("net/http: timeout awaiting response headers"), false},
+	}
+	for _, tt := range tests {
+		if got := failedBeforeSend(tt.err); got != tt.want {
+			t.Errorf("failedBeforeSend(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// 常驻模式下启动 HTTP 服务，提供 /metrics、/healthz 和 /readyz。先监听端口，地址被占用等错误直接返回
+func startHTTPServer(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics)
+	mux.HandleFunc("/healthz", health.serveHealthz)
+	mux.HandleFunc("/readyz", health.serveReadyz)
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(ln); err != nil {
+			slog.Error("HTTP server stopped", "error", err)
+		}
+	}()
+	slog.Info("HTTP server listening", "address", ln.Addr().String())
+	return nil
+}
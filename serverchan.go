@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Server酱³ 的 SendKey 以 sctp 开头，其中包含用户 ID，使用单独的接口地址
+var serverChan3Key = regexp.MustCompile(`^sctp(\d+)t`)
+
+// Server酱推送，通过微信服务号发送到个人微信，Key 为 SendKey
+type serverChanNotifier struct {
+	key string
+}
+
+func newServerChanNotifier(c NotifyConfig) (*serverChanNotifier, error) {
+	if c.Key == "" {
+		return nil, fmt.Errorf("serverchan notification requires Key")
+	}
+	return &serverChanNotifier{key: c.Key}, nil
+}
+
+func (n *serverChanNotifier) notify(e notifyEvent) error {
+	title, text := notifyMessage(e)
+	endpoint := "https://sctapi.ftqq.com/" + n.key + ".send"
+	if m := serverChan3Key.FindStringSubmatch(n.key); m != nil {
+		endpoint = fmt.Sprintf("https://%s.push.ft07.com/send/%s.send", m[1], n.key)
+	}
+	// 正文为 Markdown，每行单独成段
+	form := url.Values{
+		"title": {"aliDDNS: " + title},
+		"desp":  {strings.ReplaceAll(text, "\n", "\n\n")},
+	}
+
+	client := outboundClient()
+	resp, err := client.PostForm(endpoint, form)
+	if err != nil {
+		return hideURL(err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("status %d: failed to decode response: %w", resp.StatusCode, err)
+	}
+	if result.Code != 0 {
+		return fmt.Errorf("code %d: %s", result.Code, result.Message)
+	}
+	return nil
+}
+
+func (n *serverChanNotifier) String() string {
+	return "serverchan"
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/dysmsapi"
+)
+
+// 短信只用于失败告警：记录连续多次无法更新时发送，地址变化不发短信（On 中的 change 不生效）。
+// Provider 为 aliyun（默认，阿里云短信服务）或 twilio，To 为接收的手机号
+type smsNotifier struct {
+	provider string
+	to       []string
+
+	// 阿里云短信的签名和模板，凭据默认与阿里云 DNS 相同
+	signName     string
+	templateCode string
+	config       Config
+
+	// Twilio 的 Account SID、Auth Token 和发送号码
+	accountSID string
+	authToken  string
+	from       string
+}
+
+func newSMSNotifier(c NotifyConfig, config Config) (*smsNotifier, error) {
+	if len(c.To) == 0 {
+		return nil, fmt.Errorf("sms notification requires To")
+	}
+	n := &smsNotifier{provider: strings.ToLower(c.Provider), to: c.To}
+	switch n.provider {
+	case "", "aliyun":
+		n.provider = "aliyun"
+		if c.SignName == "" || c.TemplateCode == "" {
+			return nil, fmt.Errorf("aliyun sms notification requires SignName and TemplateCode")
+		}
+		n.signName, n.templateCode = c.SignName, c.TemplateCode
+		// 单独填写了 AccessKey 时使用单独的凭据
+		n.config = config
+		if c.AccessKeyID != "" {
+			n.config = Config{AccessKeyID: c.AccessKeyID, AccessKeySecret: c.AccessKeySecret}
+		}
+	case "twilio":
+		if c.Username == "" || c.Token == "" || c.From == "" {
+			return nil, fmt.Errorf("twilio sms notification requires Username (Account SID), Token (Auth Token) and From")
+		}
+		n.accountSID, n.authToken, n.from = c.Username, c.Token, c.From
+	default:
+		return nil, fmt.Errorf("unknown sms Provider %q, use aliyun or twilio", c.Provider)
+	}
+	return n, nil
+}
+
+func (n *smsNotifier) notify(e notifyEvent) error {
+	if e.Error == "" {
+		return nil
+	}
+	if n.provider == "twilio" {
+		return n.sendTwilio(e)
+	}
+	return n.sendAliyun(e)
+}
+
+// 阿里云短信只能使用审核通过的模板，模板中可以用 ${failures}（连续失败次数）和 ${error}（错误信息的开头）
+func (n *smsNotifier) sendAliyun(e notifyEvent) error {
+	credential, err := aliyunCredential(n.config)
+	if err != nil {
+		return err
+	}
+	sdkConfig := sdk.NewConfig()
+	sdkConfig.Transport = outboundClient().Transport
+	client, err := dysmsapi.NewClientWithOptions("cn-hangzhou", sdkConfig, credential)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+
+	// 模板变量的长度有限制，错误信息只保留开头
+	errText := []rune(e.Error)
+	if len(errText) > 35 {
+		errText = errText[:35]
+	}
+	param, err := json.Marshal(map[string]string{"failures": strconv.Itoa(e.Failures), "error": string(errText)})
+	if err != nil {
+		return err
+	}
+
+	request := dysmsapi.CreateSendSmsRequest()
+	request.Scheme = "https"
+	request.PhoneNumbers = strings.Join(n.to, ",")
+	request.SignName = n.signName
+	request.TemplateCode = n.templateCode
+	request.TemplateParam = string(param)
+	response, err := client.SendSms(request)
+	if err != nil {
+		return err
+	}
+	if response.Code != "OK" {
+		return fmt.Errorf("%s: %s", response.Code, response.Message)
+	}
+	return nil
+}
+
+// Twilio 按号码逐个发送，正文为标题和错误信息
+func (n *smsNotifier) sendTwilio(e notifyEvent) error {
+	title, text := notifyMessage(e)
+	body := []rune("aliDDNS: " + title + "\n" + text)
+	if len(body) > 300 {
+		body = body[:300]
+	}
+
+	endpoint := "https://api.twilio.com/2010-04-01/Accounts/" + url.PathEscape(n.accountSID) + "/Messages.json"
+	client := outboundClient()
+	for _, to := range n.to {
+		form := url.Values{"To": {to}, "From": {n.from}, "Body": {string(body)}}
+		req, err := http.NewRequest("POST", endpoint, strings.NewReader(form.Encode()))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(n.accountSID, n.authToken)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		var result struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusCreated {
+			return fmt.Errorf("failed to send to %s: status %d: %s", to, resp.StatusCode, result.Message)
+		}
+	}
+	return nil
+}
+
+func (n *smsNotifier) String() string {
+	return "sms " + n.provider
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 通过 SMTP 发送邮件通知
+type smtpNotifier struct {
+	host     string
+	port     int
+	security string
+	username string
+	password string
+	from     string
+	to       []string
+}
+
+func newSMTPNotifier(c NotifyConfig) (*smtpNotifier, error) {
+	n := &smtpNotifier{
+		host:     c.Host,
+		port:     c.Port,
+		security: strings.ToLower(c.Security),
+		username: c.Username,
+		password: c.Password,
+		from:     c.From,
+		to:       c.To,
+	}
+	if n.host == "" || len(n.to) == 0 {
+		return nil, fmt.Errorf("smtp notification requires Host and To")
+	}
+	if n.from == "" {
+		n.from = n.username
+	}
+	if n.from == "" {
+		return nil, fmt.Errorf("smtp notification requires From or Username")
+	}
+
+	defaultPorts := map[string]int{"starttls": 587, "ssl": 465, "none": 25}
+	if n.security == "" {
+		n.security = "starttls"
+		if n.port == 465 {
+			n.security = "ssl"
+		}
+	}
+	def, ok := defaultPorts[n.security]
+	if !ok {
+		return nil, fmt.Errorf("unknown smtp Security %q, use starttls, ssl or none", c.Security)
+	}
+	if n.port == 0 {
+		n.port = def
+	}
+	return n, nil
+}
+
+func (n *smtpNotifier) notify(e notifyEvent) error {
+	title, text := notifyMessage(e)
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", n.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", "aliDDNS: "+title))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(text, "\n", "\r\n") + "\r\n")
+
+	client, err := n.dial()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if n.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.username, n.password, n.host)); err != nil {
+			return fmt.Errorf("failed to log in: %w", err)
+		}
+	}
+	if err := client.Mail(n.from); err != nil {
+		return err
+	}
+	for _, to := range n.to {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("recipient %s rejected: %w", to, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg.Bytes()); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// 连接服务器：ssl 直接建立 TLS 连接，starttls 连接后升级为 TLS，none 不加密
+func (n *smtpNotifier) dial() (*smtp.Client, error) {
+	addr := net.JoinHostPort(n.host, strconv.Itoa(n.port))
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	tlsConfig := &tls.Config{ServerName: n.host}
+
+	var conn net.Conn
+	var err error
+	if n.security == "ssl" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(time.Minute))
+
+	client, err := smtp.NewClient(conn, n.host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if n.security == "starttls" {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("STARTTLS failed: %w", err)
+		}
+	}
+	return client, nil
+}
+
+func (n *smtpNotifier) String() string {
+	return "smtp " + n.host
+}
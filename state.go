@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// 运行状态，配置了 StateFile 时保存到文件，跨多次运行保留
+type State struct {
+	Records map[string]*RecordState `json:"Records"`
+
+	// 连续失败的次数，用于发送失败通知
+	Failures int `json:"Failures,omitempty"`
+
+	// 上次没有错误的检查和上次修改记录的时间，用于判断是否太久没有成功
+	LastSuccess time.Time `json:"LastSuccess"`
+	LastChange  time.Time `json:"LastChange"`
+}
+
+// 单条记录的状态
+type RecordState struct {
+	IP       string    `json:"IP"`       // 上次发布的地址
+	Updated  time.Time `json:"Updated"`  // 上次修改记录的时间
+	Disabled bool      `json:"Disabled"` // 记录已停用，更新时跳过
+
+	// 上次更新后记录经过 Cloudflare 代理，检查是否生效时跳过
+	Proxied bool `json:"Proxied,omitempty"`
+
+	// 缓存的记录 ID，下次可以不查询直接更新
+	ZoneID   string `json:"ZoneID,omitempty"`
+	RecordID string `json:"RecordID,omitempty"`
+
+	// 没能发布的新值，发布成功后清除，见 pending.go
+	Pending *PendingUpdate `json:"Pending,omitempty"`
+}
+
+// 读取状态文件，文件不存在时返回空状态
+func loadState(filename string) (*State, error) {
+	state := &State{Records: make(map[string]*RecordState)}
+	if filename == "" {
+		return state, nil
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal state: %w", err)
+	}
+	if state.Records == nil {
+		state.Records = make(map[string]*RecordState)
+	}
+	return state, nil
+}
+
+// 保存状态文件
+func (s *State) save(filename string) error {
+	if filename == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+	if err := writeFileAtomic(filename, data, 0600); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return nil
+}
+
+// 先写临时文件再重命名，避免中途退出留下不完整的文件，读取的程序也不会读到一半的内容
+func writeFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// 获取记录的状态，不存在时创建
+func (s *State) record(key string) *RecordState {
+	rs, ok := s.Records[key]
+	if !ok {
+		rs = &RecordState{}
+		s.Records[key] = rs
+	}
+	return rs
+}
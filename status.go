@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"time"
+)
+
+// 状态输出文件的内容，每次检查后覆盖，供监控脚本和面板读取
+type Status struct {
+	Updated     time.Time      `json:"Updated"`               // 本次检查完成的时间
+	OK          bool           `json:"OK"`                    // 本次检查没有错误
+	IP          string         `json:"IP,omitempty"`          // 全局 IP 来源检测到的 IPv4 地址
+	IPv6        string         `json:"IPv6,omitempty"`        // 全局 IP 来源检测到的 IPv6 地址
+	LastSuccess *time.Time     `json:"LastSuccess,omitempty"` // 上次没有错误的检查的时间
+	LastChange  *time.Time     `json:"LastChange,omitempty"`  // 上次修改记录的时间
+	Stale       bool           `json:"Stale"`                 // 超过 StaleAfter 没有成功的检查
+	LastError   string         `json:"LastError,omitempty"`
+	Failures    int            `json:"Failures"` // 连续失败的次数
+	Records     []RecordStatus `json:"Records"`
+}
+
+// 单条记录的状态
+type RecordStatus struct {
+	Record   string     `json:"Record"` // 完整域名
+	Type     string     `json:"Type"`
+	Provider string     `json:"Provider"`
+	Value    string     `json:"Value,omitempty"`   // 上次发布的值
+	Changed  *time.Time `json:"Changed,omitempty"` // 上次修改记录的时间，需要配置 StateFile
+	Disabled bool       `json:"Disabled,omitempty"`
+	Error    string     `json:"Error,omitempty"` // 本次检查中这条记录的错误
+
+	Pending *PendingUpdate `json:"Pending,omitempty"` // 没能发布的新值
+
+	changed  bool   // 本次检查修改了记录
+	previous string // 修改前的值
+}
+
+// 从记录的配置和状态生成记录状态，rs 可以为空
+func newRecordStatus(rec RecordConfig, rs *RecordState, errMsg string) RecordStatus {
+	s := RecordStatus{Record: rec.fqdn(), Type: rec.RecordType, Provider: rec.Provider, Error: errMsg}
+	if rs != nil {
+		s.Value = rs.IP
+		s.Disabled = rs.Disabled
+		s.Pending = rs.Pending
+		if !rs.Updated.IsZero() {
+			changed := rs.Updated
+			s.Changed = &changed
+		}
+	}
+	return s
+}
+
+// 写入状态输出文件。没有状态文件时，失败后保留文件中上次成功的时间，只运行一次的模式下也能看到
+func (s *Status) save(filename string, staleAfter time.Duration) error {
+	if filename == "" {
+		return nil
+	}
+	if s.OK {
+		updated := s.Updated
+		s.LastSuccess = &updated
+	} else if s.LastSuccess == nil {
+		if prev, err := readStatus(filename); err == nil {
+			s.LastSuccess = prev.LastSuccess
+			if s.LastChange == nil {
+				s.LastChange = prev.LastChange
+			}
+		}
+	}
+	s.Stale = s.LastSuccess == nil || s.Updated.Sub(*s.LastSuccess) > staleAfter
+	if s.Records == nil {
+		s.Records = []RecordStatus{}
+	}
+
+	data, err := json.MarshalIndent(s, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal status: %w", err)
+	}
+	if err := writeFileAtomic(filename, data, 0644); err != nil {
+		return fmt.Errorf("failed to write status file: %w", err)
+	}
+	return nil
+}
+
+func readStatus(filename string) (*Status, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var s Status
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal status: %w", err)
+	}
+	return &s, nil
+}
+
+// 本次检查是否修改了记录
+func (s *Status) anyChanged() bool {
+	for _, r := range s.Records {
+		if r.changed {
+			return true
+		}
+	}
+	return false
+}
+
+// status 命令的退出码，与 Nagios 插件相同，方便在监控系统中直接使用
+const (
+	statusOK       = 0 // 最近有成功的检查
+	statusWarning  = 1 // 最近一次检查失败，但还没有过期
+	statusCritical = 2 // 超过 StaleAfter 没有成功的检查
+	statusUnknown  = 3 // 没有配置 StatusFile/StateFile 或无法读取
+)
+
+// status 命令的结果，-o json|yaml 时原样输出
+type statusReport struct {
+	Status      string     `json:"Status"` // OK、WARNING、CRITICAL 或 UNKNOWN
+	Message     string     `json:"Message"`
+	LastCheck   *time.Time `json:"LastCheck,omitempty"`
+	LastSuccess *time.Time `json:"LastSuccess,omitempty"`
+	LastChange  *time.Time `json:"LastChange,omitempty"`
+	LastError   string     `json:"LastError,omitempty"`
+	Failures    int        `json:"Failures"`
+	Pending     []string   `json:"Pending,omitempty"` // 没能发布、等待重试的更新
+}
+
+// 显示上次成功的检查和上次修改记录距今多久：aliddns -c config.json status。
+// 读取 StatusFile，没有配置时读取 StateFile，返回退出码
+func runStatus(config Config, format string) int {
+	code, report := checkStatus(config)
+	now := time.Now()
+	ago := func(t *time.Time) string {
+		if t == nil {
+			return tr("never")
+		}
+		return trf("%s (%s ago)", t.Local().Format("2006-01-02 15:04:05"), formatDays(now.Sub(*t)))
+	}
+
+	err := writeFormatted(format, report, func(w io.Writer) error {
+		fmt.Fprintf(w, "%s: %s\n", report.Status, report.Message)
+		if code == statusUnknown {
+			return nil
+		}
+		if report.LastCheck != nil {
+			fmt.Fprint(w, trf("Last check:   %s\n", ago(report.LastCheck)))
+		}
+		fmt.Fprint(w, trf("Last success: %s\n", ago(report.LastSuccess)))
+		fmt.Fprint(w, trf("Last change:  %s\n", ago(report.LastChange)))
+		if report.LastError != "" {
+			fmt.Fprint(w, trf("Last error:   %s\n", report.LastError))
+		}
+		for _, p := range report.Pending {
+			fmt.Fprint(w, trf("Pending:      %s\n", p))
+		}
+		return nil
+	})
+	if err != nil {
+		return statusUnknown
+	}
+	return code
+}
+
+// 判断上次成功的检查是否过期，返回退出码和结果
+func checkStatus(config Config) (int, statusReport) {
+	var lastSuccess, lastChange, lastCheck time.Time
+	var report statusReport
+	switch {
+	case config.StatusFile != "":
+		s, err := readStatus(config.StatusFile)
+		if err != nil {
+			return statusUnknown, statusReport{Status: "UNKNOWN", Message: trf("failed to read status file: %v", err)}
+		}
+		if s.LastSuccess != nil {
+			lastSuccess = *s.LastSuccess
+		}
+		if s.LastChange != nil {
+			lastChange = *s.LastChange
+		}
+		lastCheck, report.LastError, report.Failures = s.Updated, s.LastError, s.Failures
+		for _, r := range s.Records {
+			if r.Pending != nil {
+				report.Pending = append(report.Pending, r.Pending.describe(r.Record+" "+r.Type))
+			}
+		}
+	case config.StateFile != "":
+		s, err := loadState(config.StateFile)
+		if err != nil {
+			return statusUnknown, statusReport{Status: "UNKNOWN", Message: err.Error()}
+		}
+		lastSuccess, lastChange, report.Failures = s.LastSuccess, s.LastChange, s.Failures
+		for key, rs := range s.Records {
+			if rs.Pending != nil {
+				report.Pending = append(report.Pending, rs.Pending.describe(key))
+			}
+		}
+		sort.Strings(report.Pending)
+	default:
+		return statusUnknown, statusReport{Status: "UNKNOWN", Message: tr("StatusFile or StateFile is not configured")}
+	}
+	report.LastCheck, report.LastSuccess, report.LastChange = optionalTime(lastCheck), optionalTime(lastSuccess), optionalTime(lastChange)
+
+	staleAfter := config.staleAfter()
+	switch {
+	case lastSuccess.IsZero() || time.Since(lastSuccess) > staleAfter:
+		report.Status, report.Message = "CRITICAL", trf("no successful check in %s", formatDays(staleAfter))
+		return statusCritical, report
+	case report.Failures > 0:
+		report.Status, report.Message = "WARNING", trf("last %d check(s) failed", report.Failures)
+		return statusWarning, report
+	}
+	report.Status, report.Message = "OK", tr("last check succeeded")
+	return statusOK, report
+}
+
+// 没有的时间在 JSON 中省略
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
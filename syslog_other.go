@@ -0,0 +1,19 @@
+//go:build windows || plan9
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime"
+)
+
+type syslogSink struct{}
+
+func newSyslogSink(addr string) (*syslogSink, error) {
+	return nil, fmt.Errorf("syslog is not supported on %s", runtime.GOOS)
+}
+
+func (s *syslogSink) send(level slog.Level, msg string, attrs []slog.Attr) error {
+	return nil
+}
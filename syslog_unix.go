@@ -0,0 +1,49 @@
+//go:build !windows && !plan9
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"log/syslog"
+	"strings"
+)
+
+// 写入 syslog 的日志
+type syslogSink struct {
+	w *syslog.Writer
+}
+
+// 连接 syslog：local 为本机的 syslog 守护进程，否则为远程地址，
+// 例如 udp://192.168.1.1:514、tcp://logs.example.com:514，省略协议时使用 UDP
+func newSyslogSink(addr string) (*syslogSink, error) {
+	network, raddr := "", ""
+	if addr != "local" {
+		network, raddr = "udp", addr
+		if i := strings.Index(addr, "://"); i >= 0 {
+			network, raddr = addr[:i], addr[i+3:]
+		}
+		if network != "udp" && network != "tcp" {
+			return nil, fmt.Errorf("unsupported syslog protocol %q, use udp or tcp", network)
+		}
+	}
+	w, err := syslog.Dial(network, raddr, syslog.LOG_DAEMON|syslog.LOG_INFO, "aliddns")
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to syslog: %w", err)
+	}
+	return &syslogSink{w: w}, nil
+}
+
+func (s *syslogSink) send(level slog.Level, msg string, attrs []slog.Attr) error {
+	line := formatLogLine(msg, attrs)
+	switch {
+	case level >= slog.LevelError:
+		return s.w.Err(line)
+	case level >= slog.LevelWarn:
+		return s.w.Warning(line)
+	case level >= slog.LevelInfo:
+		return s.w.Info(line)
+	default:
+		return s.w.Debug(line)
+	}
+}
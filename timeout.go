@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// 默认的单次网络请求超时和一次检查的总时限
+const (
+	defaultRequestTimeout = 30 * time.Second
+	defaultRunTimeout     = 5 * time.Minute
+)
+
+// 单次网络请求（查询外网 IP、调用服务商接口）的超时，配置已经检查过，无效的值按默认处理
+func (c Config) requestTimeout() time.Duration {
+	if d, err := time.ParseDuration(c.RequestTimeout); err == nil && d > 0 {
+		return d
+	}
+	return defaultRequestTimeout
+}
+
+// 一次检查（检测地址并更新所有记录）或一个命令的总时限
+func (c Config) runTimeout() time.Duration {
+	if d, err := time.ParseDuration(c.RunTimeout); err == nil && d > 0 {
+		return d
+	}
+	return defaultRunTimeout
+}
+
+// 一次检查或一个命令使用的 context，超过 RunTimeout 后所有未完成的请求都被取消
+func (c Config) runContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), c.runTimeout())
+}
+
+// 给服务商接口的每个请求加上超时，并在整次检查超时后取消。
+// 阿里云 SDK 的请求不能传入 context，在 RoundTripper 中统一设置
+type contextTransport struct {
+	base    http.RoundTripper
+	ctx     context.Context
+	timeout time.Duration
+}
+
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(t.ctx, t.timeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// 读完响应体之前不能取消
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// 关闭响应体时取消请求的 context
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
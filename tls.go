@@ -0,0 +1,64 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+)
+
+// 出站 HTTPS 连接（IP 查询地址、服务商接口、通知和 DoH）的 TLS 设置，
+// 例如公司网络中间人代理的根证书、需要客户端证书的自建服务
+type TLSConfig struct {
+	CAFile     string `json:"CAFile"`     // PEM 格式的 CA 证书，追加到系统的根证书中
+	CertFile   string `json:"CertFile"`   // PEM 格式的客户端证书，与 KeyFile 一起使用
+	KeyFile    string `json:"KeyFile"`    // 客户端证书的私钥
+	MinVersion string `json:"MinVersion"` // 最低的 TLS 版本："1.0"、"1.1"、"1.2"（默认）或 "1.3"
+}
+
+// TLS 版本名称
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// 按配置创建 tls.Config，证书文件每次创建 Transport 时重新读取，更换证书后不需要重启
+func (t *TLSConfig) clientConfig() (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if t.MinVersion != "" {
+		v, ok := tlsVersions[t.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("invalid MinVersion %q, use 1.0, 1.1, 1.2 or 1.3", t.MinVersion)
+		}
+		config.MinVersion = v
+	}
+
+	if t.CAFile != "" {
+		pem, err := ioutil.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CAFile: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in %s", t.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return nil, fmt.Errorf("CertFile and KeyFile must be set together")
+	}
+	if t.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
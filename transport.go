@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// 代理设置为 direct 时不使用代理，也不读取环境变量
+const proxyDirect = "direct"
+
+// 按代理设置返回 http.Transport 的 Proxy 函数，支持 http、https 和 socks5 代理。
+// 留空时使用环境变量 HTTPS_PROXY/HTTP_PROXY，没有设置时使用 ALL_PROXY，NO_PROXY 中的地址直连
+func proxyFunc(setting string) (func(*http.Request) (*url.URL, error), error) {
+	switch setting {
+	case "":
+		env := httpproxy.FromEnvironment()
+		all := os.Getenv("ALL_PROXY")
+		if all == "" {
+			all = os.Getenv("all_proxy")
+		}
+		if env.HTTPProxy == "" {
+			env.HTTPProxy = all
+		}
+		if env.HTTPSProxy == "" {
+			env.HTTPSProxy = all
+		}
+		proxy := env.ProxyFunc()
+		return func(req *http.Request) (*url.URL, error) {
+			return proxy(req.URL)
+		}, nil
+	case proxyDirect:
+		return nil, nil
+	}
+
+	u, err := url.Parse(setting)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy %q, expected a URL like http://127.0.0.1:8080 or socks5://127.0.0.1:1080", setting)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+		return http.ProxyURL(u), nil
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q, use http, https or socks5", u.Scheme)
+	}
+}
+
+// 常驻模式下每次检查访问的是同样几个地址，空闲连接保留得比默认的 90 秒更久，
+// 检查间隔不太长时下次检查可以省去 TCP 和 TLS 握手
+const (
+	idleConnTimeout     = 5 * time.Minute
+	maxIdleConnsPerHost = 4
+)
+
+// 创建出站连接使用的 Transport，proxy 为 IPProxy 或 APIProxy。配置了 DNSServers 时用指定的服务器解析域名，
+// 配置了 TLS 时使用其中的 CA 证书、客户端证书和最低版本
+func (c Config) newTransport(proxy string) (*http.Transport, error) {
+	proxyFn, err := proxyFunc(proxy)
+	if err != nil {
+		return nil, err
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = proxyFn
+	t.IdleConnTimeout = idleConnTimeout
+	t.MaxIdleConnsPerHost = maxIdleConnsPerHost
+	if c.TLS != nil {
+		if t.TLSClientConfig, err = c.TLS.clientConfig(); err != nil {
+			return nil, fmt.Errorf("TLS: %w", err)
+		}
+	}
+	if len(c.DNSServers) > 0 {
+		r, err := newCustomResolver(c.DNSServers, t.TLSClientConfig)
+		if err != nil {
+			return nil, err
+		}
+		t.DialContext = r.dialContext
+	}
+	return t, nil
+}
+
+// 服务商接口的 RoundTripper：记录每个请求的耗时，配置了 AuditLog 时同时写审计日志，
+// User-Agent 中带上版本号。每个请求最多等待 RequestTimeout，ctx 结束后取消所有请求，临时错误按 Retry 配置重试，
+// 通过 APIProxy 配置的代理连接，用 DNSServers 解析域名。常驻模式下所有服务商共用 newUpdater 创建的连接池
+func newProviderTransport(ctx context.Context, config Config, provider string) (http.RoundTripper, error) {
+	transport := config.apiTransport
+	if transport == nil {
+		var err error
+		if transport, err = config.newTransport(config.APIProxy); err != nil {
+			return nil, err
+		}
+	}
+	base := newAuditTransport(config, provider, transport)
+	return &retryTransport{
+		base: &contextTransport{
+			base:    &timedTransport{base: &userAgentTransport{base: base}, provider: provider},
+			ctx:     ctx,
+			timeout: config.requestTimeout(),
+		},
+		ctx:       ctx,
+		policy:    config.retryPolicy(),
+		provider:  provider,
+		throttled: throttleFuncs[provider],
+	}, nil
+}
+
+// 通知、指标推送和远程配置的请求最多等待的时间
+const outboundTimeout = 30 * time.Second
+
+// 通知、指标推送和远程配置共用的 HTTP 客户端。更新器创建成功后换成服务商接口的连接池，
+// 同样使用 APIProxy、TLS 和 DNSServers；此前（例如第一次下载远程配置）使用默认设置和环境变量中的代理
+var sharedClient atomic.Pointer[http.Client]
+
+func outboundClient() *http.Client {
+	if c := sharedClient.Load(); c != nil {
+		return c
+	}
+	return &http.Client{Timeout: outboundTimeout}
+}
+
+// 换用新配置的连接池，重新加载配置后之前的连接池由 closeIdleConnections 关闭
+func setOutboundTransport(t http.RoundTripper) {
+	sharedClient.Store(&http.Client{Transport: t, Timeout: outboundTimeout})
+}
+
+// 限定地址族的 Transport：查询 IPv4 地址时只通过 IPv4 连接，IPv6 同理（使用代理时为连接代理使用的地址族）
+func familyTransport(base *http.Transport, family int) *http.Transport {
+	network := "tcp4"
+	if family == 6 {
+		network = "tcp6"
+	}
+	t := base.Clone()
+	dial := t.DialContext
+	t.DialContext = func(ctx context.Context, _, addr string) (net.Conn, error) {
+		return dial(ctx, network, addr)
+	}
+	return t
+}
+
+// 关闭更新器保留的空闲连接，重新加载配置换用新的更新器后调用
+func (u *updater) closeIdleConnections() {
+	for _, t := range u.transports {
+		t.CloseIdleConnections()
+	}
+	if u.config.apiTransport != nil {
+		u.config.apiTransport.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/term"
+)
+
+// 仪表盘保留的日志行数
+const dashboardLogLines = 200
+
+// 一次检查完成，由常驻循环发送
+type checkMsg struct {
+	status *Status
+	next   time.Time
+}
+
+// 一行日志
+type logMsg string
+
+// 每秒刷新倒计时
+type tickMsg time.Time
+
+// 终端仪表盘：每条记录的状态和上次变化时间、下次检查的倒计时和最近的日志。
+// 检查仍由常驻循环完成，仪表盘只显示结果，按 u 立即检查，按 q 退出
+type dashboard struct {
+	status  *Status
+	next    time.Time
+	running bool // 正在检查
+	logs    []string
+	width   int
+	height  int
+	color   bool
+	trigger chan<- struct{}
+}
+
+func (m *dashboard) Init() tea.Cmd {
+	return tick()
+}
+
+func tick() tea.Cmd {
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg { return tickMsg(t) })
+}
+
+func (m *dashboard) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "ctrl+c", "esc":
+			return m, tea.Quit
+		case "u":
+			select {
+			case m.trigger <- struct{}{}:
+				m.running = true
+			default:
+			}
+		}
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+	case checkMsg:
+		// 检查前就失败时没有新的记录状态，保留上次的结果，错误见日志
+		if msg.status != nil {
+			m.status = msg.status
+		}
+		m.next, m.running = msg.next, false
+	case logMsg:
+		m.logs = append(m.logs, string(msg))
+		if len(m.logs) > dashboardLogLines {
+			m.logs = m.logs[len(m.logs)-dashboardLogLines:]
+		}
+	case tickMsg:
+		return m, tick()
+	}
+	return m, nil
+}
+
+func (m *dashboard) View() string {
+	var b strings.Builder
+
+	// 第一行：检测到的地址、上次检查的结果和下次检查的倒计时
+	fmt.Fprintf(&b, "aliDDNS %s", readBuildInfo().Version)
+	switch {
+	case m.running || m.status == nil:
+		b.WriteString("  " + tr("checking..."))
+	default:
+		if m.status.IP != "" {
+			b.WriteString("  IPv4 " + m.status.IP)
+		}
+		if m.status.IPv6 != "" {
+			b.WriteString("  IPv6 " + m.status.IPv6)
+		}
+		result := m.paint(colorGreen, tr("ok"))
+		if !m.status.OK {
+			result = m.paint(colorRed, trf("failed %d times in a row", m.status.Failures))
+		}
+		b.WriteString("  " + trf("last check %s", m.status.Updated.Local().Format("15:04:05")) + " " + result)
+		if left := time.Until(m.next).Round(time.Second); left > 0 {
+			b.WriteString("  " + trf("next check in %s", left))
+		}
+	}
+	b.WriteString("\n\n")
+
+	lines := 2
+	if m.status != nil {
+		w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, tr("RECORD\tTYPE\tPROVIDER\tVALUE\tLAST CHANGE\tSTATUS"))
+		for _, r := range m.status.Records {
+			changed := "-"
+			if r.Changed != nil {
+				changed = r.Changed.Local().Format("2006-01-02 15:04:05") + " (" + trf("%s ago", formatDays(time.Since(*r.Changed))) + ")"
+			}
+			state := m.paint(colorGreen, tr("ok"))
+			switch {
+			case r.Error != "":
+				state = m.paint(colorRed, r.Error)
+			case r.Disabled:
+				state = m.paint(colorGray, tr("disabled"))
+			case r.changed:
+				state = m.paint(colorGreen, tr("updated"))
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Record, r.Type, r.Provider, r.Value, changed, state)
+		}
+		w.Flush()
+		b.WriteString("\n")
+		lines += len(m.status.Records) + 2
+	}
+
+	// 剩下的空间显示最近的日志，最后一行是按键说明
+	b.WriteString(tr("Recent log:") + "\n")
+	lines += 2
+	logs := m.logs
+	if room := m.height - lines - 1; m.height > 0 && len(logs) > room {
+		if room < 0 {
+			room = 0
+		}
+		logs = logs[len(logs)-room:]
+	}
+	for _, line := range logs {
+		if r := []rune(line); m.width > 0 && len(r) > m.width {
+			line = string(r[:m.width])
+		}
+		b.WriteString(line + "\n")
+	}
+	b.WriteString(m.paint(colorGray, tr("u: update now  q: quit")))
+	return b.String()
+}
+
+func (m *dashboard) paint(color, s string) string {
+	if !m.color {
+		return s
+	}
+	return color + s + colorReset
+}
+
+// 日志写入仪表盘，每次 Write 是一行
+type dashboardLogWriter struct {
+	p *tea.Program
+}
+
+func (w dashboardLogWriter) Write(b []byte) (int, error) {
+	w.p.Send(logMsg(strings.TrimRight(string(b), "\n")))
+	return len(b), nil
+}
+
+// 同时交给多个 Handler，日志写入文件或 syslog 时仪表盘中也能看到
+type teeHandler []slog.Handler
+
+func (t teeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range t {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t teeHandler) Handle(ctx context.Context, r slog.Record) error {
+	for _, h := range t {
+		if h.Enabled(ctx, r.Level) {
+			if err := h.Handle(ctx, r.Clone()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (t teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	hs := make(teeHandler, len(t))
+	for i, h := range t {
+		hs[i] = h.WithAttrs(attrs)
+	}
+	return hs
+}
+
+func (t teeHandler) WithGroup(name string) slog.Handler {
+	hs := make(teeHandler, len(t))
+	for i, h := range t {
+		hs[i] = h.WithGroup(name)
+	}
+	return hs
+}
+
+// 在终端仪表盘中常驻运行：aliddns -c config.json -i 5m tui。适合在 tmux 中运行，
+// 日志显示在仪表盘中；用 -log-file 等写入文件或 syslog 时同时写入原来的位置
+func runTUI(u *updater, interval time.Duration, configFile string, reload func() (*updater, time.Duration, error), o *logOptions) error {
+	if !term.IsTerminal(int(os.Stdout.Fd())) || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("tui requires a terminal, use daemon instead")
+	}
+
+	trigger := make(chan struct{}, 1)
+	m := &dashboard{trigger: trigger, running: true, color: os.Getenv("NO_COLOR") == ""}
+	p := tea.NewProgram(m, tea.WithAltScreen())
+
+	// 时间只显示时分秒，仪表盘一行放得下
+	text := slog.NewTextHandler(dashboardLogWriter{p}, &slog.HandlerOptions{
+		Level: logLevel,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.String(a.Key, a.Value.Time().Format("15:04:05"))
+			}
+			return a
+		},
+	})
+	var handler slog.Handler = translateHandler{text}
+	if o.file != "" || o.syslog != "" || o.journald {
+		handler = teeHandler{slog.Default().Handler(), handler}
+	}
+	slog.SetDefault(slog.New(handler))
+
+	hooks := daemonHooks{
+		checked: func(status *Status, next time.Time) { p.Send(checkMsg{status: status, next: next}) },
+		trigger: trigger,
+	}
+	go runDaemon(u, interval, configFile, reload, hooks)
+
+	_, err := p.Run()
+	return err
+}
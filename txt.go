@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// 设置或清除 TXT 记录，可作为 certbot/lego 等 ACME 客户端 DNS-01 验证的钩子。
+// 服务商和域名使用配置中第一条记录的设置，name 为该域名下的记录名。
+func runTXT(config Config, name, value string, clear bool) error {
+	name, err := toASCIIName(name)
+	if err != nil {
+		return err
+	}
+
+	records := config.records()
+	if len(records) == 0 {
+		return fmt.Errorf("no domain configured")
+	}
+
+	ctx, cancel := config.runContext()
+	defer cancel()
+	p, err := newProvider(ctx, config, records[0].Provider)
+	if err != nil {
+		return err
+	}
+	first, err := resolveZone(p, records[0])
+	if err != nil {
+		return err
+	}
+
+	rec := RecordConfig{
+		Provider:   first.Provider,
+		DomainName: first.DomainName,
+		Record:     providerRecordName(first.Provider, name, first.DomainName),
+		RecordType: "TXT",
+		TTL:        first.TTL,
+	}
+
+	if clear {
+		return p.deleteRecords(rec, value)
+	}
+
+	// 未通过参数指定值时从标准输入读取
+	if value == "" {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("failed to read TXT value from stdin: %w", err)
+		}
+		value = strings.TrimSpace(line)
+	}
+	if value == "" {
+		return fmt.Errorf("TXT value is empty")
+	}
+
+	return p.createRecord(rec, value)
+}
@@ -0,0 +1,473 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 配置文件子命令：aliddns -c config.json config validate [-online] 或 config migrate [-w]
+func runConfigCommand(args []string, filename string, opts loadOptions, overrides *flagOverrides) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: config validate [-online] | config migrate [-w]")
+	}
+
+	switch args[0] {
+	case "validate":
+		fs := flag.NewFlagSet("config validate", flag.ExitOnError)
+		online := fs.Bool("online", false, "Also check credentials and zones against each provider")
+		fs.Parse(args[1:])
+
+		issues := validateConfigFile(filename, opts, overrides, *online)
+		for _, issue := range issues {
+			fmt.Println(issue)
+		}
+		if len(issues) > 0 {
+			return fmt.Errorf("%d problem(s) found in %s", len(issues), filename)
+		}
+		fmt.Print(trf("%s: OK\n", filename))
+		return nil
+	case "migrate":
+		return runMigrate(filename, opts, args[1:])
+	default:
+		return fmt.Errorf("unknown config command %q", args[0])
+	}
+}
+
+// 检查配置文件，返回发现的所有问题。先检查字段名（带行号），
+// 再按运行时的方式加载配置并检查各项取值，online 时还会用配置的凭据访问服务商
+func validateConfigFile(filename string, opts loadOptions, overrides *flagOverrides, online bool) []string {
+	// 配置目录逐个文件检查字段
+	files := []string{filename}
+	if info, err := os.Stat(filename); err == nil && info.IsDir() {
+		var err error
+		if files, err = configDirFiles(filename); err != nil {
+			return []string{err.Error()}
+		}
+	}
+
+	var issues []string
+	for _, file := range files {
+		data, err := readConfigSource(file)
+		if err != nil {
+			return []string{err.Error()}
+		}
+		if isAgeEncrypted(data) {
+			if data, err = decryptConfig(data, opts.age); err != nil {
+				return []string{err.Error()}
+			}
+		}
+		issues = append(issues, checkConfigFields(file, data)...)
+	}
+	// 字段有误时加载配置也会失败，先报告带行号的问题
+	if len(issues) > 0 {
+		return issues
+	}
+
+	config, err := loadConfig(filename, opts)
+	if err == nil {
+		err = config.applyFlags(overrides)
+	}
+	if err != nil {
+		return append(issues, fmt.Sprintf("%s: %v", filename, err))
+	}
+	for _, problem := range config.validate() {
+		issues = append(issues, fmt.Sprintf("%s: %s", filename, problem))
+	}
+	if online && len(issues) == 0 {
+		for _, problem := range config.checkAccess() {
+			issues = append(issues, fmt.Sprintf("%s: %s", filename, problem))
+		}
+	}
+	return issues
+}
+
+// 检查配置文件中的字段名和数值类型，报告所在的行。
+// JSON 也是合法的 YAML，两种格式都用 YAML 解析以得到行号；TOML 先转换为 JSON，不报告行号
+func checkConfigFields(filename string, data []byte) []string {
+	withLines := true
+	if strings.ToLower(filepath.Ext(strings.TrimSuffix(configFormatName(filename), ".age"))) == ".toml" {
+		var err error
+		if data, err = configToJSON(".toml", data); err != nil {
+			return []string{fmt.Sprintf("%s: %v", filename, err)}
+		}
+		withLines = false
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return []string{fmt.Sprintf("%s: %v", filename, err)}
+	}
+
+	var issues []string
+	report := func(line int, format string, args ...interface{}) {
+		msg := fmt.Sprintf(format, args...)
+		if withLines && line > 0 {
+			issues = append(issues, fmt.Sprintf("%s:%d: %s", filename, line, msg))
+		} else {
+			issues = append(issues, fmt.Sprintf("%s: %s", filename, msg))
+		}
+	}
+	checkFields(&doc, reflect.TypeOf(Config{}), "config", report)
+	return issues
+}
+
+// 按结构体的 json 字段名检查节点，字段名和 encoding/json 一样不区分大小写
+func checkFields(node *yaml.Node, t reflect.Type, path string, report func(line int, format string, args ...interface{})) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) > 0 {
+			checkFields(node.Content[0], t, path, report)
+		}
+		return
+	case yaml.AliasNode:
+		checkFields(node.Alias, t, path, report)
+		return
+	}
+	if node.Tag == "!!null" {
+		return
+	}
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			report(node.Line, "%s should be an object", path)
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if t == reflect.TypeOf(Config{}) && key.Value == profilesKey {
+				checkProfiles(value, path, report)
+				continue
+			}
+			field, ok := jsonField(t, key.Value)
+			if !ok {
+				if suggestion := suggestField(t, key.Value); suggestion != "" {
+					report(key.Line, "unknown field %q in %s, did you mean %q?", key.Value, path, suggestion)
+				} else {
+					report(key.Line, "unknown field %q in %s", key.Value, path)
+				}
+				continue
+			}
+			checkFields(value, field.Type, path+"."+field.Tag.Get("json"), report)
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			report(node.Line, "%s should be a list", path)
+			return
+		}
+		for i, item := range node.Content {
+			checkFields(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i), report)
+		}
+	case reflect.Int:
+		if _, err := strconv.Atoi(node.Value); node.Kind != yaml.ScalarNode || node.Tag != "!!int" || err != nil {
+			report(node.Line, "%s should be a number, got %q", path, node.Value)
+		}
+	case reflect.Bool:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!bool" {
+			report(node.Line, "%s should be true or false, got %q", path, node.Value)
+		}
+	case reflect.String:
+		if node.Kind != yaml.ScalarNode {
+			report(node.Line, "%s should be a string", path)
+		}
+	}
+}
+
+// 命名配置中的字段与顶层相同
+func checkProfiles(node *yaml.Node, path string, report func(line int, format string, args ...interface{})) {
+	if node.Kind != yaml.MappingNode {
+		report(node.Line, "%s.%s should be an object", path, profilesKey)
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		name, profile := node.Content[i], node.Content[i+1]
+		checkFields(profile, reflect.TypeOf(Config{}), path+"."+profilesKey+"."+name.Value, report)
+	}
+}
+
+// 找出与拼错的字段名最接近的字段，差别太大时返回空字符串
+func suggestField(t reflect.Type, name string) string {
+	best, bestDistance := "", len(name)/3+2
+	for i := 0; i < t.NumField(); i++ {
+		tag := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if tag == "" || tag == "-" {
+			continue
+		}
+		if d := editDistance(strings.ToLower(name), strings.ToLower(tag)); d < bestDistance {
+			best, bestDistance = tag, d
+		}
+	}
+	if t == reflect.TypeOf(Config{}) && editDistance(strings.ToLower(name), strings.ToLower(profilesKey)) < bestDistance {
+		best = profilesKey
+	}
+	return best
+}
+
+// 两个字符串的编辑距离（Levenshtein）
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// 严格解析 JSON 配置：不认识的字段直接报错并提示最接近的字段名，
+// 避免拼错的字段被忽略后以空凭据调用接口，得到难以理解的错误
+func unmarshalStrict(data []byte, v interface{}, path string) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err == nil {
+		var problems []string
+		checkFields(&doc, reflect.TypeOf(v).Elem(), path, func(_ int, format string, args ...interface{}) {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		})
+		if len(problems) > 0 {
+			return fmt.Errorf("%s", strings.Join(problems, "; "))
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// 按 json 标签查找字段，不区分大小写
+func jsonField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := strings.Split(f.Tag.Get("json"), ",")[0]
+		if tag != "" && tag != "-" && strings.EqualFold(tag, name) {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// 检查配置的取值：凭据、记录类型和值、TTL、地址过滤、IP 来源和时间间隔
+func (c Config) validate() []string {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if _, err := newIPFilter(c.AllowCIDRs, c.DenyCIDRs); err != nil {
+		add("%v", err)
+	}
+	if _, err := newIPSources(c.IPSources, 4, nil); err != nil {
+		add("IPSources: %v", err)
+	}
+	if _, err := newIPSources(c.IPv6Sources, 6, nil); err != nil {
+		add("IPv6Sources: %v", err)
+	}
+	if c.Interval != "" {
+		if d, err := time.ParseDuration(c.Interval); err != nil || d <= 0 {
+			add("invalid Interval %q", c.Interval)
+		}
+	}
+	if c.StaleAfter != "" {
+		if d, err := time.ParseDuration(c.StaleAfter); err != nil || d <= 0 {
+			add("invalid StaleAfter %q", c.StaleAfter)
+		}
+	}
+	if c.RequestTimeout != "" {
+		if d, err := time.ParseDuration(c.RequestTimeout); err != nil || d <= 0 {
+			add("invalid RequestTimeout %q", c.RequestTimeout)
+		}
+	}
+	if c.RunTimeout != "" {
+		if d, err := time.ParseDuration(c.RunTimeout); err != nil || d <= 0 {
+			add("invalid RunTimeout %q", c.RunTimeout)
+		}
+	}
+	for _, proxy := range []struct{ name, value string }{{"IPProxy", c.IPProxy}, {"APIProxy", c.APIProxy}} {
+		if _, err := proxyFunc(proxy.value); err != nil {
+			add("%s: %v", proxy.name, err)
+		}
+	}
+	if _, err := newCustomResolver(c.DNSServers, nil); err != nil {
+		add("DNSServers: %v", err)
+	}
+	if c.TLS != nil {
+		if _, err := c.TLS.clientConfig(); err != nil {
+			add("TLS: %v", err)
+		}
+	}
+	if c.AliyunRegion != "" && !aliyunRegionPattern.MatchString(c.AliyunRegion) {
+		add("invalid AliyunRegion %q, expected a region ID like cn-hangzhou", c.AliyunRegion)
+	}
+	switch c.AliyunNetwork {
+	case "", aliyunNetworkPublic, aliyunNetworkVPC:
+	default:
+		add("invalid AliyunNetwork %q, use public or vpc", c.AliyunNetwork)
+	}
+	if c.AliyunEndpoint != "" && !validEndpoint(c.AliyunEndpoint) {
+		add("invalid AliyunEndpoint %q, expected a host name like alidns.cn-hangzhou.aliyuncs.com", c.AliyunEndpoint)
+	}
+	if c.Retry != nil {
+		if c.Retry.Attempts < 0 {
+			add("invalid Retry.Attempts %d", c.Retry.Attempts)
+		}
+		if c.Retry.Backoff != "" {
+			if d, err := time.ParseDuration(c.Retry.Backoff); err != nil || d <= 0 {
+				add("invalid Retry.Backoff %q", c.Retry.Backoff)
+			}
+		}
+		if c.Retry.MaxBackoff != "" {
+			if d, err := time.ParseDuration(c.Retry.MaxBackoff); err != nil || d <= 0 {
+				add("invalid Retry.MaxBackoff %q", c.Retry.MaxBackoff)
+			}
+		}
+	}
+	if c.Listen != "" {
+		if _, _, err := net.SplitHostPort(c.Listen); err != nil {
+			add("invalid Listen %q: %v", c.Listen, err)
+		}
+	}
+	if c.Verify != nil && c.Verify.Timeout != "" {
+		if _, err := time.ParseDuration(c.Verify.Timeout); err != nil {
+			add("invalid Verify.Timeout %q", c.Verify.Timeout)
+		}
+	}
+	if _, err := newNotifiers(c); err != nil {
+		add("Notify: %v", err)
+	}
+	if _, err := newMetricsPushers(c); err != nil {
+		add("Metrics: %v", err)
+	}
+
+	checked := make(map[string]bool)
+	for _, rec := range c.records() {
+		if !checked[rec.Provider] {
+			checked[rec.Provider] = true
+			if err := c.checkCredentials(rec.Provider); err != nil {
+				add("%v", err)
+			}
+		}
+		for _, err := range rec.validate() {
+			add("%s: %v", rec, err)
+		}
+	}
+	return problems
+}
+
+// 检查服务商需要的凭据是否填写
+func (c Config) checkCredentials(provider string) error {
+	switch provider {
+	case "aliyun", "pvtz":
+		if c.AliyunProfile == "" && c.RAMRole == "" && (c.AccessKeyID == "" || c.AccessKeySecret == "") {
+			return fmt.Errorf("%s requires AccessKeyID and AccessKeySecret, RAMRole or AliyunProfile", provider)
+		}
+	case "cloudflare":
+		if c.CFAPIToken == "" && (c.CFAPIKey == "" || c.CFEmail == "") {
+			return fmt.Errorf("cloudflare requires CF_API_TOKEN, or CF_API_KEY and CF_API_EMAIL")
+		}
+	default:
+		return fmt.Errorf("unknown provider %q", provider)
+	}
+	return nil
+}
+
+// 检查单条记录的取值
+func (r RecordConfig) validate() []error {
+	var errs []error
+	if r.DomainName == "" && !strings.Contains(r.Record, ".") {
+		errs = append(errs, fmt.Errorf("missing DomainName"))
+	}
+	if r.Record == "" && r.DomainName == "" {
+		errs = append(errs, fmt.Errorf("missing Record"))
+	}
+	if !isAddressType(r.RecordType) {
+		if err := validateRecordValue(r, r.Value); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if r.TTL < 0 || r.TTL > 86400 {
+		errs = append(errs, fmt.Errorf("TTL %d out of range (1-86400)", r.TTL))
+	} else if r.Provider == "cloudflare" && r.TTL > 1 && r.TTL < 30 {
+		errs = append(errs, fmt.Errorf("Cloudflare TTL must be 1 (automatic) or at least 30, got %d", r.TTL))
+	}
+
+	switch r.MultiRecordPolicy {
+	case "", policyFirst, policyAll, policyMatch, policyCollapse:
+	default:
+		errs = append(errs, fmt.Errorf("unknown MultiRecordPolicy %q", r.MultiRecordPolicy))
+	}
+
+	family := familyOf(r.RecordType)
+	if _, err := newIPSources(r.IPSources, family, nil); err != nil {
+		errs = append(errs, fmt.Errorf("IPSources: %w", err))
+	}
+	if len(r.Weights) > 0 {
+		if r.Provider != "aliyun" || !isAddressType(r.RecordType) {
+			errs = append(errs, fmt.Errorf("Weights are only supported for Aliyun A/AAAA records"))
+		}
+		for i, w := range r.Weights {
+			if w.Weight < 0 || w.Weight > 100 {
+				errs = append(errs, fmt.Errorf("Weights[%d]: weight %d out of range (1-100)", i, w.Weight))
+			}
+			if _, err := newIPSources(w.IPSources, family, nil); err != nil {
+				errs = append(errs, fmt.Errorf("Weights[%d].IPSources: %w", i, err))
+			}
+		}
+	}
+	return errs
+}
+
+// 用配置的凭据访问每条记录所在的域名，检查凭据是否有效、域名是否在账号下
+func (c Config) checkAccess() []string {
+	ctx, cancel := c.runContext()
+	defer cancel()
+	var problems []string
+	providers := make(map[string]provider)
+	for _, rec := range c.records() {
+		p, ok := providers[rec.Provider]
+		if !ok {
+			var err error
+			if p, err = newProvider(ctx, c, rec.Provider); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", rec.Provider, err))
+				continue
+			}
+			providers[rec.Provider] = p
+		}
+
+		rec, err := resolveZone(p, rec)
+		if err == nil {
+			if ac, ok := p.(accessChecker); ok {
+				err = ac.checkAccess(rec)
+			}
+		}
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", rec, err))
+		} else {
+			fmt.Print(trf("%s: access OK\n", rec))
+		}
+	}
+	return problems
+}
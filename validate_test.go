@@ -0,0 +1,32 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnmarshalStrict(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string // 错误信息中应包含的内容，为空时应当成功
+	}{
+		{"known fields", `{"AccessKeyID": "id", "Records": [{"DomainName": "example.com", "Record": "home", "TTL": 600}]}`, ""},
+		{"case insensitive", `{"accesskeyid": "id", "records": [{"record": "home"}]}`, ""},
+		{"typo with suggestion", `{"AccesKeyID": "id"}`, `unknown field "AccesKeyID" in config, did you mean "AccessKeyID"?`},
+		{"unknown field in record", `{"Records": [{"Record": "home", "Colour": "red"}]}`, `unknown field "Colour" in config.Records`},
+		{"wrong type", `{"Records": {"Record": "home"}}`, "config.Records should be a list"},
+	}
+	for _, tt := range tests {
+		var config Config
+		err := unmarshalStrict([]byte(tt.data), &config, "config")
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		case tt.want != "" && err == nil:
+			t.Errorf("%s: expected error containing %q", tt.name, tt.want)
+		case tt.want != "" && !strings.Contains(err.Error(), tt.want):
+			t.Errorf("%s: error %q does not contain %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,372 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// 更新后检查新值是否已经生效
+type VerifyConfig struct {
+	Authoritative bool     `json:"Authoritative"` // 查询域名的权威服务器，未配置 Resolvers 时默认开启
+	Resolvers     []string `json:"Resolvers"`     // 公共递归服务器，例如 "223.5.5.5"、"1.1.1.1:53"
+	Timeout       string   `json:"Timeout"`       // 等待生效的最长时间，默认 "2m"
+}
+
+// 默认的等待时间和查询间隔
+const (
+	defaultVerifyTimeout = 2 * time.Minute
+	verifyPollInterval   = 10 * time.Second
+)
+
+// 一条待检查的记录
+type propagationCheck struct {
+	job     *updateJob
+	servers []string // 还没有看到新值的服务器
+}
+
+// 查询各个服务器直到新值全部可见，超时后返回仍未生效的记录。
+// 最多等待 Verify.Timeout，不超过整次检查的 RunTimeout
+func (u *updater) verify(ctx context.Context, jobs []*updateJob) []string {
+	cfg := u.config.Verify
+	timeout := defaultVerifyTimeout
+	if cfg.Timeout != "" {
+		d, err := time.ParseDuration(cfg.Timeout)
+		if err != nil {
+			return []string{fmt.Sprintf("invalid Verify.Timeout: %v", err)}
+		}
+		timeout = d
+	}
+
+	var errs []string
+	var pending []*propagationCheck
+	for _, j := range jobs {
+		// 内网记录在公网不可见；经过 Cloudflare 代理的记录解析到的是 Cloudflare 的地址
+		if j.rec.Provider == "pvtz" || j.proxied {
+			continue
+		}
+
+		servers := resolverAddrs(cfg.Resolvers)
+		if cfg.Authoritative || len(cfg.Resolvers) == 0 {
+			ns, err := authoritativeServers(j.rec.DomainName)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", j.rec, err))
+				continue
+			}
+			servers = append(servers, ns...)
+		}
+		pending = append(pending, &propagationCheck{job: j, servers: servers})
+	}
+
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+		timeout = time.Until(d).Round(time.Second)
+	}
+	for len(pending) > 0 {
+		var next []*propagationCheck
+		for _, c := range pending {
+			var remaining []string
+			for _, server := range c.servers {
+				if !visibleOn(ctx, server, c.job) {
+					remaining = append(remaining, server)
+				}
+			}
+			c.servers = remaining
+			if len(remaining) == 0 {
+				slog.Info("New value is visible on all nameservers", "record", c.job.rec)
+				continue
+			}
+			next = append(next, c)
+		}
+		pending = next
+
+		if len(pending) == 0 || time.Now().Add(verifyPollInterval).After(deadline) {
+			break
+		}
+		if !sleepContext(ctx, verifyPollInterval) {
+			break
+		}
+	}
+
+	for _, c := range pending {
+		errs = append(errs, fmt.Sprintf("%s: new value %s not visible on %s within %s",
+			c.job.rec, c.job.newValue, strings.Join(c.servers, ", "), timeout))
+	}
+	return errs
+}
+
+// 补全服务器地址的端口
+func resolverAddrs(resolvers []string) []string {
+	addrs := make([]string, 0, len(resolvers))
+	for _, r := range resolvers {
+		if _, _, err := net.SplitHostPort(r); err != nil {
+			r = net.JoinHostPort(r, "53")
+		}
+		addrs = append(addrs, r)
+	}
+	return addrs
+}
+
+// 查询域名的权威服务器
+func authoritativeServers(domain string) ([]string, error) {
+	ns, err := net.LookupNS(domain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up nameservers of %s: %w", domain, err)
+	}
+	servers := make([]string, 0, len(ns))
+	for _, n := range ns {
+		servers = append(servers, net.JoinHostPort(strings.TrimSuffix(n.Host, "."), "53"))
+	}
+	return servers, nil
+}
+
+// 检查服务器上是否已经能查到全部新值
+func visibleOn(ctx context.Context, server string, j *updateJob) bool {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	found, err := lookupRecord(ctx, resolverFor(server), j.rec)
+	if err != nil {
+		return false
+	}
+
+	want := []string{j.newValue}
+	if len(j.weighted) > 0 {
+		want = want[:0]
+		for _, w := range j.weighted {
+			want = append(want, w.value)
+		}
+	}
+	return hasValues(j.rec.RecordType, found, want)
+}
+
+// 只向指定服务器查询的解析器
+func resolverFor(server string) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+}
+
+// 查到的值中是否包含全部期望的值
+func hasValues(recordType string, found, want []string) bool {
+	for _, w := range want {
+		ok := false
+		for _, f := range found {
+			if normalizeRecordValue(recordType, f) == normalizeRecordValue(recordType, w) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// 按记录类型查询记录的值
+func lookupRecord(ctx context.Context, r *net.Resolver, rec RecordConfig) ([]string, error) {
+	name := rec.fqdn() + "."
+	var values []string
+	switch rec.RecordType {
+	case "A", "AAAA":
+		ips, err := r.LookupIP(ctx, "ip"+strconv.Itoa(familyOf(rec.RecordType)), name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			values = append(values, ip.String())
+		}
+	case "CNAME":
+		cname, err := r.LookupCNAME(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, cname)
+	case "NS":
+		ns, err := r.LookupNS(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range ns {
+			values = append(values, n.Host)
+		}
+	case "MX":
+		mx, err := r.LookupMX(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range mx {
+			values = append(values, m.Host)
+		}
+	case "SRV":
+		_, srv, err := r.LookupSRV(ctx, "", "", name)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range srv {
+			values = append(values, fmt.Sprintf("%d %d %d %s", s.Priority, s.Weight, s.Port, s.Target))
+		}
+	case "TXT":
+		return r.LookupTXT(ctx, name)
+	default:
+		return nil, fmt.Errorf("unsupported record type %q", rec.RecordType)
+	}
+	return values, nil
+}
+
+// 比较前统一格式：主机名忽略大小写和末尾的点，TXT 保持原样
+func normalizeRecordValue(recordType, value string) string {
+	if recordType == "TXT" {
+		return value
+	}
+	if ip := net.ParseIP(value); ip != nil {
+		return ip.String()
+	}
+	return strings.TrimSuffix(strings.ToLower(value), ".")
+}
+
+// verify 命令默认查询的公共递归服务器
+var defaultPublicResolvers = []string{"223.5.5.5", "119.29.29.29", "1.1.1.1", "8.8.8.8"}
+
+// verify 命令中一条记录在一个服务器上的检查结果
+type verifyResult struct {
+	Record   string   `json:"Record"`
+	Type     string   `json:"Type"`
+	Expected []string `json:"Expected"` // 检测到的地址，或配置的值
+	Resolver string   `json:"Resolver"`
+	Found    []string `json:"Found"`
+	Error    string   `json:"Error,omitempty"`
+	OK       bool     `json:"OK"`
+}
+
+// 端到端检查：通过公共递归服务器解析配置的记录，与当前检测到的地址比较，不一致时返回错误。
+// aliddns -c config.json verify [-resolver 223.5.5.5,1.1.1.1] [-o json]
+func runVerify(u *updater, args []string, format string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	resolverList := fs.String("resolver", "", "Comma-separated resolvers to query (default: Verify.Resolvers, or well-known public resolvers)")
+	fs.StringVar(&format, "o", format, "Output format: table, json or yaml")
+	fs.Parse(args)
+	if err := checkOutputFormat(format); err != nil {
+		return withExitCode(exitConfig, err)
+	}
+
+	resolvers := defaultPublicResolvers
+	if u.config.Verify != nil && len(u.config.Verify.Resolvers) > 0 {
+		resolvers = u.config.Verify.Resolvers
+	}
+	if *resolverList != "" {
+		resolvers = strings.Split(*resolverList, ",")
+	}
+	servers := resolverAddrs(resolvers)
+
+	ctx, cancel := u.config.runContext()
+	defer cancel()
+	results := []verifyResult{}
+	code := exitOK
+	for _, rec := range u.config.records() {
+		// 内网记录在公网不可见；经过 Cloudflare 代理的记录解析到的是 Cloudflare 的地址
+		rs := u.state.Records[rec.key()]
+		if rec.Provider == "pvtz" || rec.Proxied != nil && *rec.Proxied || rec.Proxied == nil && rs != nil && rs.Proxied {
+			slog.Info("Skipping record not visible on public resolvers", "record", rec)
+			continue
+		}
+		if rs != nil && rs.Disabled {
+			continue
+		}
+		// 未配置域名时 Record 为完整域名
+		if rec.DomainName == "" && rec.Provider != "cloudflare" {
+			rec.Record, rec.DomainName = "@", rec.Record
+		}
+
+		expected, err := u.expectedValues(ctx, rec)
+		if err != nil {
+			results = append(results, verifyResult{Record: rec.fqdn(), Type: rec.RecordType, Error: err.Error()})
+			if code == exitOK {
+				code = exitDetect
+			}
+			continue
+		}
+		for _, server := range servers {
+			lctx, lcancel := context.WithTimeout(ctx, 5*time.Second)
+			found, err := lookupRecord(lctx, resolverFor(server), rec)
+			lcancel()
+			r := verifyResult{Record: rec.fqdn(), Type: rec.RecordType, Expected: expected, Resolver: server, Found: found}
+			if err != nil {
+				r.Error = err.Error()
+			}
+			r.OK = err == nil && hasValues(rec.RecordType, found, expected)
+			if !r.OK && code == exitOK {
+				code = exitMismatch
+			}
+			results = append(results, r)
+		}
+	}
+
+	err := writeFormatted(format, results, func(out io.Writer) error {
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, tr("RECORD\tTYPE\tRESOLVER\tEXPECTED\tFOUND\tRESULT"))
+		for _, r := range results {
+			result := "ok"
+			if !r.OK {
+				result = "MISMATCH"
+			}
+			found := strings.Join(r.Found, ",")
+			if r.Error != "" {
+				result, found = "ERROR", r.Error
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Record, r.Type, r.Resolver, strings.Join(r.Expected, ","), found, result)
+		}
+		return w.Flush()
+	})
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, r := range results {
+		if !r.OK {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return withExitCode(code, fmt.Errorf("%d of %d checks do not match", failed, len(results)))
+	}
+	return nil
+}
+
+// 记录应该解析到的值：地址类记录为当前检测到的地址（加权解析为每个出口的地址），其余为配置的值
+func (u *updater) expectedValues(ctx context.Context, rec RecordConfig) ([]string, error) {
+	if !isAddressType(rec.RecordType) {
+		return []string{rec.Value}, nil
+	}
+	family := familyOf(rec.RecordType)
+	if len(rec.Weights) == 0 {
+		ip, err := u.detect(ctx, rec.IPSources, family)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get external IP: %w", err)
+		}
+		return []string{ip}, nil
+	}
+	values := make([]string, 0, len(rec.Weights))
+	for _, w := range rec.Weights {
+		ip, err := u.detect(ctx, w.IPSources, family)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get external IP: %w", err)
+		}
+		values = append(values, ip)
+	}
+	return values, nil
+}
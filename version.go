@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// 版本信息，发布时用 -ldflags 设置，例如
+// -ldflags "-X main.version=v1.2.0 -X main.commit=$(git rev-parse HEAD) -X main.buildDate=$(date -u +%FT%TZ)"。
+// 没有设置时从 Go 写入的构建信息中读取（go install 的模块版本，或源码目录的 VCS 信息）
+var (
+	version   = ""
+	commit    = ""
+	buildDate = ""
+)
+
+// 构建信息
+type buildInfo struct {
+	Version   string `json:"Version"`
+	Commit    string `json:"Commit,omitempty"`
+	BuildDate string `json:"BuildDate,omitempty"`
+	Modified  bool   `json:"Modified,omitempty"` // 构建时源码有未提交的修改
+	GoVersion string `json:"GoVersion"`
+	Platform  string `json:"Platform"`
+}
+
+// 只在第一次用到时读取
+var readBuildInfo = sync.OnceValue(func() buildInfo {
+	info := buildInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if commit == "" {
+					info.Commit = s.Value
+				}
+			case "vcs.modified":
+				info.Modified = commit == "" && s.Value == "true"
+			}
+		}
+	}
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	return info
+})
+
+// 调用服务商接口时的 User-Agent，例如 aliddns/v1.2.0，方便服务商和用户排查问题
+func userAgent() string {
+	return "aliddns/" + readBuildInfo().Version
+}
+
+// 在服务商接口请求的 User-Agent 后追加 aliddns 的版本，保留阿里云 SDK 自带的 User-Agent
+type userAgentTransport struct {
+	base http.RoundTripper
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ua := userAgent()
+	if existing := req.Header.Get("User-Agent"); existing != "" {
+		ua = existing + " " + ua
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", ua)
+	return t.base.RoundTrip(req)
+}
+
+// 输出版本和构建信息：aliddns version [-o json]
+func runVersion(format string) error {
+	info := readBuildInfo()
+	return writeFormatted(format, info, func(w io.Writer) error {
+		fmt.Fprintf(w, "aliddns %s\n", info.Version)
+		if info.Commit != "" {
+			commit := info.Commit
+			if len(commit) > 12 {
+				commit = commit[:12]
+			}
+			if info.Modified {
+				commit += " (modified)"
+			}
+			fmt.Fprintf(w, "  commit:     %s\n", commit)
+		}
+		if info.BuildDate != "" {
+			fmt.Fprintf(w, "  built:      %s\n", info.BuildDate)
+		}
+		fmt.Fprintf(w, "  go:         %s %s\n", info.GoVersion, info.Platform)
+		return nil
+	})
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// 通用 Webhook 的模板数据，每条变化的记录发送一次，失败时发送一次
+type webhookData struct {
+	Event    string // change、failure 或 check（没有变化）
+	Record   string // 完整域名
+	Type     string
+	Provider string
+	OldIP    string
+	NewIP    string
+	Error    string
+	Failures int
+	Time     string // RFC 3339 格式
+	Title    string // 与其他通知相同的标题和正文
+	Message  string
+}
+
+// 向任意地址发送请求，请求体由 Go 模板生成，例如
+// {"text": "{{.Record}} {{.OldIP}} -> {{.NewIP}}"}。模板中可以用 {{json .Error}} 输出 JSON 字符串。
+// 未配置模板时发送 webhookData 的 JSON
+type webhookNotifier struct {
+	url     string
+	method  string
+	headers map[string]string
+	body    *template.Template
+}
+
+func newWebhookNotifier(c NotifyConfig) (*webhookNotifier, error) {
+	if c.URL == "" {
+		return nil, fmt.Errorf("webhook notification requires URL")
+	}
+	n := &webhookNotifier{url: c.URL, method: strings.ToUpper(c.Method), headers: c.Headers}
+	if n.method == "" {
+		n.method = "POST"
+	}
+	if c.Body != "" {
+		funcs := template.FuncMap{"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		}}
+		var err error
+		if n.body, err = template.New("Body").Funcs(funcs).Parse(c.Body); err != nil {
+			return nil, fmt.Errorf("invalid webhook Body: %w", err)
+		}
+	}
+	return n, nil
+}
+
+func (n *webhookNotifier) notify(e notifyEvent) error {
+	for _, data := range webhookEvents(e) {
+		if err := n.send(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 把事件拆成模板数据，每条变化的记录一项，失败或没有变化时只有一项
+func webhookEvents(e notifyEvent) []webhookData {
+	title, message := notifyMessage(e)
+	now := time.Now().Format(time.RFC3339)
+	if e.Error != "" {
+		return []webhookData{{Event: "failure", Error: e.Error, Failures: e.Failures, Time: now, Title: title, Message: message}}
+	}
+	if len(e.Changes) == 0 {
+		return []webhookData{{Event: "check", Time: now, Title: title, Message: message}}
+	}
+	events := make([]webhookData, 0, len(e.Changes))
+	for _, c := range e.Changes {
+		events = append(events, webhookData{
+			Event:    "change",
+			Record:   c.Record,
+			Type:     c.Type,
+			Provider: c.Provider,
+			OldIP:    c.OldValue,
+			NewIP:    c.NewValue,
+			Time:     now,
+			Title:    title,
+			Message:  message,
+		})
+	}
+	return events
+}
+
+func (n *webhookNotifier) send(data webhookData) error {
+	var body bytes.Buffer
+	if n.body != nil {
+		if err := n.body.Execute(&body, data); err != nil {
+			return fmt.Errorf("failed to render webhook Body: %w", err)
+		}
+	} else if err := json.NewEncoder(&body).Encode(data); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(n.method, n.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range n.headers {
+		req.Header.Set(k, v)
+	}
+
+	client := outboundClient()
+	resp, err := client.Do(req)
+	if err != nil {
+		return hideURL(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
+
+// 地址中可能带有密钥，日志中只显示主机名
+func (n *webhookNotifier) String() string {
+	if u, err := url.Parse(n.url); err == nil {
+		return "webhook " + u.Host
+	}
+	return "webhook"
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// 企业微信接口地址
+const weComAPI = "https://qyapi.weixin.qq.com/cgi-bin"
+
+// 企业微信通知：填写 URL 时使用群机器人的 Webhook，否则用 CorpID/CorpSecret/AgentID 发送应用消息
+type weComNotifier struct {
+	webhook    string
+	corpID     string
+	corpSecret string
+	agentID    int
+	toUser     string
+
+	// 应用消息的 access_token，过期前重复使用
+	token   string
+	expires time.Time
+}
+
+// 企业微信接口的返回值，errcode 为 0 表示成功
+type weComResponse struct {
+	ErrCode     int    `json:"errcode"`
+	ErrMsg      string `json:"errmsg"`
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+func (r weComResponse) err() error {
+	if r.ErrCode != 0 {
+		return fmt.Errorf("errcode %d: %s", r.ErrCode, r.ErrMsg)
+	}
+	return nil
+}
+
+func newWeComNotifier(c NotifyConfig) (*weComNotifier, error) {
+	n := &weComNotifier{webhook: c.URL, corpID: c.CorpID, corpSecret: c.CorpSecret, agentID: c.AgentID, toUser: c.ToUser}
+	if n.webhook == "" && (n.corpID == "" || n.corpSecret == "" || n.agentID == 0) {
+		return nil, fmt.Errorf("wecom notification requires URL (group bot) or CorpID, CorpSecret and AgentID (application)")
+	}
+	if n.toUser == "" {
+		n.toUser = "@all"
+	}
+	return n, nil
+}
+
+func (n *weComNotifier) notify(e notifyEvent) error {
+	title, text := notifyMessage(e)
+	content := title + "\n" + text
+
+	var resp weComResponse
+	if n.webhook != "" {
+		msg := map[string]interface{}{
+			"msgtype": "text",
+			"text":    map[string]string{"content": content},
+		}
+		if err := postJSON(n.webhook, msg, &resp); err != nil {
+			return err
+		}
+		return resp.err()
+	}
+
+	token, err := n.accessToken()
+	if err != nil {
+		return err
+	}
+	msg := map[string]interface{}{
+		"touser":  n.toUser,
+		"msgtype": "text",
+		"agentid": n.agentID,
+		"text":    map[string]string{"content": content},
+	}
+	if err := postJSON(weComAPI+"/message/send?access_token="+url.QueryEscape(token), msg, &resp); err != nil {
+		return err
+	}
+	return resp.err()
+}
+
+// 获取应用消息的 access_token，有效期内使用缓存
+func (n *weComNotifier) accessToken() (string, error) {
+	if n.token != "" && time.Now().Before(n.expires) {
+		return n.token, nil
+	}
+
+	query := url.Values{"corpid": {n.corpID}, "corpsecret": {n.corpSecret}}
+	client := outboundClient()
+	resp, err := client.Get(weComAPI + "/gettoken?" + query.Encode())
+	if err != nil {
+		return "", fmt.Errorf("failed to get access token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result weComResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode access token: %w", err)
+	}
+	if err := result.err(); err != nil {
+		return "", fmt.Errorf("failed to get access token: %w", err)
+	}
+	// 提前一分钟刷新
+	n.token = result.AccessToken
+	n.expires = time.Now().Add(time.Duration(result.ExpiresIn)*time.Second - time.Minute)
+	return n.token, nil
+}
+
+func (n *weComNotifier) String() string {
+	if n.webhook != "" {
+		return "wecom bot"
+	}
+	return "wecom app " + n.corpID
+}